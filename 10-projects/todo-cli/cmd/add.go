@@ -29,21 +29,38 @@ var addCmd = &cobra.Command{
 	Use:   "add [task]",
 	Short: "Add a new todo task",
 	Args:  cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		repo := todo.NewRepository()
-		task := args[0]
-		err := repo.Add(task)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		due, _ := cmd.Flags().GetString("due")
+		priority, _ := cmd.Flags().GetString("priority")
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		list, _ := cmd.Flags().GetString("list")
+
+		_, err := deps.service.AddTask(todo.Todo{
+			Task:     args[0],
+			Due:      due,
+			Priority: todo.Priority(priority),
+			Tags:     tags,
+			List:     list,
+		})
 		if err != nil {
-			fmt.Println("Error:", err)
-			return
+			return err
 		}
-		fmt.Println("Todo added successfully!")
+		fmt.Fprintln(cmd.OutOrStdout(), "Todo added successfully!")
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(addCmd)
 
+	addCmd.Flags().String("due", "", "Due date (YYYY-MM-DD)")
+	addCmd.Flags().StringP("priority", "p", "", "Priority: low, medium or high")
+	addCmd.Flags().StringSlice("tag", nil, "Tag the todo (repeatable or comma-separated)")
+	addCmd.Flags().StringP("list", "l", "", "List the todo belongs to")
+	addCmd.RegisterFlagCompletionFunc("priority", fixedValues("low", "medium", "high"))
+	addCmd.RegisterFlagCompletionFunc("tag", completeValues(todo.Tags))
+	addCmd.RegisterFlagCompletionFunc("list", completeValues(todo.Lists))
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -0,0 +1,107 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+// boardCmd represents the board command
+var boardCmd = &cobra.Command{
+	Use:   "board",
+	Short: "Show todos as a kanban board",
+	Long: `Board shows one column per workflow status, in workflow order. Todos
+with a status that is no longer part of the workflow, for example after
+renaming a status in the config, are listed with their status in an
+UNKNOWN column at the end. "todo doctor" reports them as well.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		todos, err := deps.service.ListTasks()
+		if err != nil {
+			return err
+		}
+		width, _ := cmd.Flags().GetInt("width")
+		renderBoard(cmd.OutOrStdout(), todo.CurrentWorkflow(), todos, width)
+		return nil
+	},
+}
+
+// unknownColumn collects todos whose status is not in the workflow. A
+// workflow never contains the empty status, so it cannot clash.
+const unknownColumn todo.Status = ""
+
+// renderBoard writes the todos grouped into workflow columns. Cells are
+// cut to width runes so that wide boards stay readable.
+func renderBoard(w io.Writer, workflow todo.Workflow, todos []todo.Todo, width int) {
+	columns := make([]todo.Status, len(workflow))
+	copy(columns, workflow)
+
+	cards := make(map[todo.Status][]string)
+	for _, t := range todos {
+		card := fmt.Sprintf("%d %s", t.ID, t.Task)
+		status := t.Status
+		if !workflow.Contains(status) {
+			card = fmt.Sprintf("%d [%s] %s", t.ID, t.Status, t.Task)
+			status = unknownColumn
+			if len(cards[status]) == 0 {
+				columns = append(columns, status)
+			}
+		}
+		cards[status] = append(cards[status], card)
+	}
+
+	rows := 0
+	for _, c := range columns {
+		if len(cards[c]) > rows {
+			rows = len(cards[c])
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := make([]string, len(columns))
+	rule := make([]string, len(columns))
+	for i, c := range columns {
+		name := string(c)
+		if c == unknownColumn {
+			name = "unknown"
+		}
+		header[i] = truncate(fmt.Sprintf("%s (%d)", strings.ToUpper(name), len(cards[c])), width)
+		rule[i] = strings.Repeat("-", utf8.RuneCountInString(header[i]))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	fmt.Fprintln(tw, strings.Join(rule, "\t"))
+
+	for r := 0; r < rows; r++ {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			if r < len(cards[c]) {
+				cells[i] = truncate(cards[c][r], width)
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	tw.Flush()
+}
+
+// truncate shortens s to at most n runes, marking the cut with "…".
+func truncate(s string, n int) string {
+	if n <= 0 || utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:n-1]) + "…"
+}
+
+func init() {
+	rootCmd.AddCommand(boardCmd)
+
+	boardCmd.Flags().IntP("width", "w", 24, "Maximum width of a column")
+}
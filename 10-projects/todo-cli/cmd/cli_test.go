@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var testNow = time.Date(2026, 10, 19, 9, 30, 0, 0, time.UTC)
+
+// runCLI runs the CLI with args against repo and a fixed clock. It returns
+// the exit code and what was written to stdout and stderr.
+func runCLI(t *testing.T, repo todo.Repository, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	t.Setenv("TODO_CONFIG", filepath.Join(t.TempDir(), "missing.json"))
+
+	old := deps
+	deps = newDependencies(repo, func() time.Time { return testNow })
+	defer func() {
+		deps = old
+		resetCommands(rootCmd)
+	}()
+
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// resetCommands puts every flag back to its default, since cobra keeps
+// parsed values on the command between runs.
+func resetCommands(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			s.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	c.SilenceUsage = false
+	for _, sub := range c.Commands() {
+		resetCommands(sub)
+	}
+}
+
+func TestCLIAddListComplete(t *testing.T) {
+	repo := todo.NewMemoryRepository()
+
+	code, out, _ := runCLI(t, repo, "", "add", "Write docs", "--due", "2026-11-01", "-p", "high", "--tag", "docs")
+	if code != 0 || out != "Todo added successfully!\n" {
+		t.Fatalf("Expected add to succeed, got %d %q", code, out)
+	}
+	runCLI(t, repo, "", "add", "Ship release", "--list", "work")
+
+	code, out, _ = runCLI(t, repo, "", "list")
+	want := "[ ] 1: Write docs (due 2026-11-01, high, #docs)\n[ ] 2: Ship release (list work)\n"
+	if code != 0 || out != want {
+		t.Errorf("Expected list output %q, got %d %q", want, code, out)
+	}
+
+	code, out, _ = runCLI(t, repo, "", "complete", "1")
+	if code != 0 || out != "Todo marked as completed!\n" {
+		t.Errorf("Expected complete to succeed, got %d %q", code, out)
+	}
+
+	code, out, _ = runCLI(t, repo, "", "list", "--list", "work")
+	if code != 0 || out != "[ ] 2: Ship release (list work)\n" {
+		t.Errorf("Expected only the work list, got %d %q", code, out)
+	}
+
+	code, out, _ = runCLI(t, repo, "", "status", "2", "review")
+	if code != 0 || out != "Todo moved to review!\n" {
+		t.Errorf("Expected status to succeed, got %d %q", code, out)
+	}
+
+	todos, _ := repo.List()
+	if !todos[0].IsDone() || todos[1].Status != todo.StatusReview {
+		t.Errorf("Unexpected todos in store: %+v", todos)
+	}
+}
+
+func TestCLIErrorsExitNonZero(t *testing.T) {
+	repo := todo.NewMemoryRepository(todo.Todo{ID: 1, Task: "Existing", Status: todo.StatusTodo})
+	t.Setenv("TODO_TOKEN", "")
+
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{[]string{"complete", "9"}, "Error: todo not found\n"},
+		{[]string{"complete", "abc"}, "Error: invalid ID: abc\n"},
+		{[]string{"delete", "9"}, "Error: todo not found\n"},
+		{[]string{"add", " "}, "Error: task cannot be empty\n"},
+		{[]string{"add", "Task", "--due", "tomorrow"}, "Error: invalid due date \"tomorrow\", expected YYYY-MM-DD\n"},
+		{[]string{"status", "1", "shipped"}, "Error: unknown status \"shipped\""},
+		{[]string{"status", "x", "done"}, "Error: invalid ID: x\n"},
+		{[]string{"export", "--format", "xml"}, "Error: unknown format \"xml\", expected json or ics\n"},
+		{[]string{"doctor"}, "Error: doctor only works with the data file\n"},
+		{[]string{"complete"}, "Error: requires at least 1 arg(s), only received 0\n"},
+		{[]string{"serve", "--addr", ":7070"}, "Error: refusing to serve on :7070 without a token"},
+	}
+
+	for _, tt := range tests {
+		code, _, stderr := runCLI(t, repo, "", tt.args...)
+		if code != 1 {
+			t.Errorf("%q: expected exit code 1, got %d", tt.args, code)
+		}
+		if !strings.HasPrefix(stderr, tt.wantErr) {
+			t.Errorf("%q: expected stderr %q, got %q", tt.args, tt.wantErr, stderr)
+		}
+	}
+
+	if todos, _ := repo.List(); len(todos) != 1 || todos[0].IsDone() {
+		t.Errorf("Expected store unchanged, got %+v", todos)
+	}
+}
+
+func TestCLIBulk(t *testing.T) {
+	repo := todo.NewMemoryRepository()
+	for _, task := range []string{"a", "b", "c"} {
+		runCLI(t, repo, "", "add", task)
+	}
+
+	code, out, stderr := runCLI(t, repo, "", "complete", "1", "7")
+	if code != 1 || !strings.Contains(out, "  7: todo not found\n") || stderr != "Error: 1 of 2 IDs failed, no changes were made\n" {
+		t.Errorf("Expected failed bulk complete, got %d %q %q", code, out, stderr)
+	}
+
+	code, out, _ = runCLI(t, repo, "n\n", "delete", "1-2")
+	if code != 0 || !strings.HasSuffix(out, "Delete 2 todos? [y/N] Aborted, nothing was deleted.\n") {
+		t.Errorf("Expected delete to be aborted, got %d %q", code, out)
+	}
+
+	code, out, _ = runCLI(t, repo, "y\n", "delete", "1-2")
+	if code != 0 || !strings.HasSuffix(out, "2 todos deleted.\n") {
+		t.Errorf("Expected delete to succeed, got %d %q", code, out)
+	}
+	if todos, _ := repo.List(); len(todos) != 1 || todos[0].Task != "c" {
+		t.Errorf("Expected only c left, got %+v", todos)
+	}
+}
+
+func TestCLIExportUsesClock(t *testing.T) {
+	repo := todo.NewMemoryRepository(todo.Todo{ID: 1, Task: "Pay rent", Status: todo.StatusTodo, Due: "2026-11-01"})
+
+	code, out, _ := runCLI(t, repo, "", "export", "--format", "ics")
+	if code != 0 {
+		t.Fatalf("Expected export to succeed, got %d", code)
+	}
+	for _, want := range []string{"DTSTAMP:20261019T093000Z\r\n", "SUMMARY:Pay rent\r\n", "DUE;VALUE=DATE:20261101\r\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in export, got %q", want, out)
+		}
+	}
+}
+
+func TestNeedsPlugins(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{nil, true},
+		{[]string{"report"}, true},
+		{[]string{"--file", "todos.json", "report", "--week"}, true},
+		{[]string{"list"}, false},
+		{[]string{"--file", "todos.json", "add", "Task"}, false},
+		{[]string{"plugins"}, false},
+		{[]string{"__complete", "complete", ""}, false},
+		{[]string{"__completeNoDesc", "rep"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := needsPlugins(tt.args); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.args, tt.want, got)
+		}
+	}
+}
+
+func TestCLIBoardShowsUnknownStatuses(t *testing.T) {
+	repo := todo.NewMemoryRepository(
+		todo.Todo{ID: 1, Task: "Plan", Status: todo.StatusTodo},
+		todo.Todo{ID: 2, Task: "Ship", Status: "shipped"},
+	)
+
+	code, out, _ := runCLI(t, repo, "", "board")
+	if code != 0 || !strings.Contains(out, "UNKNOWN (1)") || !strings.Contains(out, "2 [shipped] Ship") {
+		t.Errorf("Expected the shipped todo in the unknown column, got %d %q", code, out)
+	}
+}
+
+func TestGlobalFlagArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"report"}, 0},
+		{[]string{"-v", "report", "-v"}, 1},
+		{[]string{"--verbose", "--file", "todos.json", "report"}, 3},
+		{[]string{"--file=todos.json", "report", "--file", "x"}, 1},
+		{[]string{"-v=false", "report"}, 1},
+		{[]string{"--log-level", "info", "-v", "report", "--week"}, 3},
+		{[]string{"--week", "report"}, 0},
+		{[]string{"--", "report"}, 0},
+	}
+
+	for _, tt := range tests {
+		if got := globalFlagArgs(tt.args); got != tt.want {
+			t.Errorf("%q: expected %d, got %d", tt.args, tt.want, got)
+		}
+	}
+}
+
+func TestCLIPluginGetsOnlyItsArgs(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "todo-echo"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TODO_PLUGINS_DIR", dir)
+	t.Setenv("TODO_CONFIG", filepath.Join(dir, "missing.json"))
+
+	args := []string{"-v", "--output-format", "text", "echo", "-v", "--week"}
+	registerPlugins(args)
+	defer func() {
+		for _, c := range rootCmd.Commands() {
+			if _, isPlugin := c.Annotations[pluginAnnotation]; isPlugin {
+				rootCmd.RemoveCommand(c)
+			}
+		}
+		resetCommands(rootCmd)
+	}()
+
+	code, out, stderr := runCLI(t, todo.NewMemoryRepository(), "", args...)
+	if code != 0 || out != "-v --week\n" {
+		t.Errorf("Expected the plugin to get only its own args, got %d %q %q", code, out, stderr)
+	}
+}
+
+func TestCLILogsFailedCommands(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.log")
+
+	code, _, _ := runCLI(t, todo.NewMemoryRepository(), "", "-v", "--log-file", path, "complete", "9")
+	if code != 1 {
+		t.Fatalf("Expected complete to fail, got %d", code)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `msg="command failed" error="todo not found"`) {
+		t.Errorf("Expected the failure in the log file, got %q", data)
+	}
+}
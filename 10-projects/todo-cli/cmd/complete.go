@@ -5,9 +5,7 @@ package cmd
 
 import (
 	"fmt"
-	"strconv"
 
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
 	"github.com/spf13/cobra"
 )
 
@@ -27,22 +25,30 @@ to quickly create a Cobra application.`,
 } */
 
 var completeCmd = &cobra.Command{
-	Use:   "complete [id]",
-	Short: "Mark a todo as completed",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		repo := todo.NewRepository()
-		id, err := strconv.Atoi(args[0])
+	Use:   "complete [id...]",
+	Short: "Mark one or more todos as completed",
+	Long: `Complete moves todos to the final workflow status. IDs can be listed
+individually or as ranges, for example:
+
+  todo complete 3 5 8-12
+
+Either all todos are completed or, if any ID does not exist, none are.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeIDs(isOpen),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ids, err := parseIDs(args)
 		if err != nil {
-			fmt.Println("Invalid ID:", args[0])
-			return
+			return err
+		}
+		results, err := deps.service.CompleteTasks(ids)
+		if len(ids) > 1 {
+			return printResults(cmd.OutOrStdout(), results, err, "completed")
 		}
-		err = repo.Complete(id)
 		if err != nil {
-			fmt.Println("Error:", err)
-			return
+			return firstError(results, err)
 		}
-		fmt.Println("Todo marked as completed!")
+		fmt.Fprintln(cmd.OutOrStdout(), "Todo marked as completed!")
+		return nil
 	},
 }
 
@@ -0,0 +1,153 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+// completionCmd represents the completion command
+var completionCmd = &cobra.Command{
+	Use:   "completion [bash|zsh|fish|powershell]",
+	Short: "Generate the shell completion script",
+	Long: `Completion prints a completion script for your shell. Besides commands
+and flags it completes todo IDs, statuses, tags and lists from the data file.
+
+  bash:        source <(todo completion bash)
+  zsh:         todo completion zsh > "${fpath[1]}/_todo"
+  fish:        todo completion fish > ~/.config/fish/completions/todo.fish
+  powershell:  todo completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		var err error
+		switch args[0] {
+		case "bash":
+			err = rootCmd.GenBashCompletionV2(out, true)
+		case "zsh":
+			err = rootCmd.GenZshCompletion(out)
+		case "fish":
+			err = rootCmd.GenFishCompletion(out, true)
+		case "powershell":
+			err = rootCmd.GenPowerShellCompletionWithDesc(out)
+		}
+		return err
+	},
+}
+
+// completionFunc is the signature cobra uses for dynamic completion.
+type completionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// snapshotter is implemented by stores that can be read without locking.
+type snapshotter interface {
+	Snapshot() ([]todo.Todo, error)
+}
+
+// completionTodos reads the todos for shell completion. It applies the
+// flags cobra has parsed for the command being completed and reads a
+// snapshot if it can, so completing never blocks on the data file lock.
+func completionTodos() ([]todo.Todo, error) {
+	if err := loadConfig(); err != nil {
+		return nil, err
+	}
+	if s, ok := deps.repo.(snapshotter); ok {
+		return s.Snapshot()
+	}
+	return deps.repo.List()
+}
+
+// completeIDs suggests the IDs of the todos accepted by keep, described by
+// their task. IDs already on the command line are left out.
+func completeIDs(keep func(todo.Todo) bool) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		todos, err := completionTodos()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		used := make(map[string]bool, len(args))
+		for _, arg := range args {
+			used[arg] = true
+		}
+
+		var ids []string
+		for _, t := range todos {
+			id := strconv.Itoa(t.ID)
+			if used[id] || !strings.HasPrefix(id, toComplete) || !keep(t) {
+				continue
+			}
+			ids = append(ids, id+"\t"+describe(t.Task))
+		}
+		return ids, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// describe turns a task into a one-line completion description.
+func describe(task string) string {
+	return truncate(strings.Join(strings.Fields(task), " "), 60)
+}
+
+func isOpen(t todo.Todo) bool { return !t.IsDone() }
+
+func isAny(todo.Todo) bool { return true }
+
+// completeValues suggests values returned by values(todos), such as the
+// tags or lists in use. For comma-separated flags only the last element is
+// completed, and values already given are skipped.
+func completeValues(values func([]todo.Todo) []string) completionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		todos, err := completionTodos()
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		head, last := "", toComplete
+		used := make(map[string]bool)
+		if i := strings.LastIndex(toComplete, ","); i >= 0 {
+			head, last = toComplete[:i+1], toComplete[i+1:]
+			for _, v := range strings.Split(toComplete[:i], ",") {
+				used[v] = true
+			}
+		}
+
+		var matches []string
+		for _, v := range values(todos) {
+			if !used[v] && strings.HasPrefix(v, last) {
+				matches = append(matches, head+v)
+			}
+		}
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// fixedValues suggests a fixed set of flag values.
+func fixedValues(values ...string) completionFunc {
+	return cobra.FixedCompletions(values, cobra.ShellCompDirectiveNoFileComp)
+}
+
+// completeStatusArgs completes `todo status <id> <status>`.
+func completeStatusArgs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return completeIDs(isAny)(cmd, args, toComplete)
+	case 1:
+		if err := loadConfig(); err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		var statuses []string
+		for _, s := range todo.CurrentWorkflow() {
+			statuses = append(statuses, string(s))
+		}
+		return statuses, cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	rootCmd.AddCommand(completionCmd)
+}
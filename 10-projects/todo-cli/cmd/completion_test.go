@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+)
+
+// useCompletionStore points the completion helpers at a data file holding
+// todos.
+func useCompletionStore(t testing.TB, todos []todo.Todo) {
+	t.Helper()
+	dir := t.TempDir()
+	data, err := json.Marshal(todo.Document{Version: todo.CurrentSchemaVersion, Todos: todos})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "todos.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	oldCfg, oldData := cfgFile, dataFile
+	cfgFile, dataFile = filepath.Join(dir, "missing.json"), path
+	t.Cleanup(func() {
+		cfgFile, dataFile = oldCfg, oldData
+		todo.SetFilePath(todo.DefaultFilePath)
+	})
+}
+
+func TestCompleteIDs(t *testing.T) {
+	useCompletionStore(t, []todo.Todo{
+		{ID: 1, Task: "Write docs", Status: todo.StatusTodo},
+		{ID: 2, Task: "Ship release", Status: todo.StatusDone},
+		{ID: 12, Task: "Fix\tflaky test", Status: todo.StatusInProgress, Tags: []string{"ci", "bug"}, List: "work"},
+	})
+
+	got, _ := completeIDs(isOpen)(completeCmd, nil, "")
+	want := []string{"1\tWrite docs", "12\tFix flaky test"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected open IDs %q, got %q", want, got)
+	}
+
+	got, _ = completeIDs(isAny)(deleteCmd, []string{"12"}, "1")
+	want = []string{"1\tWrite docs"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected IDs not yet given, got %q", got)
+	}
+
+	got, _ = completeValues(todo.Tags)(addCmd, nil, "ci,")
+	want = []string{"ci,bug"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected tag completion %q, got %q", want, got)
+	}
+
+	got, _ = completeValues(todo.Lists)(listCmd, nil, "")
+	if !reflect.DeepEqual(got, []string{"work"}) {
+		t.Errorf("Expected list completion [work], got %q", got)
+	}
+}
+
+func BenchmarkCompleteIDs(b *testing.B) {
+	todos := make([]todo.Todo, 5000)
+	for i := range todos {
+		todos[i] = todo.Todo{ID: i + 1, Task: fmt.Sprintf("Task number %d", i+1), Status: todo.StatusTodo}
+	}
+	useCompletionStore(b, todos)
+	complete := completeIDs(isOpen)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		complete(completeCmd, nil, "4")
+	}
+}
@@ -5,9 +5,7 @@ package cmd
 
 import (
 	"fmt"
-	"strconv"
 
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
 	"github.com/spf13/cobra"
 )
 
@@ -27,28 +25,62 @@ to quickly create a Cobra application.`,
 } */
 
 var deleteCmd = &cobra.Command{
-	Use:   "delete [id]",
-	Short: "Delete a todo by ID",
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		repo := todo.NewRepository()
-		id, err := strconv.Atoi(args[0])
+	Use:   "delete [id...]",
+	Short: "Delete one or more todos by ID",
+	Long: `Delete removes todos. IDs can be listed individually or as ranges, for
+example:
+
+  todo delete 3 5 8-12
+
+Deleting more than one todo asks for confirmation unless --yes is given.
+Either all todos are deleted or, if any ID does not exist, none are.`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: completeIDs(isAny),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		ids, err := parseIDs(args)
 		if err != nil {
-			fmt.Println("Invalid ID:", args[0])
-			return
+			return err
+		}
+
+		yes, _ := cmd.Flags().GetBool("yes")
+		if len(ids) > 1 && !yes {
+			todos, err := deps.service.ListTasks()
+			if err != nil {
+				return err
+			}
+			set := make(map[int]bool, len(ids))
+			for _, id := range ids {
+				set[id] = true
+			}
+			for _, t := range todos {
+				if set[t.ID] {
+					fmt.Fprintf(out, "  %d: %s\n", t.ID, t.Task)
+				}
+			}
+			if !confirm(cmd.InOrStdin(), out, fmt.Sprintf("Delete %d todos?", len(ids))) {
+				fmt.Fprintln(out, "Aborted, nothing was deleted.")
+				return nil
+			}
+		}
+
+		results, err := deps.service.DeleteTasks(ids)
+		if len(ids) > 1 {
+			return printResults(out, results, err, "deleted")
 		}
-		err = repo.Delete(id)
 		if err != nil {
-			fmt.Println("Error:", err)
-			return
+			return firstError(results, err)
 		}
-		fmt.Println("Todo deleted successfully!")
+		fmt.Fprintln(out, "Todo deleted successfully!")
+		return nil
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(deleteCmd)
 
+	deleteCmd.Flags().BoolP("yes", "y", false, "Delete several todos without asking for confirmation")
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+)
+
+// dependencies are what the commands work with. Output goes through the
+// command's writers (cmd.OutOrStdout), which run sets.
+type dependencies struct {
+	repo    todo.Repository
+	service *todo.Service
+	now     func() time.Time
+}
+
+func newDependencies(repo todo.Repository, now func() time.Time) dependencies {
+	return dependencies{repo: repo, service: todo.NewService(repo), now: now}
+}
+
+// deps default to the data file and the system clock. Tests replace them
+// to run the commands against an in-memory store.
+var deps = newDependencies(todo.NewRepository(), time.Now)
+
+// exitError makes run exit with a specific code, for example the one a
+// plugin exited with, without printing anything.
+type exitError struct {
+	code int
+}
+
+func (e *exitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.code)
+}
+
+// run executes the command line args with the given streams and returns
+// the exit code: 0 on success, 1 on errors.
+func run(args []string, in io.Reader, out, errOut io.Writer) int {
+	rootCmd.SetArgs(args)
+	rootCmd.SetIn(in)
+	rootCmd.SetOut(out)
+	rootCmd.SetErr(errOut)
+
+	err := rootCmd.Execute()
+	finishLogging(err)
+	if err == nil {
+		return 0
+	}
+	var exit *exitError
+	if errors.As(err, &exit) {
+		return exit.code
+	}
+	fmt.Fprintln(errOut, "Error:", err)
+	return 1
+}
@@ -0,0 +1,72 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+	"github.com/spf13/cobra"
+)
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the data file for problems and optionally repair them",
+	Long: `Doctor validates the todo data file: it reports malformed entries,
+empty tasks and duplicate or invalid IDs. Pass --fix to repair them; the
+original file is kept next to it with a .bak suffix. It exits with status
+1 if problems are found and not repaired.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		repo, ok := deps.repo.(checker)
+		if !ok {
+			return errors.New("doctor only works with the data file")
+		}
+		fix, _ := cmd.Flags().GetBool("fix")
+
+		var report todo.Report
+		var err error
+		if fix {
+			report, err = repo.Repair()
+		} else {
+			report, err = repo.Check()
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Checked %s (schema v%d, %d entries)\n", report.Path, report.Version, report.Total)
+		if report.Version < todo.CurrentSchemaVersion && (!fix || report.Healthy()) {
+			fmt.Fprintf(out, "File will be migrated to schema v%d on next use.\n", todo.CurrentSchemaVersion)
+		}
+		if report.Healthy() {
+			fmt.Fprintln(out, "No problems found.")
+			return nil
+		}
+
+		for _, issue := range report.Issues {
+			fmt.Fprintf(out, "  entry %d: %s → %s\n", issue.Index, issue.Problem, issue.Repair)
+		}
+		if fix {
+			fmt.Fprintf(out, "Repaired %d problem(s). Backup written to %s.bak\n", len(report.Issues), report.Path)
+			return nil
+		}
+		return fmt.Errorf("found %d problem(s), run `todo doctor --fix` to repair them", len(report.Issues))
+	},
+}
+
+// checker is implemented by stores that can be checked and repaired.
+type checker interface {
+	Check() (todo.Report, error)
+	Repair() (todo.Report, error)
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().Bool("fix", false, "Repair the problems found")
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/ics"
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export todos as JSON or iCalendar",
+	Long: `Export writes all todos to stdout or to the file given with --output.
+
+  --format json   all todos as a JSON array
+  --format ics    todos with a due date as iCalendar VTODO entries`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+
+		todos, err := deps.service.ListTasks()
+		if err != nil {
+			return err
+		}
+
+		w := cmd.OutOrStdout()
+		if output != "" {
+			f, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+
+		switch format {
+		case "json":
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(todos)
+		case "ics":
+			err = ics.Encode(w, todos, deps.now())
+		default:
+			err = fmt.Errorf("unknown format %q, expected json or ics", format)
+		}
+		if err != nil {
+			return err
+		}
+		if output != "" {
+			fmt.Fprintf(cmd.OutOrStdout(), "Exported %d todos to %s\n", len(todos), output)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringP("format", "f", "json", "Output format: json or ics")
+	exportCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	exportCmd.RegisterFlagCompletionFunc("format", fixedValues("json", "ics"))
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+)
+
+// maxRangeSize guards against typos such as 1-1000000.
+const maxRangeSize = 10000
+
+// parseIDs turns arguments such as "3", "5,6" and "8-12" into a list of
+// unique IDs in the order given.
+func parseIDs(args []string) ([]int, error) {
+	var ids []int
+	seen := make(map[int]bool)
+	add := func(id int) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	for _, arg := range args {
+		for _, part := range strings.Split(arg, ",") {
+			if part == "" {
+				continue
+			}
+			from, to, isRange := strings.Cut(part, "-")
+			start, err := strconv.Atoi(from)
+			if err != nil || start <= 0 {
+				return nil, fmt.Errorf("invalid ID: %s", part)
+			}
+			if !isRange {
+				add(start)
+				continue
+			}
+			end, err := strconv.Atoi(to)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid ID range: %s", part)
+			}
+			if end-start >= maxRangeSize {
+				return nil, fmt.Errorf("ID range %s is too large", part)
+			}
+			for id := start; id <= end; id++ {
+				add(id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no IDs given")
+	}
+	return ids, nil
+}
+
+// printResults reports the outcome of a bulk operation per ID.
+func printResults(w io.Writer, results []todo.Result, err error, verb string) error {
+	var bulkErr *todo.BulkError
+	if err != nil && !errors.As(err, &bulkErr) {
+		return err
+	}
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			fmt.Fprintf(w, "  %d: %v\n", r.ID, r.Err)
+		case bulkErr != nil:
+			fmt.Fprintf(w, "  %d: ok, not %s\n", r.ID, verb)
+		default:
+			fmt.Fprintf(w, "  %d: %s\n", r.ID, verb)
+		}
+	}
+	if bulkErr != nil {
+		return bulkErr
+	}
+	fmt.Fprintf(w, "%d todos %s.\n", len(results), verb)
+	return nil
+}
+
+// firstError returns the per-ID error of a failed operation, falling back
+// to err itself.
+func firstError(results []todo.Result, err error) error {
+	for _, r := range results {
+		if r.Err != nil {
+			return r.Err
+		}
+	}
+	return err
+}
+
+// confirm asks a yes/no question and reads the answer from in. Anything
+// but y or yes, including end of input, means no.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIDs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		want    []int
+		wantErr bool
+	}{
+		{[]string{"3"}, []int{3}, false},
+		{[]string{"3", "5", "8-10"}, []int{3, 5, 8, 9, 10}, false},
+		{[]string{"1,2", "2-3"}, []int{1, 2, 3}, false},
+		{[]string{"4-4"}, []int{4}, false},
+		{[]string{"abc"}, nil, true},
+		{[]string{"0"}, nil, true},
+		{[]string{"-3"}, nil, true},
+		{[]string{"5-2"}, nil, true},
+		{[]string{"1-x"}, nil, true},
+		{[]string{"1-100000"}, nil, true},
+		{[]string{","}, nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseIDs(tt.args)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseIDs(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseIDs(%q) = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
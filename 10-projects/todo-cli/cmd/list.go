@@ -4,7 +4,9 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
 	"github.com/spf13/cobra"
@@ -28,30 +30,71 @@ to quickly create a Cobra application.`,
 var listCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all todos",
-	Run: func(cmd *cobra.Command, args []string) {
-		repo := todo.NewRepository()
-		todos, err := repo.List()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		var filter todo.Filter
+		filter.Tag, _ = cmd.Flags().GetString("tag")
+		filter.List, _ = cmd.Flags().GetString("list")
+		todos, err := deps.service.FindTasks(filter)
 		if err != nil {
-			fmt.Println("Error:", err)
-			return
+			return err
+		}
+
+		if outputFormat == "json" {
+			enc := json.NewEncoder(out)
+			enc.SetIndent("", "  ")
+			return enc.Encode(todos)
 		}
 		if len(todos) == 0 {
-			fmt.Println("No todos found.")
-			return
+			fmt.Fprintln(out, "No todos found.")
+			return nil
 		}
 		for _, t := range todos {
 			status := "[ ]"
-			if t.Completed {
+			switch {
+			case t.IsDone():
 				status = "[x]"
+			case t.Status != todo.CurrentWorkflow().Initial():
+				status = "[~]"
 			}
-			fmt.Printf("%s %d: %s\n", status, t.ID, t.Task)
+			fmt.Fprintf(out, "%s %d: %s%s\n", status, t.ID, t.Task, details(t))
 		}
+		return nil
 	},
 }
 
+// details formats the in-between status, due date and priority of a todo.
+func details(t todo.Todo) string {
+	var parts []string
+	if !t.IsDone() && t.Status != todo.CurrentWorkflow().Initial() {
+		parts = append(parts, string(t.Status))
+	}
+	if t.Due != "" {
+		parts = append(parts, "due "+t.Due)
+	}
+	if t.Priority != todo.PriorityNone {
+		parts = append(parts, string(t.Priority))
+	}
+	if t.List != "" {
+		parts = append(parts, "list "+t.List)
+	}
+	for _, tag := range t.Tags {
+		parts = append(parts, "#"+tag)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
 func init() {
 	rootCmd.AddCommand(listCmd)
 
+	listCmd.Flags().String("tag", "", "Only show todos with this tag")
+	listCmd.Flags().StringP("list", "l", "", "Only show todos in this list")
+	listCmd.RegisterFlagCompletionFunc("tag", completeValues(todo.Tags))
+	listCmd.RegisterFlagCompletionFunc("list", completeValues(todo.Lists))
+
 	// Here you will define your flags and configuration settings.
 
 	// Cobra supports Persistent Flags which will work for this command
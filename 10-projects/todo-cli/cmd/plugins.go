@@ -0,0 +1,218 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/config"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/plugin"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// pluginsCmd represents the plugins command
+var pluginsCmd = &cobra.Command{
+	Use:   "plugins",
+	Short: "List external todo-<name> plugins",
+	Long: `Any executable named todo-<name> in the plugins directory or on $PATH
+can be run as "todo <name>". The plugins directory is $TODO_PLUGINS_DIR,
+"plugins_dir" in the config file or ~/.todo-cli/plugins.
+
+Plugins receive the CLI settings through the environment:
+
+  TODO_DATA_FILE      absolute path of the data file
+  TODO_CONFIG         path of the config file
+  TODO_OUTPUT_FORMAT  text or json
+  TODO_STATUSES       comma-separated workflow statuses`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		out := cmd.OutOrStdout()
+		found := plugin.Discover(plugin.SearchPath(pluginsDir()))
+		if len(found) == 0 {
+			fmt.Fprintln(out, "No plugins found.")
+			return
+		}
+		for _, p := range found {
+			note := ""
+			if builtin(p.Name) {
+				note = " (shadowed by built-in command)"
+			}
+			fmt.Fprintf(out, "%s\t%s%s\n", p.Name, p.Path, note)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pluginsCmd)
+}
+
+// registerPlugins adds a command for every discovered plugin that does
+// not clash with a built-in one. It runs before cobra parses the command
+// line, so the persistent flags are parsed here first to honor --config.
+// Scanning $PATH is skipped when args already name a built-in command.
+func registerPlugins(args []string) {
+	if !needsPlugins(args) {
+		return
+	}
+
+	// Flags after the plugin name belong to the plugin.
+	skip := globalFlagArgs(args)
+	pre := pflag.NewFlagSet("plugins", pflag.ContinueOnError)
+	pre.ParseErrorsWhitelist.UnknownFlags = true
+	pre.Usage = func() {}
+	pre.AddFlagSet(rootCmd.PersistentFlags())
+	pre.Parse(args[:skip])
+
+	// Errors surface again when the command runs.
+	loadConfig()
+
+	for _, p := range plugin.Discover(plugin.SearchPath(pluginsDir())) {
+		if builtin(p.Name) {
+			continue
+		}
+		p := p
+		rootCmd.AddCommand(&cobra.Command{
+			Use:                p.Name,
+			Short:              "Plugin " + p.Path,
+			Annotations:        map[string]string{pluginAnnotation: p.Path},
+			DisableFlagParsing: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				// Cobra leaves the global flags given before the plugin
+				// name in args; they were applied above.
+				n := skip
+				if n > len(args) {
+					n = len(args)
+				}
+				return runPlugin(cmd, p, args[n:])
+			},
+		})
+	}
+}
+
+// needsPlugins reports whether args may run a plugin: they name an
+// unknown subcommand, or none so that help lists the plugins. Shell
+// completion is never one of them, as it runs on every tab press.
+func needsPlugins(args []string) bool {
+	if len(args) > 0 && (args[0] == cobra.ShellCompRequestCmd || args[0] == cobra.ShellCompNoDescRequestCmd) {
+		return false
+	}
+	c, _, err := rootCmd.Find(args)
+	return err != nil || c == rootCmd
+}
+
+// globalFlagArgs returns how many of the leading args are persistent
+// flags and their values, up to the plugin name. It understands --name,
+// --name=value, --name value, -v, -x value, -xvalue, -x=value and
+// combined shorthands like -vx value.
+func globalFlagArgs(args []string) int {
+	flags := rootCmd.PersistentFlags()
+	n := 0
+	for n < len(args) {
+		arg := args[n]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			break
+		}
+
+		if strings.HasPrefix(arg, "--") {
+			name, _, hasValue := strings.Cut(arg[2:], "=")
+			f := flags.Lookup(name)
+			if f == nil {
+				break
+			}
+			n++
+			if !hasValue && f.NoOptDefVal == "" {
+				n++
+			}
+			continue
+		}
+
+		shorts := arg[1:]
+		for i := 0; i < len(shorts); i++ {
+			f := flags.ShorthandLookup(shorts[i : i+1])
+			if f == nil {
+				return n
+			}
+			if f.NoOptDefVal != "" {
+				if i+1 < len(shorts) && shorts[i+1] == '=' {
+					break // -v=false
+				}
+				continue
+			}
+			if i+1 == len(shorts) {
+				n++ // the value is the next argument
+			}
+			break // the rest of arg is the value
+		}
+		n++
+	}
+	if n > len(args) {
+		return len(args)
+	}
+	return n
+}
+
+// runPlugin runs p. If the plugin fails, the CLI exits with its exit code.
+func runPlugin(cmd *cobra.Command, p plugin.Plugin, args []string) error {
+	c := p.Command(args, pluginContext())
+	c.Stdin, c.Stdout, c.Stderr = cmd.InOrStdin(), cmd.OutOrStdout(), cmd.ErrOrStderr()
+	err := c.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return &exitError{code: exitErr.ExitCode()}
+	}
+	return err
+}
+
+func pluginContext() plugin.Context {
+	path, err := filepath.Abs(todo.FilePath())
+	if err != nil {
+		path = todo.FilePath()
+	}
+	var statuses []string
+	for _, s := range todo.CurrentWorkflow() {
+		statuses = append(statuses, string(s))
+	}
+	return plugin.Context{
+		DataFile:     path,
+		ConfigFile:   cfgPath,
+		OutputFormat: outputFormat,
+		Statuses:     statuses,
+	}
+}
+
+func pluginsDir() string {
+	if dir := os.Getenv("TODO_PLUGINS_DIR"); dir != "" {
+		return dir
+	}
+	if cfg.PluginsDir != "" {
+		return cfg.PluginsDir
+	}
+	return config.DefaultPluginsDir()
+}
+
+// pluginAnnotation marks commands added by registerPlugins.
+const pluginAnnotation = "plugin"
+
+// builtin reports whether name is taken by a built-in command.
+func builtin(name string) bool {
+	if name == "help" || name == "completion" {
+		return true
+	}
+	for _, c := range rootCmd.Commands() {
+		if _, isPlugin := c.Annotations[pluginAnnotation]; isPlugin {
+			continue
+		}
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
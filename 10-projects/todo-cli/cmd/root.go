@@ -4,8 +4,13 @@ Copyright © 2025 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"time"
 
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/config"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/pkg/logger"
 	"github.com/spf13/cobra"
 )
 
@@ -28,15 +33,118 @@ var rootCmd = &cobra.Command{
 	Use:   "todo",
 	Short: "todo is a CLI-based todo manager",
 	Long:  `A simple CLI app to manage your todo tasks using Go.`,
+	// run prints errors itself so that plugins can set the exit code.
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// Arguments are valid at this point, so later errors are not
+		// usage errors.
+		cmd.SilenceUsage = true
+		if err := setupLogging(); err != nil {
+			return err
+		}
+		started = time.Now()
+		logger.Debug("command started", "command", cmd.CommandPath(), "args", args)
+		if err := loadConfig(); err != nil {
+			return err
+		}
+		logger.Debug("config loaded", "config", cfgPath, "data_file", todo.FilePath(), "statuses", len(todo.CurrentWorkflow()))
+		return nil
+	},
+}
+
+var (
+	logLevel  string
+	logFile   string
+	logFormat string
+	verbose   bool
+	started   time.Time // zero until a command starts running
+)
+
+// setupLogging configures pkg/logger from the --log-* flags. --verbose is
+// a shortcut for --log-level debug.
+func setupLogging() error {
+	level := logLevel
+	if verbose {
+		level = "debug"
+	}
+	return logger.Setup(logger.Options{Level: level, Format: logFormat, File: logFile})
+}
+
+// finishLogging logs how the command ended and closes the log file. It
+// runs after every command, as cobra skips PersistentPostRun on errors.
+func finishLogging(err error) {
+	if !started.IsZero() {
+		if err != nil {
+			logger.Debug("command failed", "error", err, "took", time.Since(started))
+		} else {
+			logger.Debug("command finished", "took", time.Since(started))
+		}
+		started = time.Time{}
+	}
+	logger.Close()
+}
+
+var (
+	cfgFile      string
+	dataFile     string
+	outputFormat string
+)
+
+// cfg and cfgPath hold the config loaded by loadConfig.
+var (
+	cfg     config.Config
+	cfgPath string
+)
+
+// loadConfig reads the config file and applies it to the todo package.
+// The data file is taken from --file, $TODO_FILE, the config file or the
+// default, in that order.
+func loadConfig() error {
+	path := cfgFile
+	if path == "" {
+		path = config.DefaultPath()
+	}
+	loaded, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	cfg, cfgPath = loaded, path
+
+	switch outputFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("invalid output format %q, expected text or json", outputFormat)
+	}
+
+	file := dataFile
+	if file == "" {
+		file = os.Getenv("TODO_FILE")
+	}
+	if file == "" {
+		file = cfg.DataFile
+	}
+	if file == "" {
+		file = todo.DefaultFilePath
+	}
+	todo.SetFilePath(file)
+
+	if len(cfg.Statuses) > 0 {
+		workflow := make(todo.Workflow, len(cfg.Statuses))
+		for i, s := range cfg.Statuses {
+			workflow[i] = todo.Status(s)
+		}
+		if err := todo.SetWorkflow(workflow); err != nil {
+			return fmt.Errorf("invalid statuses in %s: %w", path, err)
+		}
+	}
+	return nil
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
-	if err != nil {
-		os.Exit(1)
-	}
+	registerPlugins(os.Args[1:])
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
 
 func init() {
@@ -44,7 +152,16 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.todo-cli.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.todo-cli.json)")
+	rootCmd.PersistentFlags().StringVar(&dataFile, "file", "", "todo data file (default is $TODO_FILE or data/todos.json)")
+	rootCmd.PersistentFlags().StringVar(&outputFormat, "output-format", "text", "output format: text or json")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "warn", "log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "write logs to this file instead of stderr, rotated at 10MB")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "log format: text or json")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "log what the command does (same as --log-level debug)")
+	rootCmd.RegisterFlagCompletionFunc("output-format", fixedValues("text", "json"))
+	rootCmd.RegisterFlagCompletionFunc("log-level", fixedValues("debug", "info", "warn", "error"))
+	rootCmd.RegisterFlagCompletionFunc("log-format", fixedValues("text", "json"))
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
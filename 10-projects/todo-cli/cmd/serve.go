@@ -0,0 +1,87 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/server"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/pkg/logger"
+	"github.com/spf13/cobra"
+)
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve todos over a local HTTP API and web page",
+	Long: `Serve starts a web server with a JSON API under /api/todos and a web page
+at /. It uses the same data file and locking as the CLI, so both can be
+used at the same time. Todos with a due date are published as an
+iCalendar feed at /calendar.ics for calendar apps to subscribe to.
+
+If a token is set (--token or $TODO_TOKEN), API clients must send
+"Authorization: Bearer <token>" and browsers must open the page once
+with ?token=<token>. Calendar subscriptions use
+/calendar.ics?token=<token>. Without a token, the server only listens on
+a loopback address and only answers requests for localhost.
+
+Changes from pages of other sites are refused, and API requests that
+change todos must be sent as Content-Type: application/json.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+		addr, _ := cmd.Flags().GetString("addr")
+		token, _ := cmd.Flags().GetString("token")
+		if token == "" {
+			token = os.Getenv("TODO_TOKEN")
+		}
+		if token == "" && !server.IsLoopback(addr) {
+			return fmt.Errorf("refusing to serve on %s without a token: set --token or $TODO_TOKEN, or listen on 127.0.0.1", addr)
+		}
+
+		srv := &http.Server{
+			Addr:              addr,
+			Handler:           server.New(deps.service, token),
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       10 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			IdleTimeout:       time.Minute,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			srv.Shutdown(shutdownCtx)
+		}()
+
+		fmt.Fprintf(out, "Serving todos at http://%s\n", addr)
+		if token == "" {
+			fmt.Fprintln(out, "Warning: no token set, every program on this machine can change your todos.")
+		}
+		logger.Info("serving", "addr", addr, "data_file", todo.FilePath(), "token", token != "")
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().String("addr", "127.0.0.1:7070", "Address to listen on")
+	serveCmd.Flags().String("token", "", "Bearer token required for access (default $TODO_TOKEN)")
+}
@@ -0,0 +1,41 @@
+/*
+Copyright © 2025 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status [id] [status]",
+	Short: "Move a todo to another workflow status",
+	Long: `Status moves a todo to any status of the workflow, for example:
+
+  todo status 3 in-progress
+
+The workflow defaults to todo, in-progress, review, blocked and done and
+can be changed with "statuses" in the config file.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeStatusArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid ID: %s", args[0])
+		}
+		status, err := deps.service.MoveTask(id, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Todo moved to %s!\n", status)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+}
@@ -1,3 +1,65 @@
 package config
 
-// You can define environment-based config here if needed
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// FileName is the config file looked up in the home directory.
+const FileName = ".todo-cli.json"
+
+// Config holds user settings read from the config file.
+type Config struct {
+	// Statuses is the workflow todos move through, from the status new
+	// todos start in to the one `todo complete` sets.
+	Statuses []string `json:"statuses,omitempty"`
+
+	// DataFile is the todo data file. $TODO_FILE and --file override it.
+	DataFile string `json:"data_file,omitempty"`
+
+	// PluginsDir is searched for todo-<name> plugins before $PATH.
+	// $TODO_PLUGINS_DIR overrides it.
+	PluginsDir string `json:"plugins_dir,omitempty"`
+}
+
+// DefaultPath returns $TODO_CONFIG, or the config file in the home
+// directory.
+func DefaultPath() string {
+	if path := os.Getenv("TODO_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return FileName
+	}
+	return filepath.Join(home, FileName)
+}
+
+// Load reads the config file at path. A missing file yields the zero
+// Config, so every setting falls back to its default.
+func Load(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// DefaultPluginsDir returns the plugins directory used when none is
+// configured.
+func DefaultPluginsDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".todo-cli", "plugins")
+}
@@ -0,0 +1,118 @@
+// Package ics renders todos as an RFC 5545 iCalendar document.
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+)
+
+// ProdID identifies this program in generated calendars.
+const ProdID = "-//neotylor//todo-cli//EN"
+
+// maxLineOctets is the longest content line allowed before folding.
+const maxLineOctets = 75
+
+// Encode writes every todo that has a due date as a VTODO component.
+// now is used for DTSTAMP so that output is reproducible.
+func Encode(w io.Writer, todos []todo.Todo, now time.Time) error {
+	bw := bufio.NewWriter(w)
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	writeLine(bw, "BEGIN:VCALENDAR")
+	writeLine(bw, "VERSION:2.0")
+	writeLine(bw, "PRODID:"+ProdID)
+	writeLine(bw, "CALSCALE:GREGORIAN")
+	writeLine(bw, "X-WR-CALNAME:todo")
+
+	for _, t := range todos {
+		due, ok := t.DueDate()
+		if !ok {
+			continue
+		}
+
+		writeLine(bw, "BEGIN:VTODO")
+		writeLine(bw, "UID:"+uid(t))
+		writeLine(bw, "DTSTAMP:"+stamp)
+		writeLine(bw, "SUMMARY:"+escapeText(t.Task))
+		writeLine(bw, "DUE;VALUE=DATE:"+due.Format("20060102"))
+		switch {
+		case t.IsDone():
+			writeLine(bw, "STATUS:COMPLETED")
+			writeLine(bw, "PERCENT-COMPLETE:100")
+		case t.Status == "" || t.Status == todo.CurrentWorkflow().Initial():
+			writeLine(bw, "STATUS:NEEDS-ACTION")
+		default:
+			writeLine(bw, "STATUS:IN-PROCESS")
+		}
+		if p := priority(t.Priority); p != 0 {
+			writeLine(bw, fmt.Sprintf("PRIORITY:%d", p))
+		}
+		writeLine(bw, "END:VTODO")
+	}
+
+	writeLine(bw, "END:VCALENDAR")
+	return bw.Flush()
+}
+
+// uid returns the todo's stored UID. Todos that were never saved have
+// none and fall back to the form older data files were migrated to.
+func uid(t todo.Todo) string {
+	if t.UID != "" {
+		return t.UID
+	}
+	return fmt.Sprintf("todo-%d@todo-cli", t.ID)
+}
+
+// priority maps to the iCalendar scale, where 1 is highest, 9 is lowest
+// and 0 means undefined (RFC 5545 section 3.8.1.9).
+func priority(p todo.Priority) int {
+	switch p {
+	case todo.PriorityHigh:
+		return 1
+	case todo.PriorityMedium:
+		return 5
+	case todo.PriorityLow:
+		return 9
+	default:
+		return 0
+	}
+}
+
+// escapeText escapes a TEXT property value (RFC 5545 section 3.3.11).
+func escapeText(s string) string {
+	r := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	)
+	return r.Replace(s)
+}
+
+// writeLine writes a content line terminated by CRLF, folding it so that
+// no physical line exceeds 75 octets (RFC 5545 section 3.1). Folds never
+// split a UTF-8 sequence.
+func writeLine(w *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space, which counts.
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
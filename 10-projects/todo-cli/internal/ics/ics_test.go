@@ -0,0 +1,104 @@
+package ics
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+var stamp = time.Date(2025, 6, 1, 9, 30, 0, 0, time.UTC)
+
+func TestEncodeGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		todos []todo.Todo
+	}{
+		{"empty", nil},
+		{"basic", []todo.Todo{
+			{ID: 1, Task: "Write report", Due: "2025-06-10", Priority: todo.PriorityHigh},
+			{ID: 2, Task: "No due date, skipped"},
+			{ID: 3, Task: "Ship release", Status: todo.StatusDone, Due: "2025-06-02", Priority: todo.PriorityLow},
+			{ID: 4, Task: "Plan sprint", Due: "2025-06-15", Priority: todo.PriorityMedium, UID: "0f3c9a7e@todo-cli"},
+			{ID: 5, Task: "Review PR", Status: todo.StatusInProgress, Due: "2025-06-05"},
+		}},
+		{"escaping", []todo.Todo{
+			{ID: 7, Task: "Buy milk, eggs; and \\ butter\nthen cook", Due: "2025-07-01"},
+			{ID: 8, Task: "A very long task description that certainly goes past the seventy-five octet limit — with ünïcödé", Due: "2025-07-02"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, tt.todos, stamp); err != nil {
+				t.Fatalf("Encode failed: %v", err)
+			}
+			checkCompliance(t, buf.Bytes())
+
+			golden := filepath.Join("testdata", tt.name+".golden.ics")
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("Failed to read golden file (run with -update): %v", err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("Output does not match %s\n got:\n%s\nwant:\n%s", golden, buf.Bytes(), want)
+			}
+		})
+	}
+}
+
+// checkCompliance asserts the structural RFC 5545 rules that are easy to
+// break: CRLF line endings, 75-octet lines and balanced components.
+func checkCompliance(t *testing.T, data []byte) {
+	t.Helper()
+
+	if !bytes.HasSuffix(data, []byte("\r\n")) {
+		t.Errorf("Output must end with CRLF")
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\r\n"), "\r\n")
+	depth := 0
+	for i, line := range lines {
+		if strings.Contains(line, "\n") || strings.Contains(line, "\r") {
+			t.Errorf("Line %d contains a bare line break: %q", i+1, line)
+		}
+		if len(line) > maxLineOctets {
+			t.Errorf("Line %d is %d octets long: %q", i+1, len(line), line)
+		}
+		switch {
+		case strings.HasPrefix(line, "BEGIN:"):
+			depth++
+		case strings.HasPrefix(line, "END:"):
+			depth--
+		}
+	}
+	if depth != 0 {
+		t.Errorf("Unbalanced BEGIN/END components")
+	}
+	if lines[0] != "BEGIN:VCALENDAR" || lines[len(lines)-1] != "END:VCALENDAR" {
+		t.Errorf("Output must be a single VCALENDAR")
+	}
+}
+
+func TestUnfoldRestoresLongLines(t *testing.T) {
+	long := strings.Repeat("é", 100)
+	var buf bytes.Buffer
+	Encode(&buf, []todo.Todo{{ID: 1, Task: long, Due: "2025-01-01"}}, stamp)
+
+	unfolded := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	if !strings.Contains(unfolded, "SUMMARY:"+long+"\r\n") {
+		t.Errorf("Unfolded output lost content:\n%s", unfolded)
+	}
+}
@@ -0,0 +1,134 @@
+// Package plugin finds and runs external todo-<name> executables, in the
+// same way git runs git-<name>.
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// Prefix is the file name prefix that marks an executable as a plugin.
+const Prefix = "todo-"
+
+// Environment variables passed to every plugin.
+const (
+	EnvDataFile     = "TODO_DATA_FILE"
+	EnvConfig       = "TODO_CONFIG"
+	EnvOutputFormat = "TODO_OUTPUT_FORMAT"
+	EnvStatuses     = "TODO_STATUSES"
+)
+
+// Plugin is an executable exposed as `todo <Name>`.
+type Plugin struct {
+	Name string
+	Path string
+}
+
+// Context is what a plugin is told about the running CLI.
+type Context struct {
+	DataFile     string
+	ConfigFile   string
+	OutputFormat string
+	Statuses     []string
+}
+
+// Env returns the environment variables describing ctx.
+func (ctx Context) Env() []string {
+	return []string{
+		EnvDataFile + "=" + ctx.DataFile,
+		EnvConfig + "=" + ctx.ConfigFile,
+		EnvOutputFormat + "=" + ctx.OutputFormat,
+		EnvStatuses + "=" + strings.Join(ctx.Statuses, ","),
+	}
+}
+
+// SearchPath returns the plugins directory, if any, followed by $PATH.
+func SearchPath(pluginsDir string) []string {
+	var dirs []string
+	if pluginsDir != "" {
+		dirs = append(dirs, pluginsDir)
+	}
+	return append(dirs, filepath.SplitList(os.Getenv("PATH"))...)
+}
+
+// Discover returns the plugins found in dirs, sorted by name. When two
+// directories contain the same plugin the earlier directory wins.
+func Discover(dirs []string) []Plugin {
+	found := make(map[string]Plugin)
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := pluginName(e.Name())
+			if !ok || e.IsDir() {
+				continue
+			}
+			if _, seen := found[name]; seen {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			if !isExecutable(path) {
+				continue
+			}
+			found[name] = Plugin{Name: name, Path: path}
+		}
+	}
+
+	plugins := make([]Plugin, 0, len(found))
+	for _, p := range found {
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins
+}
+
+// Command returns an exec.Cmd that runs the plugin with args and the
+// environment of ctx, wired to the standard streams.
+func (p Plugin) Command(args []string, ctx Context) *exec.Cmd {
+	c := exec.Command(p.Path, args...)
+	c.Env = append(os.Environ(), ctx.Env()...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c
+}
+
+// pluginName extracts the command name from a file name such as
+// todo-report or, on Windows, todo-report.exe.
+func pluginName(file string) (string, bool) {
+	if !strings.HasPrefix(file, Prefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(file, Prefix)
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(name))
+		if ext != ".exe" && ext != ".bat" && ext != ".cmd" {
+			return "", false
+		}
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return "", false
+	}
+	return name, true
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return true // the extension was checked in pluginName
+	}
+	return info.Mode()&0111 != 0
+}
@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, path string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho \"$TODO_DATA_FILE\"\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses unix permission bits")
+	}
+
+	first, second := t.TempDir(), t.TempDir()
+	writeFile(t, filepath.Join(first, "todo-report"), 0755)
+	writeFile(t, filepath.Join(first, "todo-notes"), 0644) // not executable
+	writeFile(t, filepath.Join(first, "other-tool"), 0755)
+	writeFile(t, filepath.Join(second, "todo-report"), 0755) // shadowed
+	writeFile(t, filepath.Join(second, "todo-sync"), 0755)
+
+	plugins := Discover([]string{first, second, filepath.Join(first, "missing")})
+
+	if len(plugins) != 2 {
+		t.Fatalf("Expected 2 plugins, got %+v", plugins)
+	}
+	if plugins[0].Name != "report" || plugins[0].Path != filepath.Join(first, "todo-report") {
+		t.Errorf("Expected report from the first directory, got %+v", plugins[0])
+	}
+	if plugins[1].Name != "sync" {
+		t.Errorf("Expected sync plugin, got %+v", plugins[1])
+	}
+}
+
+func TestCommandEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "todo-echo"), 0755)
+	p := Discover([]string{dir})[0]
+
+	c := p.Command(nil, Context{DataFile: "/tmp/todos.json", OutputFormat: "json", Statuses: []string{"todo", "done"}})
+	c.Stdout = nil
+	out, err := c.Output()
+	if err != nil {
+		t.Fatalf("Plugin failed: %v", err)
+	}
+	if strings.TrimSpace(string(out)) != "/tmp/todos.json" {
+		t.Errorf("Expected data file in environment, got %q", out)
+	}
+
+	env := strings.Join(c.Env, "\n")
+	for _, want := range []string{"TODO_OUTPUT_FORMAT=json", "TODO_STATUSES=todo,done"} {
+		if !strings.Contains(env, want) {
+			t.Errorf("Expected %s in environment", want)
+		}
+	}
+}
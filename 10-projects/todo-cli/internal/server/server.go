@@ -0,0 +1,397 @@
+// Package server exposes a todo.Service over a small JSON API and a web
+// page, for `todo serve`.
+package server
+
+import (
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/ics"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/pkg/logger"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var pageTemplate = template.Must(template.ParseFS(templateFS, "templates/index.html"))
+
+// tokenCookie carries the bearer token for browsers, which cannot set an
+// Authorization header on plain form posts.
+const tokenCookie = "todo_token"
+
+// CalendarPath is the stable iCalendar feed URL for calendar subscriptions.
+const CalendarPath = "/calendar.ics"
+
+// Server serves the todo API and web page.
+type Server struct {
+	service *todo.Service
+	token   string
+	mux     *http.ServeMux
+}
+
+// New returns a Server backed by service, so that todos are validated the
+// same way as on the command line. If token is not empty every request
+// must present it as a bearer token.
+func New(service *todo.Service, token string) *Server {
+	s := &Server{service: service, token: token, mux: http.NewServeMux()}
+
+	s.mux.HandleFunc("/", s.handleIndex)
+	s.mux.HandleFunc("/add", s.handleFormAdd)
+	s.mux.HandleFunc("/complete", s.handleFormComplete)
+	s.mux.HandleFunc("/delete", s.handleFormDelete)
+	s.mux.HandleFunc("/status", s.handleFormStatus)
+	s.mux.HandleFunc("/api/todos", s.handleTodos)
+	s.mux.HandleFunc("/api/todos/", s.handleTodo)
+	s.mux.HandleFunc(CalendarPath, s.handleCalendar)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.serve(rec, r)
+	logger.Info("request", "method", r.Method, "path", r.URL.Path, "status", rec.status, "took", time.Since(start))
+}
+
+// statusRecorder remembers the status code for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	// Without a token, only this machine is trusted. A page that rebinds
+	// its own DNS name to 127.0.0.1 still sends that name as Host.
+	if s.token == "" && !IsLoopback(r.Host) {
+		writeError(w, http.StatusForbidden, "host not allowed: "+r.Host)
+		return
+	}
+	// Other sites may post forms here too; browsers tell them apart by
+	// Origin and Sec-Fetch-Site.
+	if !safeMethod(r.Method) && crossOrigin(r) {
+		writeError(w, http.StatusForbidden, "cross-origin request refused")
+		return
+	}
+	// Browsers cannot send JSON across origins without asking first, so
+	// the API only takes JSON.
+	if strings.HasPrefix(r.URL.Path, "/api/") && r.Method == http.MethodPost && !isJSON(r.Header.Get("Content-Type")) {
+		writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+	if s.token == "" {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+
+	// Calendar apps can only subscribe to a plain URL, so the feed accepts
+	// the token as a query parameter.
+	if r.URL.Path == CalendarPath && s.validToken(r.URL.Query().Get("token")) {
+		s.mux.ServeHTTP(w, r)
+		return
+	}
+
+	// Opening the page with ?token=... stores it in a cookie for the forms.
+	if q := r.URL.Query().Get("token"); q != "" && r.Method == http.MethodGet && s.validToken(q) {
+		http.SetCookie(w, &http.Cookie{Name: tokenCookie, Value: q, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		query := r.URL.Query()
+		query.Del("token")
+		http.Redirect(w, r, r.URL.Path+"?"+query.Encode(), http.StatusSeeOther)
+		return
+	}
+
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="todo"`)
+		writeError(w, http.StatusUnauthorized, "missing or invalid token")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// IsLoopback reports whether addr, a host with an optional port, names
+// this machine only: localhost or a loopback IP. An empty host, which
+// listens on every interface, is not.
+func IsLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// crossOrigin reports whether a browser sent r from a page of another
+// origin. Requests without these headers, like those of curl, are not.
+func crossOrigin(r *http.Request) bool {
+	if r.Header.Get("Sec-Fetch-Site") == "cross-site" {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err != nil || !strings.EqualFold(u.Host, r.Host)
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return s.validToken(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if c, err := r.Cookie(tokenCookie); err == nil {
+		return s.validToken(c.Value)
+	}
+	return false
+}
+
+func (s *Server) validToken(token string) bool {
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// filterFromQuery reads the status and q parameters.
+func filterFromQuery(q url.Values) todo.Filter {
+	return todo.Filter{Status: q.Get("status"), Query: q.Get("q")}
+}
+
+// handleTodos serves GET and POST on /api/todos.
+func (s *Server) handleTodos(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		todos, err := s.service.FindTasks(filterFromQuery(r.URL.Query()))
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, todos)
+
+	case http.MethodPost:
+		var req struct {
+			Task     string `json:"task"`
+			Due      string `json:"due"`
+			Priority string `json:"priority"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		created, err := s.service.AddTask(todo.Todo{Task: req.Task, Due: req.Due, Priority: todo.Priority(req.Priority)})
+		if err != nil {
+			writeError(w, errorStatus(err), err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// handleTodo serves DELETE /api/todos/{id}, POST /api/todos/{id}/complete
+// and POST /api/todos/{id}/status.
+func (s *Server) handleTodo(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/todos/")
+	idPart, action, _ := strings.Cut(rest, "/")
+
+	id, err := strconv.Atoi(idPart)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "invalid ID: "+idPart)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodDelete:
+		err = s.service.DeleteTask(id)
+	case action == "complete" && r.Method == http.MethodPost:
+		err = s.service.CompleteTask(id)
+	case action == "status" && r.Method == http.MethodPost:
+		var req struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		_, err = s.service.MoveTask(id, req.Status)
+	case action == "" || action == "complete" || action == "status":
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	if err != nil {
+		writeError(w, errorStatus(err), err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errorStatus maps a service error to an HTTP status.
+func errorStatus(err error) int {
+	var input *todo.InputError
+	switch {
+	case errors.Is(err, todo.ErrNotFound), errors.Is(err, todo.ErrInvalidID):
+		return http.StatusNotFound
+	case errors.As(err, &input):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// handleCalendar serves todos with due dates as an iCalendar feed.
+func (s *Server) handleCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	todos, err := s.service.ListTasks()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="todo.ics"`)
+	ics.Encode(w, todos, time.Now())
+}
+
+type pageData struct {
+	Todos    []todo.Todo
+	Statuses todo.Workflow
+	Status   string
+	Query    string
+	Error    string
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter := filterFromQuery(r.URL.Query())
+	data := pageData{
+		Statuses: todo.CurrentWorkflow(),
+		Status:   filter.Status,
+		Query:    filter.Query,
+		Error:    r.URL.Query().Get("error"),
+	}
+
+	todos, err := s.service.FindTasks(filter)
+	if err != nil {
+		data.Error = err.Error()
+	} else {
+		data.Todos = todos
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := pageTemplate.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleFormAdd(w http.ResponseWriter, r *http.Request) {
+	s.handleForm(w, r, func() error {
+		_, err := s.service.AddTask(todo.Todo{
+			Task:     r.PostFormValue("task"),
+			Due:      r.PostFormValue("due"),
+			Priority: todo.Priority(r.PostFormValue("priority")),
+		})
+		return err
+	})
+}
+
+func (s *Server) handleFormComplete(w http.ResponseWriter, r *http.Request) {
+	s.handleForm(w, r, func() error {
+		id, err := strconv.Atoi(r.PostFormValue("id"))
+		if err != nil {
+			return errors.New("invalid ID")
+		}
+		return s.service.CompleteTask(id)
+	})
+}
+
+func (s *Server) handleFormStatus(w http.ResponseWriter, r *http.Request) {
+	s.handleForm(w, r, func() error {
+		id, err := strconv.Atoi(r.PostFormValue("id"))
+		if err != nil {
+			return errors.New("invalid ID")
+		}
+		_, err = s.service.MoveTask(id, r.PostFormValue("to"))
+		return err
+	})
+}
+
+func (s *Server) handleFormDelete(w http.ResponseWriter, r *http.Request) {
+	s.handleForm(w, r, func() error {
+		id, err := strconv.Atoi(r.PostFormValue("id"))
+		if err != nil {
+			return errors.New("invalid ID")
+		}
+		return s.service.DeleteTask(id)
+	})
+}
+
+// handleForm runs a form action and redirects back to the page, keeping
+// the current filters and reporting any error.
+func (s *Server) handleForm(w http.ResponseWriter, r *http.Request, action func() error) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	back := url.Values{}
+	if status := r.PostFormValue("status"); status != "" {
+		back.Set("status", status)
+	}
+	if q := r.PostFormValue("q"); q != "" {
+		back.Set("q", q)
+	}
+	if err := action(); err != nil {
+		back.Set("error", err.Error())
+	}
+	http.Redirect(w, r, "/?"+back.Encode(), http.StatusSeeOther)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/internal/todo"
+)
+
+// fakeRepo is a minimal in-memory todo.Repository for handler tests.
+type fakeRepo struct {
+	todos []todo.Todo
+}
+
+func (f *fakeRepo) Add(t todo.Todo) (todo.Todo, error) {
+	t.ID = len(f.todos) + 1
+	t.Status = todo.CurrentWorkflow().Initial()
+	f.todos = append(f.todos, t)
+	return t, nil
+}
+
+func (f *fakeRepo) List() ([]todo.Todo, error) {
+	return append([]todo.Todo(nil), f.todos...), nil
+}
+
+func (f *fakeRepo) Complete(id int) error {
+	return f.SetStatus(id, todo.CurrentWorkflow().Final())
+}
+
+func (f *fakeRepo) SetStatus(id int, status todo.Status) error {
+	for i := range f.todos {
+		if f.todos[i].ID == id {
+			f.todos[i].Status = status
+			return nil
+		}
+	}
+	return todo.ErrNotFound
+}
+
+func (f *fakeRepo) Delete(id int) error {
+	for i := range f.todos {
+		if f.todos[i].ID == id {
+			f.todos = append(f.todos[:i], f.todos[i+1:]...)
+			return nil
+		}
+	}
+	return todo.ErrNotFound
+}
+
+func (f *fakeRepo) CompleteMany(ids []int) ([]todo.Result, error) {
+	var results []todo.Result
+	for _, id := range ids {
+		results = append(results, todo.Result{ID: id, Err: f.Complete(id)})
+	}
+	return results, nil
+}
+
+func (f *fakeRepo) DeleteMany(ids []int) ([]todo.Result, error) {
+	var results []todo.Result
+	for _, id := range ids {
+		results = append(results, todo.Result{ID: id, Err: f.Delete(id)})
+	}
+	return results, nil
+}
+
+func do(t *testing.T, h http.Handler, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Host = "localhost:7070"
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	if host := header["Host"]; host != "" {
+		req.Host = host
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+// jsonBody is the header the API requires on POST requests.
+var jsonBody = map[string]string{"Content-Type": "application/json"}
+
+func TestAPIAddCompleteDelete(t *testing.T) {
+	repo := &fakeRepo{}
+	srv := New(todo.NewService(repo), "")
+
+	rr := do(t, srv, http.MethodPost, "/api/todos", `{"task":"Write docs"}`, jsonBody)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rr.Code, rr.Body)
+	}
+	var created todo.Todo
+	json.NewDecoder(rr.Body).Decode(&created)
+	if created.ID != 1 || created.Task != "Write docs" {
+		t.Fatalf("Unexpected created todo: %+v", created)
+	}
+
+	rr = do(t, srv, http.MethodPost, "/api/todos/1/complete", "", jsonBody)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 on complete, got %d", rr.Code)
+	}
+
+	rr = do(t, srv, http.MethodGet, "/api/todos?status=done", "", nil)
+	var done []todo.Todo
+	json.NewDecoder(rr.Body).Decode(&done)
+	if len(done) != 1 {
+		t.Fatalf("Expected 1 done todo, got %+v", done)
+	}
+
+	rr = do(t, srv, http.MethodDelete, "/api/todos/1", "", nil)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 on delete, got %d", rr.Code)
+	}
+
+	rr = do(t, srv, http.MethodDelete, "/api/todos/1", "", nil)
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing todo, got %d", rr.Code)
+	}
+}
+
+func TestAPIRejectsEmptyTask(t *testing.T) {
+	srv := New(todo.NewService(&fakeRepo{}), "")
+
+	rr := do(t, srv, http.MethodPost, "/api/todos", `{"task":"  "}`, jsonBody)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", rr.Code)
+	}
+}
+
+func TestToken(t *testing.T) {
+	srv := New(todo.NewService(&fakeRepo{}), "secret")
+
+	if rr := do(t, srv, http.MethodGet, "/api/todos", "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", rr.Code)
+	}
+	if rr := do(t, srv, http.MethodGet, "/api/todos", "", map[string]string{"Authorization": "Bearer wrong"}); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with wrong token, got %d", rr.Code)
+	}
+	if rr := do(t, srv, http.MethodGet, "/api/todos", "", map[string]string{"Authorization": "Bearer secret"}); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 with token, got %d", rr.Code)
+	}
+
+	rr := do(t, srv, http.MethodGet, "/?token=secret", "", nil)
+	if rr.Code != http.StatusSeeOther || len(rr.Result().Cookies()) != 1 {
+		t.Fatalf("Expected redirect setting a cookie, got %d", rr.Code)
+	}
+	cookie := rr.Result().Cookies()[0]
+	if rr := do(t, srv, http.MethodGet, "/", "", map[string]string{"Cookie": cookie.Name + "=" + cookie.Value}); rr.Code != http.StatusOK {
+		t.Errorf("Expected 200 with token cookie, got %d", rr.Code)
+	}
+}
+
+func TestPageForms(t *testing.T) {
+	repo := &fakeRepo{}
+	srv := New(todo.NewService(repo), "")
+	form := map[string]string{"Content-Type": "application/x-www-form-urlencoded"}
+
+	rr := do(t, srv, http.MethodPost, "/add", url.Values{"task": {"Buy milk"}}.Encode(), form)
+	if rr.Code != http.StatusSeeOther || len(repo.todos) != 1 {
+		t.Fatalf("Expected todo to be added, got %d %+v", rr.Code, repo.todos)
+	}
+
+	do(t, srv, http.MethodPost, "/complete", url.Values{"id": {"1"}}.Encode(), form)
+	if !repo.todos[0].IsDone() {
+		t.Errorf("Expected todo to be completed")
+	}
+
+	rr = do(t, srv, http.MethodGet, "/?status=done", "", nil)
+	if !strings.Contains(rr.Body.String(), "Buy milk") {
+		t.Errorf("Expected page to list the completed todo")
+	}
+	rr = do(t, srv, http.MethodGet, "/?status=open", "", nil)
+	if strings.Contains(rr.Body.String(), "Buy milk") {
+		t.Errorf("Expected open filter to hide the completed todo")
+	}
+
+	do(t, srv, http.MethodPost, "/delete", url.Values{"id": {"1"}}.Encode(), form)
+	if len(repo.todos) != 0 {
+		t.Errorf("Expected todo to be deleted")
+	}
+}
+
+func TestPageShowsUnknownStatus(t *testing.T) {
+	repo := &fakeRepo{todos: []todo.Todo{{ID: 1, Task: "Ship", Status: "shipped"}}}
+
+	rr := do(t, New(todo.NewService(repo), ""), http.MethodGet, "/", "", nil)
+	if !strings.Contains(rr.Body.String(), "shipped (unknown)") {
+		t.Errorf("Expected the page to show the unknown status, got %s", rr.Body)
+	}
+}
+
+func TestCalendarFeed(t *testing.T) {
+	repo := &fakeRepo{}
+	repo.Add(todo.Todo{Task: "Pay rent", Due: "2025-07-01", Priority: todo.PriorityHigh})
+	repo.Add(todo.Todo{Task: "Someday"})
+	srv := New(todo.NewService(repo), "secret")
+
+	if rr := do(t, srv, http.MethodGet, CalendarPath, "", nil); rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", rr.Code)
+	}
+
+	rr := do(t, srv, http.MethodGet, CalendarPath+"?token=secret", "", nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("Unexpected content type %q", ct)
+	}
+	body := rr.Body.String()
+	if strings.Count(body, "BEGIN:VTODO") != 1 || !strings.Contains(body, "SUMMARY:Pay rent") {
+		t.Errorf("Expected one VTODO for the due todo, got:\n%s", body)
+	}
+}
+
+func TestAPIRejectsInvalidDue(t *testing.T) {
+	srv := New(todo.NewService(&fakeRepo{}), "")
+
+	rr := do(t, srv, http.MethodPost, "/api/todos", `{"task":"x","due":"tomorrow"}`, jsonBody)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400, got %d", rr.Code)
+	}
+}
+
+func TestAPIUsesServiceRules(t *testing.T) {
+	repo := &fakeRepo{}
+	srv := New(todo.NewService(repo), "")
+
+	rr := do(t, srv, http.MethodPost, "/api/todos", `{"task": "  Trim me  ", "priority": "HIGH"}`, jsonBody)
+	if rr.Code != http.StatusCreated || repo.todos[0].Task != "Trim me" || repo.todos[0].Priority != todo.PriorityHigh {
+		t.Errorf("Expected the service to normalize the todo, got %d %+v", rr.Code, repo.todos)
+	}
+	if rr = do(t, srv, http.MethodDelete, "/api/todos/0", "", nil); rr.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an invalid ID, got %d", rr.Code)
+	}
+}
+
+func TestAPISetStatus(t *testing.T) {
+	repo := &fakeRepo{}
+	repo.Add(todo.Todo{Task: "Review me"})
+	srv := New(todo.NewService(repo), "")
+
+	rr := do(t, srv, http.MethodPost, "/api/todos/1/status", `{"status":"review"}`, jsonBody)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d: %s", rr.Code, rr.Body)
+	}
+	if repo.todos[0].Status != todo.StatusReview {
+		t.Errorf("Expected status review, got %q", repo.todos[0].Status)
+	}
+
+	rr = do(t, srv, http.MethodPost, "/api/todos/1/status", `{"status":"unknown"}`, jsonBody)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown status, got %d", rr.Code)
+	}
+}
+
+func TestCrossSiteRequests(t *testing.T) {
+	repo := &fakeRepo{}
+	srv := New(todo.NewService(repo), "")
+	form := url.Values{"task": {"Pwned"}}.Encode()
+
+	// DNS rebinding: the attacker's name resolves to 127.0.0.1.
+	if rr := do(t, srv, http.MethodGet, "/api/todos", "", map[string]string{"Host": "evil.example:7070"}); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a foreign Host, got %d", rr.Code)
+	}
+	// CSRF: a form on another site posting here.
+	for _, header := range []map[string]string{
+		{"Content-Type": "application/x-www-form-urlencoded", "Origin": "http://evil.example"},
+		{"Content-Type": "application/x-www-form-urlencoded", "Sec-Fetch-Site": "cross-site"},
+		{"Content-Type": "application/json", "Origin": "null"},
+	} {
+		if rr := do(t, srv, http.MethodPost, "/add", form, header); rr.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 for a cross-site post with %v, got %d", header, rr.Code)
+		}
+	}
+	// Forms cannot send JSON, so the API refuses everything else.
+	if rr := do(t, srv, http.MethodPost, "/api/todos", `{"task":"Pwned"}`, map[string]string{"Content-Type": "text/plain"}); rr.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for a text/plain API post, got %d", rr.Code)
+	}
+	if len(repo.todos) != 0 {
+		t.Fatalf("Expected no todos from cross-site requests, got %+v", repo.todos)
+	}
+
+	// The page itself posts with its own origin.
+	same := map[string]string{"Content-Type": "application/x-www-form-urlencoded", "Origin": "http://localhost:7070", "Sec-Fetch-Site": "same-origin"}
+	if rr := do(t, srv, http.MethodPost, "/add", url.Values{"task": {"Mine"}}.Encode(), same); rr.Code != http.StatusSeeOther || len(repo.todos) != 1 {
+		t.Errorf("Expected a same-origin post to add a todo, got %d %+v", rr.Code, repo.todos)
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1:7070": true,
+		"localhost:7070": true,
+		"[::1]:7070":     true,
+		"localhost":      true,
+		"[::1]":          true,
+		":7070":          false,
+		"0.0.0.0:7070":   false,
+		"192.168.1.5":    false,
+		"evil.example":   false,
+	}
+	for addr, want := range tests {
+		if got := IsLoopback(addr); got != want {
+			t.Errorf("IsLoopback(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
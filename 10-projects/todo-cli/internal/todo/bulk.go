@@ -0,0 +1,105 @@
+package todo
+
+import "fmt"
+
+// Result is the outcome of a bulk operation for one ID.
+type Result struct {
+	ID  int
+	Err error // nil if the change was applied
+}
+
+// BulkError is returned by bulk operations when at least one ID failed.
+// In that case none of the changes are written.
+type BulkError struct {
+	Failed int
+	Total  int
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d of %d IDs failed, no changes were made", e.Failed, e.Total)
+}
+
+// checkIDs returns a result per ID and a *BulkError if any ID is missing
+// from todos.
+func checkIDs(todos []Todo, ids []int) ([]Result, error) {
+	exists := make(map[int]bool, len(todos))
+	for _, t := range todos {
+		exists[t.ID] = true
+	}
+
+	results := make([]Result, len(ids))
+	failed := 0
+	for i, id := range ids {
+		results[i] = Result{ID: id}
+		if !exists[id] {
+			results[i].Err = ErrNotFound
+			failed++
+		}
+	}
+	if failed > 0 {
+		return results, &BulkError{Failed: failed, Total: len(ids)}
+	}
+	return results, nil
+}
+
+// idSet returns ids as a set.
+func idSet(ids []int) map[int]bool {
+	set := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// CompleteMany moves every todo in ids to the final status. Either all of
+// them are completed or, if any ID is missing, none are.
+func (r *FileRepository) CompleteMany(ids []int) ([]Result, error) {
+	var results []Result
+	err := r.update(func(todos []Todo) ([]Todo, error) {
+		var err error
+		todos, results, err = completeMany(todos, ids)
+		return todos, err
+	})
+	return results, err
+}
+
+// DeleteMany deletes every todo in ids. Either all of them are deleted or,
+// if any ID is missing, none are.
+func (r *FileRepository) DeleteMany(ids []int) ([]Result, error) {
+	var results []Result
+	err := r.update(func(todos []Todo) ([]Todo, error) {
+		var err error
+		todos, results, err = deleteMany(todos, ids)
+		return todos, err
+	})
+	return results, err
+}
+
+func completeMany(todos []Todo, ids []int) ([]Todo, []Result, error) {
+	results, err := checkIDs(todos, ids)
+	if err != nil {
+		return nil, results, err
+	}
+	set := idSet(ids)
+	for i := range todos {
+		if set[todos[i].ID] {
+			todos[i].Status = workflow.Final()
+		}
+	}
+	return todos, results, nil
+}
+
+func deleteMany(todos []Todo, ids []int) ([]Todo, []Result, error) {
+	results, err := checkIDs(todos, ids)
+	if err != nil {
+		return nil, results, err
+	}
+	set := idSet(ids)
+	kept := make([]Todo, 0, len(todos))
+	for _, t := range todos {
+		if !set[t.ID] {
+			kept = append(kept, t)
+		}
+	}
+	return kept, results, nil
+}
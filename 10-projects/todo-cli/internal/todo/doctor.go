@@ -0,0 +1,177 @@
+package todo
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/pkg/logger"
+)
+
+// Issue is a single problem found in the data file.
+type Issue struct {
+	Index   int    // position of the entry in the file
+	ID      int    // ID of the entry, 0 if it could not be read
+	Problem string // what is wrong
+	Repair  string // what Repair will do about it
+}
+
+// Report is the result of checking the data file.
+type Report struct {
+	Path    string
+	Version int // schema version found on disk
+	Total   int // number of entries in the file
+	Issues  []Issue
+}
+
+// Healthy reports whether no issues were found.
+func (r Report) Healthy() bool {
+	return len(r.Issues) == 0
+}
+
+// Check validates the data file without modifying it.
+func (r *FileRepository) Check() (Report, error) {
+	var report Report
+	err := withLock(func() error {
+		var err error
+		report, _, _, err = inspect()
+		return err
+	})
+	return report, err
+}
+
+// Repair fixes the issues reported by Check. Malformed entries are dropped
+// and duplicate or invalid IDs are renumbered. The original file is backed
+// up before anything is written.
+func (r *FileRepository) Repair() (Report, error) {
+	var report Report
+	err := withLock(func() error {
+		var todos []Todo
+		var original []byte
+		var err error
+		report, todos, original, err = inspect()
+		if err != nil || report.Healthy() {
+			return err
+		}
+
+		if err := writeBackup(filePath+".bak", original); err != nil {
+			return err
+		}
+		logger.Info("repairing data file", "path", filePath, "issues", len(report.Issues), "backup", filePath+".bak")
+		return saveTodos(todos)
+	})
+	return report, err
+}
+
+// inspect reads the data file and returns the report, the todos as they
+// would look after repair and the raw file contents. Callers hold the lock.
+func inspect() (Report, []Todo, []byte, error) {
+	report := Report{Path: filePath, Version: CurrentSchemaVersion}
+
+	file, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return report, []Todo{}, nil, nil
+		}
+		return report, nil, nil, err
+	}
+
+	doc, err := decodeRawDocument(file)
+	if err != nil {
+		return report, nil, file, err
+	}
+	report.Version = doc.Version
+	report.Total = len(doc.Todos)
+	if err := migrateDocument(doc); err != nil {
+		return report, nil, file, err
+	}
+
+	var kept []Todo
+	var renumber []int // indexes into kept that need a new ID
+	seen := make(map[int]bool)
+	maxID := 0
+
+	for i, raw := range doc.Todos {
+		var t Todo
+		if err := json.Unmarshal(raw, &t); err != nil {
+			report.Issues = append(report.Issues, Issue{
+				Index:   i,
+				Problem: fmt.Sprintf("malformed entry: %v", err),
+				Repair:  "remove entry",
+			})
+			continue
+		}
+		if strings.TrimSpace(t.Task) == "" {
+			report.Issues = append(report.Issues, Issue{
+				Index:   i,
+				ID:      t.ID,
+				Problem: "empty task",
+				Repair:  "remove entry",
+			})
+			continue
+		}
+
+		if _, err := ParseDue(t.Due); err != nil {
+			report.Issues = append(report.Issues, Issue{
+				Index:   i,
+				ID:      t.ID,
+				Problem: err.Error(),
+				Repair:  "clear due date",
+			})
+			t.Due = ""
+		}
+		if !workflow.Contains(t.Status) {
+			report.Issues = append(report.Issues, Issue{
+				Index:   i,
+				ID:      t.ID,
+				Problem: fmt.Sprintf("unknown status %q", t.Status),
+				Repair:  fmt.Sprintf("set status to %q", workflow.Initial()),
+			})
+			t.Status = workflow.Initial()
+		}
+		if _, err := ParsePriority(string(t.Priority)); err != nil {
+			report.Issues = append(report.Issues, Issue{
+				Index:   i,
+				ID:      t.ID,
+				Problem: err.Error(),
+				Repair:  "clear priority",
+			})
+			t.Priority = PriorityNone
+		}
+
+		switch {
+		case t.ID <= 0:
+			report.Issues = append(report.Issues, Issue{
+				Index:   i,
+				ID:      t.ID,
+				Problem: fmt.Sprintf("invalid ID %d", t.ID),
+				Repair:  "assign a new ID",
+			})
+			renumber = append(renumber, len(kept))
+		case seen[t.ID]:
+			report.Issues = append(report.Issues, Issue{
+				Index:   i,
+				ID:      t.ID,
+				Problem: fmt.Sprintf("duplicate ID %d", t.ID),
+				Repair:  "assign a new ID",
+			})
+			renumber = append(renumber, len(kept))
+		default:
+			seen[t.ID] = true
+			if t.ID > maxID {
+				maxID = t.ID
+			}
+		}
+		kept = append(kept, t)
+	}
+
+	for _, k := range renumber {
+		maxID++
+		kept[k].ID = maxID
+	}
+	if kept == nil {
+		kept = []Todo{}
+	}
+	return report, kept, file, nil
+}
@@ -0,0 +1,51 @@
+package todo
+
+import "strings"
+
+// Filter selects todos by status, task text, tag and list.
+type Filter struct {
+	Status string // "open", "done", a workflow status or "" for all
+	Query  string // case-insensitive substring of the task
+	Tag    string // exact tag, or "" for any
+	List   string // exact list name, or "" for any
+}
+
+// Match reports whether t passes the filter.
+func (f Filter) Match(t Todo) bool {
+	switch f.Status {
+	case "":
+	case "open":
+		if t.IsDone() {
+			return false
+		}
+	case "done":
+		if !t.IsDone() {
+			return false
+		}
+	default:
+		if !strings.EqualFold(string(t.Status), f.Status) {
+			return false
+		}
+	}
+	if f.Query != "" && !strings.Contains(strings.ToLower(t.Task), strings.ToLower(f.Query)) {
+		return false
+	}
+	if f.Tag != "" && !t.HasTag(f.Tag) {
+		return false
+	}
+	if f.List != "" && t.List != f.List {
+		return false
+	}
+	return true
+}
+
+// Apply returns the todos that match the filter.
+func (f Filter) Apply(todos []Todo) []Todo {
+	matched := make([]Todo, 0, len(todos))
+	for _, t := range todos {
+		if f.Match(t) {
+			matched = append(matched, t)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,56 @@
+package todo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/pkg/logger"
+)
+
+// lockTimeout is how long to wait for another process to release the
+// data file before giving up.
+var lockTimeout = 5 * time.Second
+
+// staleLockAge is the age after which a lock file is assumed to belong to
+// a process that died without cleaning up.
+const staleLockAge = 30 * time.Second
+
+// lockDataFile takes an exclusive lock on the data file by creating a
+// sibling .lock file. It works across processes and platforms, so a
+// running `todo serve` and the CLI never interleave writes. The returned
+// function releases the lock.
+func lockDataFile() (func(), error) {
+	path := filePath + ".lock"
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	deadline := start.Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			if waited := time.Since(start); waited > 100*time.Millisecond {
+				logger.Debug("waited for data file lock", "path", path, "took", waited)
+			}
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, statErr := os.Stat(path); statErr == nil && time.Since(info.ModTime()) > staleLockAge {
+			logger.Warn("removing stale lock file", "path", path, "age", time.Since(info.ModTime()))
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("data file is locked by another process (remove %s if it is stale)", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
@@ -0,0 +1,88 @@
+package todo
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MemoryRepository keeps todos in memory. It behaves like FileRepository
+// without touching the disk, which makes it useful for tests.
+type MemoryRepository struct {
+	mu    sync.Mutex
+	todos []Todo
+}
+
+var _ Repository = (*MemoryRepository)(nil)
+
+// NewMemoryRepository returns a repository holding a copy of todos.
+func NewMemoryRepository(todos ...Todo) *MemoryRepository {
+	return &MemoryRepository{todos: append([]Todo{}, todos...)}
+}
+
+// update applies fn to a copy of the todos and keeps the result only if
+// fn succeeds, matching the all-or-nothing writes of FileRepository.
+func (r *MemoryRepository) update(fn func(todos []Todo) ([]Todo, error)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todos, err := fn(append([]Todo{}, r.todos...))
+	if err != nil {
+		return err
+	}
+	r.todos = todos
+	return nil
+}
+
+func (r *MemoryRepository) Add(todo Todo) (Todo, error) {
+	err := r.update(func(todos []Todo) ([]Todo, error) {
+		todos, todo = addTodo(todos, todo)
+		return todos, nil
+	})
+	return todo, err
+}
+
+func (r *MemoryRepository) List() ([]Todo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Todo{}, r.todos...), nil
+}
+
+// Complete moves a todo to the final workflow status.
+func (r *MemoryRepository) Complete(id int) error {
+	return r.SetStatus(id, workflow.Final())
+}
+
+func (r *MemoryRepository) SetStatus(id int, status Status) error {
+	if !workflow.Contains(status) {
+		return fmt.Errorf("unknown status %q", status)
+	}
+	return r.update(func(todos []Todo) ([]Todo, error) {
+		return setStatus(todos, id, status)
+	})
+}
+
+func (r *MemoryRepository) Delete(id int) error {
+	return r.update(func(todos []Todo) ([]Todo, error) {
+		return deleteTodo(todos, id)
+	})
+}
+
+func (r *MemoryRepository) CompleteMany(ids []int) ([]Result, error) {
+	var results []Result
+	err := r.update(func(todos []Todo) ([]Todo, error) {
+		var err error
+		todos, results, err = completeMany(todos, ids)
+		return todos, err
+	})
+	return results, err
+}
+
+func (r *MemoryRepository) DeleteMany(ids []int) ([]Result, error) {
+	var results []Result
+	err := r.update(func(todos []Todo) ([]Todo, error) {
+		var err error
+		todos, results, err = deleteMany(todos, ids)
+		return todos, err
+	})
+	return results, err
+}
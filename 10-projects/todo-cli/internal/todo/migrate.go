@@ -0,0 +1,166 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// rawDocument is the envelope with its entries left undecoded, so that
+// migrations and the doctor can work on files Todo no longer matches.
+type rawDocument struct {
+	Version int               `json:"version"`
+	Todos   []json.RawMessage `json:"todos"`
+}
+
+// migration upgrades a document from one schema version to the next.
+type migration func(doc *rawDocument) error
+
+// migrations is keyed by the version a migration upgrades from.
+var migrations = map[int]migration{
+	1: migrateV1ToV2,
+	2: migrateV2ToV3,
+	3: migrateV3ToV4,
+	4: migrateV4ToV5,
+	5: migrateV5ToV6,
+}
+
+// migrateV1ToV2 only introduces the envelope; entries are unchanged.
+func migrateV1ToV2(doc *rawDocument) error {
+	return nil
+}
+
+// migrateV2ToV3 adds the optional due and priority fields. Existing entries
+// are valid as they are; the bump keeps older builds from rewriting the
+// file and dropping the new fields.
+func migrateV2ToV3(doc *rawDocument) error {
+	return nil
+}
+
+// migrateV3ToV4 replaces the completed flag with a workflow status:
+// completed todos get the final status and the rest the initial one.
+func migrateV3ToV4(doc *rawDocument) error {
+	for i, raw := range doc.Todos {
+		var entry map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			continue // left for `todo doctor` to report
+		}
+		if _, ok := entry["status"]; !ok {
+			var completed bool
+			json.Unmarshal(entry["completed"], &completed)
+			status := workflow.Initial()
+			if completed {
+				status = workflow.Final()
+			}
+			entry["status"], _ = json.Marshal(status)
+		}
+		delete(entry, "completed")
+
+		updated, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		doc.Todos[i] = updated
+	}
+	return nil
+}
+
+// migrateV4ToV5 adds the optional tags and list fields.
+func migrateV4ToV5(doc *rawDocument) error {
+	return nil
+}
+
+// migrateV5ToV6 gives every todo a UID. Existing todos keep the one the
+// calendar export derived from their ID, so subscribed calendars do not
+// see them as new events.
+func migrateV5ToV6(doc *rawDocument) error {
+	for i, raw := range doc.Todos {
+		var entry map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			continue // left for `todo doctor` to report
+		}
+		if _, ok := entry["uid"]; ok {
+			continue
+		}
+		var id int
+		json.Unmarshal(entry["id"], &id)
+		entry["uid"], _ = json.Marshal(fmt.Sprintf("todo-%d@todo-cli", id))
+
+		updated, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		doc.Todos[i] = updated
+	}
+	return nil
+}
+
+// decodeRawDocument parses the data file. Version 1 files are a bare JSON
+// array and are wrapped into an envelope before being returned.
+func decodeRawDocument(data []byte) (*rawDocument, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return &rawDocument{Version: CurrentSchemaVersion, Todos: []json.RawMessage{}}, nil
+	}
+
+	if data[0] == '[' {
+		var todos []json.RawMessage
+		if err := json.Unmarshal(data, &todos); err != nil {
+			return nil, fmt.Errorf("invalid data file: %w", err)
+		}
+		return &rawDocument{Version: 1, Todos: todos}, nil
+	}
+
+	var doc rawDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid data file: %w", err)
+	}
+	if doc.Version < 1 {
+		return nil, fmt.Errorf("invalid data file: missing schema version")
+	}
+	if doc.Todos == nil {
+		doc.Todos = []json.RawMessage{}
+	}
+	return &doc, nil
+}
+
+// migrateDocument runs every pending migration on doc in order.
+func migrateDocument(doc *rawDocument) error {
+	if doc.Version > CurrentSchemaVersion {
+		return fmt.Errorf("data file schema version %d is newer than supported version %d", doc.Version, CurrentSchemaVersion)
+	}
+	for doc.Version < CurrentSchemaVersion {
+		m, ok := migrations[doc.Version]
+		if !ok {
+			return fmt.Errorf("no migration from schema version %d", doc.Version)
+		}
+		if err := m(doc); err != nil {
+			return fmt.Errorf("migrating schema version %d: %w", doc.Version, err)
+		}
+		doc.Version++
+	}
+	return nil
+}
+
+// decodeTodos decodes every entry of doc, failing on the first bad one.
+func decodeTodos(doc *rawDocument) ([]Todo, error) {
+	todos := make([]Todo, 0, len(doc.Todos))
+	for i, raw := range doc.Todos {
+		var t Todo
+		if err := json.Unmarshal(raw, &t); err != nil {
+			return nil, fmt.Errorf("malformed todo at index %d (run `todo doctor`): %w", i, err)
+		}
+		todos = append(todos, t)
+	}
+	return todos, nil
+}
+
+// backupPath returns where the pre-migration copy of a version is kept.
+func backupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
+
+func writeBackup(path string, data []byte) error {
+	return os.WriteFile(path, data, 0644)
+}
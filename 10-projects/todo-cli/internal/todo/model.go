@@ -1,7 +1,149 @@
 package todo
 
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CurrentSchemaVersion is the data file format written by this build.
+// Bump it together with a new entry in migrations whenever the on-disk
+// layout of Todo changes.
+const CurrentSchemaVersion = 6
+
+// DueLayout is the date format used for due dates.
+const DueLayout = "2006-01-02"
+
+type Priority string
+
+const (
+	PriorityNone   Priority = ""
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+)
+
 type Todo struct {
-	ID        int    `json:"id"`
-	Task      string `json:"task"`
-	Completed bool   `json:"completed"`
+	ID       int      `json:"id"`
+	Task     string   `json:"task"`
+	Status   Status   `json:"status"`
+	Due      string   `json:"due,omitempty"`
+	Priority Priority `json:"priority,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	List     string   `json:"list,omitempty"`
+	UID      string   `json:"uid,omitempty"`
+}
+
+// IsDone reports whether the todo has reached the final workflow status.
+func (t Todo) IsDone() bool {
+	return t.Status == workflow.Final()
+}
+
+// DueDate returns the parsed due date, if the todo has one.
+func (t Todo) DueDate() (time.Time, bool) {
+	if t.Due == "" {
+		return time.Time{}, false
+	}
+	d, err := time.Parse(DueLayout, t.Due)
+	return d, err == nil
+}
+
+// NewUID returns a random identifier for a new todo. Unlike the ID, which
+// is reused once the newest todo is deleted, it is never handed out twice.
+func NewUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b) + "@todo-cli"
+}
+
+// ParseDue validates a due date given as YYYY-MM-DD.
+func ParseDue(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	d, err := time.Parse(DueLayout, s)
+	if err != nil {
+		return "", fmt.Errorf("invalid due date %q, expected YYYY-MM-DD", s)
+	}
+	return d.Format(DueLayout), nil
+}
+
+// ParsePriority validates a priority name.
+func ParsePriority(s string) (Priority, error) {
+	switch p := Priority(strings.ToLower(s)); p {
+	case PriorityNone, PriorityLow, PriorityMedium, PriorityHigh:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid priority %q, expected low, medium or high", s)
+	}
+}
+
+// HasTag reports whether the todo carries tag.
+func (t Todo) HasTag(tag string) bool {
+	for _, have := range t.Tags {
+		if have == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTags trims and de-duplicates tag names, keeping their order.
+func ParseTags(tags []string) ([]string, error) {
+	var parsed []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if strings.ContainsAny(tag, " \t,") {
+			return nil, fmt.Errorf("invalid tag %q, tags cannot contain spaces or commas", tag)
+		}
+		seen[tag] = true
+		parsed = append(parsed, tag)
+	}
+	return parsed, nil
+}
+
+// Tags returns the distinct tags used by todos, sorted.
+func Tags(todos []Todo) []string {
+	var values []string
+	for _, t := range todos {
+		values = append(values, t.Tags...)
+	}
+	return distinct(values)
+}
+
+// Lists returns the distinct list names used by todos, sorted.
+func Lists(todos []Todo) []string {
+	var values []string
+	for _, t := range todos {
+		if t.List != "" {
+			values = append(values, t.List)
+		}
+	}
+	return distinct(values)
+}
+
+func distinct(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Document is the versioned envelope stored in the data file.
+type Document struct {
+	Version int    `json:"version"`
+	Todos   []Todo `json:"todos"`
 }
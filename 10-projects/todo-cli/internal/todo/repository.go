@@ -3,102 +3,250 @@ package todo
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"sync"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/todo-cli/pkg/logger"
 )
 
 type Repository interface {
-	Add(todo Todo) error
+	Add(todo Todo) (Todo, error)
 	List() ([]Todo, error)
 	Delete(id int) error
 	Complete(id int) error
+	SetStatus(id int, status Status) error
+	CompleteMany(ids []int) ([]Result, error)
+	DeleteMany(ids []int) ([]Result, error)
 }
 
-var filePath = "data/todos.json"
+// DefaultFilePath is the data file used when none is configured.
+const DefaultFilePath = "data/todos.json"
+
+var filePath = DefaultFilePath
 var mu sync.Mutex
 
+// SetFilePath changes the data file used by FileRepository.
+func SetFilePath(path string) {
+	mu.Lock()
+	defer mu.Unlock()
+	filePath = path
+}
+
+// FilePath returns the data file used by FileRepository.
+func FilePath() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return filePath
+}
+
+// ErrNotFound is returned when no todo has the requested ID.
+var ErrNotFound = errors.New("todo not found")
+
 type FileRepository struct{}
 
+var _ Repository = (*FileRepository)(nil)
+
 func NewRepository() *FileRepository {
 	return &FileRepository{}
 }
 
-func (r *FileRepository) readTodos() ([]Todo, error) {
+// withLock runs fn while holding the in-process mutex and the data file
+// lock, so the CLI and `todo serve` can safely share one file.
+func withLock(fn func() error) error {
 	mu.Lock()
 	defer mu.Unlock()
 
+	unlock, err := lockDataFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return fn()
+}
+
+func (r *FileRepository) readTodos() ([]Todo, error) {
 	var todos []Todo
+	err := withLock(func() error {
+		var err error
+		todos, err = loadTodos()
+		return err
+	})
+	return todos, err
+}
+
+// update loads the todos, applies fn and writes the result back as one
+// locked operation. Nothing is written if fn returns an error.
+func (r *FileRepository) update(fn func(todos []Todo) ([]Todo, error)) error {
+	return withLock(func() error {
+		todos, err := loadTodos()
+		if err != nil {
+			return err
+		}
+		todos, err = fn(todos)
+		if err != nil {
+			return err
+		}
+		return saveTodos(todos)
+	})
+}
+
+// loadTodos reads the data file, migrating it in place if it uses an older
+// schema. Callers hold the lock.
+func loadTodos() ([]Todo, error) {
+	start := time.Now()
 	file, err := os.ReadFile(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
+			logger.Debug("data file does not exist yet", "path", filePath)
 			return []Todo{}, nil // file doesn't exist → return empty list
 		}
 		return nil, err
 	}
 
-	if len(file) == 0 { // ✅ fix: skip unmarshalling empty files
-		return []Todo{}, nil
+	doc, err := decodeRawDocument(file)
+	if err != nil {
+		return nil, err
 	}
 
-	err = json.Unmarshal(file, &todos)
-	return todos, err
-}
-
-func (r *FileRepository) writeTodos(todos []Todo) error {
-	mu.Lock()
-	defer mu.Unlock()
+	fromVersion := doc.Version
+	if err := migrateDocument(doc); err != nil {
+		return nil, err
+	}
 
-	data, err := json.MarshalIndent(todos, "", "  ")
+	todos, err := decodeTodos(doc)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return os.WriteFile(filePath, data, 0644)
+
+	// Old files are upgraded in place, keeping the original next to it.
+	if fromVersion != doc.Version {
+		backup := backupPath(filePath, fromVersion)
+		if err := writeBackup(backup, file); err != nil {
+			return nil, err
+		}
+		logger.Info("migrated data file", "path", filePath, "from", fromVersion, "to", doc.Version, "backup", backup)
+		if err := saveTodos(todos); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Debug("read data file", "path", filePath, "bytes", len(file), "version", fromVersion, "todos", len(todos), "took", time.Since(start))
+	return todos, nil
 }
 
-func (r *FileRepository) Add(task string) error {
-	// func (r *FileRepository) Add(todo Todo) error {
-	todos, err := r.readTodos()
+// saveTodos writes todos wrapped in the current envelope. The file is
+// replaced atomically so readers never see a partial write. Callers hold
+// the lock.
+func saveTodos(todos []Todo) error {
+	if todos == nil {
+		todos = []Todo{}
+	}
+	data, err := json.MarshalIndent(Document{Version: CurrentSchemaVersion, Todos: todos}, "", "  ")
 	if err != nil {
 		return err
 	}
-	id := 1
-	if len(todos) > 0 {
-		id = todos[len(todos)-1].ID + 1
+
+	start := time.Now()
+	tmp := filePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filePath); err != nil {
+		return err
 	}
-	newTodo := Todo{ID: id, Task: task, Completed: false}
-	todos = append(todos, newTodo)
-	return r.writeTodos(todos)
+	logger.Debug("wrote data file", "path", filePath, "bytes", len(data), "todos", len(todos), "took", time.Since(start))
+	return nil
+}
+
+func (r *FileRepository) Add(todo Todo) (Todo, error) {
+	err := r.update(func(todos []Todo) ([]Todo, error) {
+		todos, todo = addTodo(todos, todo)
+		return todos, nil
+	})
+	if err != nil {
+		return Todo{}, err
+	}
+	return todo, nil
 }
 
 func (r *FileRepository) List() ([]Todo, error) {
 	return r.readTodos()
 }
 
-func (r *FileRepository) Complete(id int) error {
-	todos, err := r.readTodos()
+// Snapshot returns the todos without taking the lock or migrating the file
+// on disk. Writes replace the file atomically, so the result is always a
+// consistent state. Shell completion uses it so that it never waits on a
+// running `todo serve`.
+func (r *FileRepository) Snapshot() ([]Todo, error) {
+	file, err := os.ReadFile(FilePath())
 	if err != nil {
-		return err
-	}
-	found := false
-	for i, t := range todos {
-		if t.ID == id {
-			todos[i].Completed = true
-			found = true
-			break
+		if os.IsNotExist(err) {
+			return []Todo{}, nil
 		}
+		return nil, err
 	}
-	if !found {
-		return errors.New("todo not found")
+	doc, err := decodeRawDocument(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrateDocument(doc); err != nil {
+		return nil, err
 	}
-	return r.writeTodos(todos)
+	return decodeTodos(doc)
+}
+
+// Complete moves a todo to the final workflow status.
+func (r *FileRepository) Complete(id int) error {
+	return r.SetStatus(id, workflow.Final())
+}
+
+func (r *FileRepository) SetStatus(id int, status Status) error {
+	if !workflow.Contains(status) {
+		return fmt.Errorf("unknown status %q", status)
+	}
+	return r.update(func(todos []Todo) ([]Todo, error) {
+		return setStatus(todos, id, status)
+	})
 }
 
 func (r *FileRepository) Delete(id int) error {
-	todos, err := r.readTodos()
-	if err != nil {
-		return err
+	return r.update(func(todos []Todo) ([]Todo, error) {
+		return deleteTodo(todos, id)
+	})
+}
+
+// addTodo appends todo with the next free ID, the initial status and a
+// UID if it has none.
+func addTodo(todos []Todo, todo Todo) ([]Todo, Todo) {
+	id := 0
+	for _, t := range todos {
+		if t.ID > id {
+			id = t.ID
+		}
+	}
+	todo.ID = id + 1
+	todo.Status = workflow.Initial()
+	if todo.UID == "" {
+		todo.UID = NewUID()
 	}
-	newTodos := make([]Todo, 0)
+	return append(todos, todo), todo
+}
+
+func setStatus(todos []Todo, id int, status Status) ([]Todo, error) {
+	for i, t := range todos {
+		if t.ID == id {
+			todos[i].Status = status
+			return todos, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func deleteTodo(todos []Todo, id int) ([]Todo, error) {
+	newTodos := make([]Todo, 0, len(todos))
 	found := false
 	for _, t := range todos {
 		if t.ID == id {
@@ -108,7 +256,7 @@ func (r *FileRepository) Delete(id int) error {
 		newTodos = append(newTodos, t)
 	}
 	if !found {
-		return errors.New("todo not found")
+		return nil, ErrNotFound
 	}
-	return r.writeTodos(newTodos)
+	return newTodos, nil
 }
@@ -1,8 +1,12 @@
 package todo
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -23,7 +27,7 @@ func TestAddAndList(t *testing.T) {
 	repo, tmp := setupTestRepo(t)
 	defer os.Remove(tmp)
 
-	err := repo.Add("Write Go tests")
+	_, err := repo.Add(Todo{Task: "Write Go tests"})
 	if err != nil {
 		t.Fatalf("Failed to add todo: %v", err)
 	}
@@ -46,11 +50,11 @@ func TestComplete(t *testing.T) {
 	repo, tmp := setupTestRepo(t)
 	defer os.Remove(tmp)
 
-	_ = repo.Add("Complete this task")
+	_, _ = repo.Add(Todo{Task: "Complete this task"})
 	_ = repo.Complete(1)
 
 	todos, _ := repo.List()
-	if !todos[0].Completed {
+	if !todos[0].IsDone() {
 		t.Errorf("Expected todo to be completed")
 	}
 }
@@ -59,7 +63,7 @@ func TestDelete(t *testing.T) {
 	repo, tmp := setupTestRepo(t)
 	defer os.Remove(tmp)
 
-	_ = repo.Add("Delete me")
+	_, _ = repo.Add(Todo{Task: "Delete me"})
 	_ = repo.Delete(1)
 
 	todos, _ := repo.List()
@@ -87,3 +91,348 @@ func TestDeleteInvalidID(t *testing.T) {
 		t.Errorf("Expected error when deleting invalid ID")
 	}
 }
+
+func TestMigrateBareArray(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+	defer os.Remove(backupPath(tmp, 1))
+
+	legacy := `[{"id":1,"task":"Old task","completed":true}]`
+	if err := os.WriteFile(tmp, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	todos, err := repo.List()
+	if err != nil {
+		t.Fatalf("Failed to list legacy todos: %v", err)
+	}
+	if len(todos) != 1 || todos[0].Task != "Old task" || todos[0].Status != StatusDone {
+		t.Fatalf("Unexpected todos after migration: %+v", todos)
+	}
+
+	backup, err := os.ReadFile(backupPath(tmp, 1))
+	if err != nil {
+		t.Fatalf("Expected backup of legacy file: %v", err)
+	}
+	if string(backup) != legacy {
+		t.Errorf("Backup does not match original file: %s", backup)
+	}
+
+	data, _ := os.ReadFile(tmp)
+	var doc Document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Migrated file is not an envelope: %v", err)
+	}
+	if doc.Version != CurrentSchemaVersion || len(doc.Todos) != 1 {
+		t.Errorf("Unexpected migrated document: %+v", doc)
+	}
+}
+
+func TestNewerSchemaRejected(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+
+	_ = os.WriteFile(tmp, []byte(`{"version": 999, "todos": []}`), 0644)
+
+	if _, err := repo.List(); err == nil {
+		t.Errorf("Expected error for unsupported schema version")
+	}
+}
+
+func TestDoctorRepair(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+	defer os.Remove(tmp + ".bak")
+
+	broken := `{"version": 2, "todos": [
+		{"id": 1, "task": "first"},
+		{"id": 1, "task": "duplicate"},
+		{"id": "x", "task": "bad id"},
+		{"id": 4, "task": ""}
+	]}`
+	_ = os.WriteFile(tmp, []byte(broken), 0644)
+
+	report, err := repo.Check()
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(report.Issues) != 3 {
+		t.Fatalf("Expected 3 issues, got %d: %+v", len(report.Issues), report.Issues)
+	}
+
+	if _, err := repo.Repair(); err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+
+	todos, err := repo.List()
+	if err != nil {
+		t.Fatalf("Failed to list repaired todos: %v", err)
+	}
+	if len(todos) != 2 || todos[0].ID != 1 || todos[1].ID != 2 {
+		t.Errorf("Unexpected todos after repair: %+v", todos)
+	}
+
+	report, _ = repo.Check()
+	if !report.Healthy() {
+		t.Errorf("Expected healthy file after repair, got %+v", report.Issues)
+	}
+}
+
+func TestDoctorUnknownStatus(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+	defer SetWorkflow(DefaultWorkflow)
+
+	_, _ = repo.Add(Todo{Task: "in review"})
+	_ = repo.SetStatus(1, StatusReview)
+
+	// The status is renamed in the config.
+	if err := SetWorkflow(Workflow{StatusTodo, "checking", StatusDone}); err != nil {
+		t.Fatal(err)
+	}
+	report, err := repo.Check()
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(report.Issues) != 1 || report.Issues[0].Problem != `unknown status "review"` {
+		t.Errorf("Expected the review status to be reported, got %+v", report.Issues)
+	}
+}
+
+func TestConcurrentAdd(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := repo.Add(Todo{Task: "parallel"}); err != nil {
+				t.Errorf("Failed to add todo: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	todos, _ := repo.List()
+	if len(todos) != 20 {
+		t.Fatalf("Expected 20 todos, got %d", len(todos))
+	}
+	seen := map[int]bool{}
+	for _, td := range todos {
+		if seen[td.ID] {
+			t.Errorf("Duplicate ID %d", td.ID)
+		}
+		seen[td.ID] = true
+	}
+}
+
+func TestMigrateCompletedToStatus(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+	defer os.Remove(backupPath(tmp, 3))
+
+	v3 := `{"version": 3, "todos": [
+		{"id": 1, "task": "open", "completed": false},
+		{"id": 2, "task": "closed", "completed": true, "due": "2025-01-02"}
+	]}`
+	_ = os.WriteFile(tmp, []byte(v3), 0644)
+
+	todos, err := repo.List()
+	if err != nil {
+		t.Fatalf("Failed to list v3 todos: %v", err)
+	}
+	if todos[0].Status != StatusTodo || todos[1].Status != StatusDone || todos[1].Due != "2025-01-02" {
+		t.Errorf("Unexpected statuses after migration: %+v", todos)
+	}
+
+	data, _ := os.ReadFile(tmp)
+	if strings.Contains(string(data), "completed") {
+		t.Errorf("Expected completed flag to be removed, got %s", data)
+	}
+}
+
+func TestMigrateKeepsCalendarUIDs(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+	defer os.Remove(backupPath(tmp, 5))
+
+	v5 := `{"version": 5, "todos": [
+		{"id": 3, "task": "old", "status": "todo"},
+		{"id": 4, "task": "kept", "status": "todo", "uid": "abc@todo-cli"}
+	]}`
+	_ = os.WriteFile(tmp, []byte(v5), 0644)
+
+	todos, err := repo.List()
+	if err != nil {
+		t.Fatalf("Failed to list v5 todos: %v", err)
+	}
+	if todos[0].UID != "todo-3@todo-cli" || todos[1].UID != "abc@todo-cli" {
+		t.Errorf("Unexpected UIDs after migration: %+v", todos)
+	}
+}
+
+func TestUIDNotReused(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+
+	_, _ = repo.Add(Todo{Task: "first"})
+	deleted, _ := repo.Add(Todo{Task: "second"})
+	_ = repo.Delete(deleted.ID)
+	added, _ := repo.Add(Todo{Task: "third"})
+
+	if added.ID != deleted.ID {
+		t.Fatalf("Expected ID %d to be reused, got %d", deleted.ID, added.ID)
+	}
+	if added.UID == "" || added.UID == deleted.UID {
+		t.Errorf("Expected a fresh UID, got %q after %q", added.UID, deleted.UID)
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+
+	_, _ = repo.Add(Todo{Task: "Move me"})
+	if err := repo.SetStatus(1, StatusInProgress); err != nil {
+		t.Fatalf("Failed to set status: %v", err)
+	}
+	todos, _ := repo.List()
+	if todos[0].Status != StatusInProgress || todos[0].IsDone() {
+		t.Errorf("Expected todo to be in progress, got %q", todos[0].Status)
+	}
+
+	if err := repo.SetStatus(1, "nonsense"); err == nil {
+		t.Errorf("Expected error for unknown status")
+	}
+}
+
+func TestCustomWorkflow(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+	defer SetWorkflow(DefaultWorkflow)
+
+	if err := SetWorkflow(Workflow{"backlog", "doing", "shipped"}); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = repo.Add(Todo{Task: "Custom"})
+	_ = repo.Complete(1)
+
+	todos, _ := repo.List()
+	if todos[0].Status != "shipped" || !todos[0].IsDone() {
+		t.Errorf("Expected final custom status, got %q", todos[0].Status)
+	}
+
+	if err := SetWorkflow(Workflow{"only"}); err == nil {
+		t.Errorf("Expected error for single-status workflow")
+	}
+	if err := SetWorkflow(Workflow{"Review", "review", "done"}); err == nil {
+		t.Errorf("Expected error for statuses that differ only in case")
+	}
+}
+
+func TestMixedCaseStatuses(t *testing.T) {
+	defer SetWorkflow(DefaultWorkflow)
+	if err := SetWorkflow(Workflow{"Backlog", "QA", "Done"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"QA", "qa", " Qa "} {
+		if status, err := ParseStatus(name); err != nil || status != "QA" {
+			t.Errorf("ParseStatus(%q) = %q, %v; want QA", name, status, err)
+		}
+	}
+	if !(Filter{Status: "qa"}).Match(Todo{Task: "x", Status: "QA"}) {
+		t.Errorf("Expected the status filter to ignore case")
+	}
+}
+
+func TestCompleteManyIsAtomic(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+
+	for i := 0; i < 3; i++ {
+		_, _ = repo.Add(Todo{Task: "bulk"})
+	}
+
+	results, err := repo.CompleteMany([]int{1, 2, 99})
+	var bulkErr *BulkError
+	if !errors.As(err, &bulkErr) || bulkErr.Failed != 1 {
+		t.Fatalf("Expected bulk error for missing ID, got %v", err)
+	}
+	if len(results) != 3 || results[2].Err != ErrNotFound || results[0].Err != nil {
+		t.Errorf("Unexpected per-ID results: %+v", results)
+	}
+	todos, _ := repo.List()
+	for _, td := range todos {
+		if td.IsDone() {
+			t.Fatalf("Expected no todo to be completed after failed bulk operation")
+		}
+	}
+
+	if _, err := repo.CompleteMany([]int{1, 3}); err != nil {
+		t.Fatalf("CompleteMany failed: %v", err)
+	}
+	todos, _ = repo.List()
+	if !todos[0].IsDone() || todos[1].IsDone() || !todos[2].IsDone() {
+		t.Errorf("Unexpected statuses: %+v", todos)
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+
+	for i := 0; i < 4; i++ {
+		_, _ = repo.Add(Todo{Task: "bulk"})
+	}
+
+	if _, err := repo.DeleteMany([]int{2, 5}); err == nil {
+		t.Fatalf("Expected error for missing ID")
+	}
+	if todos, _ := repo.List(); len(todos) != 4 {
+		t.Fatalf("Expected nothing deleted, got %d todos", len(todos))
+	}
+
+	if _, err := repo.DeleteMany([]int{2, 3}); err != nil {
+		t.Fatalf("DeleteMany failed: %v", err)
+	}
+	todos, _ := repo.List()
+	if len(todos) != 2 || todos[0].ID != 1 || todos[1].ID != 4 {
+		t.Errorf("Unexpected todos after delete: %+v", todos)
+	}
+}
+
+func TestTagsAndLists(t *testing.T) {
+	repo, tmp := setupTestRepo(t)
+	defer os.Remove(tmp)
+
+	tags, err := ParseTags([]string{" home ", "errand", "home", ""})
+	if err != nil {
+		t.Fatalf("ParseTags failed: %v", err)
+	}
+	_, _ = repo.Add(Todo{Task: "Buy milk", Tags: tags, List: "shopping"})
+	_, _ = repo.Add(Todo{Task: "Fix bug", Tags: []string{"work"}, List: "work"})
+
+	if _, err := ParseTags([]string{"two words"}); err == nil {
+		t.Errorf("Expected error for tag with a space")
+	}
+
+	todos, err := repo.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if got := Tags(todos); strings.Join(got, ",") != "errand,home,work" {
+		t.Errorf("Expected sorted distinct tags, got %v", got)
+	}
+	if got := Lists(todos); strings.Join(got, ",") != "shopping,work" {
+		t.Errorf("Expected sorted distinct lists, got %v", got)
+	}
+	if matched := (Filter{Tag: "home"}).Apply(todos); len(matched) != 1 || matched[0].Task != "Buy milk" {
+		t.Errorf("Expected tag filter to match Buy milk, got %+v", matched)
+	}
+	if matched := (Filter{List: "work"}).Apply(todos); len(matched) != 1 || matched[0].Task != "Fix bug" {
+		t.Errorf("Expected list filter to match Fix bug, got %+v", matched)
+	}
+}
@@ -2,8 +2,22 @@ package todo
 
 import (
 	"errors"
+	"strings"
 )
 
+// ErrInvalidID is returned for IDs that can never exist.
+var ErrInvalidID = errors.New("invalid task ID")
+
+// InputError is returned when a todo or status given by the user does
+// not validate, as opposed to the store failing.
+type InputError struct {
+	Err error
+}
+
+func (e *InputError) Error() string { return e.Err.Error() }
+
+func (e *InputError) Unwrap() error { return e.Err }
+
 type Service struct {
 	repo Repository
 }
@@ -12,27 +26,90 @@ func NewService(repo Repository) *Service {
 	return &Service{repo: repo}
 }
 
-// func (s *Service) AddTask(task string) error {
-// 	if task == "" {
-// 		return errors.New("task cannot be empty")
-// 	}
-// 	return s.repo.Add(task)
-// }
+// AddTask validates and normalizes t before adding it. The ID and status
+// are assigned by the repository.
+func (s *Service) AddTask(t Todo) (Todo, error) {
+	t.Task = strings.TrimSpace(t.Task)
+	if t.Task == "" {
+		return Todo{}, &InputError{errors.New("task cannot be empty")}
+	}
+	var err error
+	if t.Due, err = ParseDue(t.Due); err != nil {
+		return Todo{}, &InputError{err}
+	}
+	if t.Priority, err = ParsePriority(string(t.Priority)); err != nil {
+		return Todo{}, &InputError{err}
+	}
+	if t.Tags, err = ParseTags(t.Tags); err != nil {
+		return Todo{}, &InputError{err}
+	}
+	t.List = strings.TrimSpace(t.List)
+	return s.repo.Add(t)
+}
 
 func (s *Service) ListTasks() ([]Todo, error) {
 	return s.repo.List()
 }
 
+// FindTasks returns the todos matching f.
+func (s *Service) FindTasks(f Filter) ([]Todo, error) {
+	todos, err := s.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	return f.Apply(todos), nil
+}
+
 func (s *Service) CompleteTask(id int) error {
 	if id <= 0 {
-		return errors.New("invalid task ID")
+		return ErrInvalidID
 	}
 	return s.repo.Complete(id)
 }
 
+// CompleteTasks completes all of ids or, if any is missing, none of them.
+func (s *Service) CompleteTasks(ids []int) ([]Result, error) {
+	if err := checkValidIDs(ids); err != nil {
+		return nil, err
+	}
+	return s.repo.CompleteMany(ids)
+}
+
 func (s *Service) DeleteTask(id int) error {
 	if id <= 0 {
-		return errors.New("invalid task ID")
+		return ErrInvalidID
 	}
 	return s.repo.Delete(id)
 }
+
+// DeleteTasks deletes all of ids or, if any is missing, none of them.
+func (s *Service) DeleteTasks(ids []int) ([]Result, error) {
+	if err := checkValidIDs(ids); err != nil {
+		return nil, err
+	}
+	return s.repo.DeleteMany(ids)
+}
+
+// MoveTask sets the workflow status of a todo, given by name.
+func (s *Service) MoveTask(id int, status string) (Status, error) {
+	if id <= 0 {
+		return "", ErrInvalidID
+	}
+	parsed, err := ParseStatus(status)
+	if err != nil {
+		return "", &InputError{err}
+	}
+	return parsed, s.repo.SetStatus(id, parsed)
+}
+
+func checkValidIDs(ids []int) error {
+	if len(ids) == 0 {
+		return errors.New("no task IDs given")
+	}
+	for _, id := range ids {
+		if id <= 0 {
+			return ErrInvalidID
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package todo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Status is a step in the workflow a todo moves through.
+type Status string
+
+const (
+	StatusTodo       Status = "todo"
+	StatusInProgress Status = "in-progress"
+	StatusReview     Status = "review"
+	StatusBlocked    Status = "blocked"
+	StatusDone       Status = "done"
+)
+
+// Workflow is the ordered list of statuses. The first status is given to
+// new todos and the last one means the todo is done.
+type Workflow []Status
+
+// DefaultWorkflow is used when no workflow is configured.
+var DefaultWorkflow = Workflow{StatusTodo, StatusInProgress, StatusReview, StatusBlocked, StatusDone}
+
+// workflow is the active workflow, set from the config at startup.
+var workflow = DefaultWorkflow
+
+// SetWorkflow makes w the active workflow.
+func SetWorkflow(w Workflow) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+	workflow = w
+	return nil
+}
+
+// CurrentWorkflow returns the active workflow.
+func CurrentWorkflow() Workflow {
+	return workflow
+}
+
+// Validate checks that w has at least two distinct, non-empty statuses.
+// Statuses are matched case-insensitively, so "QA" and "qa" are the same.
+func (w Workflow) Validate() error {
+	if len(w) < 2 {
+		return errors.New("workflow needs at least two statuses")
+	}
+	seen := make(map[string]bool)
+	for _, s := range w {
+		if strings.TrimSpace(string(s)) == "" {
+			return errors.New("workflow contains an empty status")
+		}
+		key := strings.ToLower(string(s))
+		if seen[key] {
+			return fmt.Errorf("workflow contains %q twice", s)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// Initial is the status of new todos.
+func (w Workflow) Initial() Status {
+	return w[0]
+}
+
+// Final is the status `todo complete` moves a todo to.
+func (w Workflow) Final() Status {
+	return w[len(w)-1]
+}
+
+// Contains reports whether s is part of the workflow.
+func (w Workflow) Contains(s Status) bool {
+	for _, ws := range w {
+		if ws == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseStatus validates a status name against the active workflow,
+// ignoring case, and returns it spelled as in the workflow.
+func ParseStatus(s string) (Status, error) {
+	name := strings.TrimSpace(s)
+	names := make([]string, len(workflow))
+	for i, ws := range workflow {
+		if strings.EqualFold(string(ws), name) {
+			return ws, nil
+		}
+		names[i] = string(ws)
+	}
+	return "", fmt.Errorf("unknown status %q, expected one of: %s", s, strings.Join(names, ", "))
+}
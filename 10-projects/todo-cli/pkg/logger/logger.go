@@ -1,13 +1,155 @@
+// Package logger is a small leveled logger with text or JSON output and
+// optional size-based rotation of the log file. It is a thin layer over
+// log/slog so that callers only deal with a handful of functions.
 package logger
 
 import (
-	"log"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
 )
 
-func Info(msg string) {
-	log.Println("[INFO]", msg)
+// Options configure Setup.
+type Options struct {
+	Level      string // debug, info, warn or error
+	Format     string // text or json
+	File       string // log file, stderr if empty
+	MaxSize    int64  // rotate the file once it grows past this many bytes
+	MaxBackups int    // number of rotated files to keep
 }
 
-func Error(msg string) {
-	log.Println("[ERROR]", msg)
+// Default rotation settings.
+const (
+	DefaultMaxSize    = 10 << 20
+	DefaultMaxBackups = 3
+)
+
+// fallback is used before Setup and after Close.
+var fallback = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+var (
+	mu      sync.Mutex
+	current *slog.Logger // nil means fallback
+	closer  io.Closer
+)
+
+// ParseLevel converts a level name to a slog.Level.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info", "":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("invalid log level %q, expected debug, info, warn or error", s)
+	}
+}
+
+// New builds a logger writing to w.
+func New(w io.Writer, level slog.Level, format string) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "text", "":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, expected text or json", format)
+	}
+}
+
+// Setup replaces the package logger according to opts. It closes the log
+// file of a previous Setup call.
+func Setup(opts Options) error {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stderr
+	var file *RotatingFile
+	if opts.File != "" {
+		if opts.MaxSize <= 0 {
+			opts.MaxSize = DefaultMaxSize
+		}
+		if opts.MaxBackups <= 0 {
+			opts.MaxBackups = DefaultMaxBackups
+		}
+		file, err = OpenRotatingFile(opts.File, opts.MaxSize, opts.MaxBackups)
+		if err != nil {
+			return err
+		}
+		w = file
+	}
+
+	l, err := New(w, level, opts.Format)
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if closer != nil {
+		closer.Close()
+		closer = nil
+	}
+	if file != nil {
+		closer = file
+	}
+	current = l
+	return nil
+}
+
+// Close flushes and closes the log file, if any. Later messages go to
+// stderr until the next Setup instead of to the closed file.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	current = nil
+	if closer == nil {
+		return nil
+	}
+	err := closer.Close()
+	closer = nil
+	return err
+}
+
+// Logger returns the package logger.
+func Logger() *slog.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		return fallback
+	}
+	return current
+}
+
+// Debug logs msg with optional key/value pairs at debug level.
+func Debug(msg string, args ...interface{}) {
+	Logger().Debug(msg, args...)
+}
+
+// Info logs msg with optional key/value pairs at info level.
+func Info(msg string, args ...interface{}) {
+	Logger().Info(msg, args...)
+}
+
+// Warn logs msg with optional key/value pairs at warn level.
+func Warn(msg string, args ...interface{}) {
+	Logger().Warn(msg, args...)
+}
+
+// Error logs msg with optional key/value pairs at error level.
+func Error(msg string, args ...interface{}) {
+	Logger().Error(msg, args...)
 }
@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	var buf bytes.Buffer
+	level, _ := ParseLevel("warn")
+	l, err := New(&buf, level, "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.Info("hidden")
+	l.Warn("shown", "key", "value")
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("Expected info to be filtered at warn level: %s", out)
+	}
+	if !strings.Contains(out, "shown") || !strings.Contains(out, "key=value") {
+		t.Errorf("Expected warn with attributes: %s", out)
+	}
+
+	if _, err := ParseLevel("loud"); err == nil {
+		t.Errorf("Expected error for unknown level")
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	l, _ := New(&buf, 0, "json")
+	l.Info("loaded", "todos", 3)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected JSON log line, got %q: %v", buf.String(), err)
+	}
+	if entry["msg"] != "loaded" || entry["level"] != "INFO" || entry["todos"] != float64(3) {
+		t.Errorf("Unexpected entry: %v", entry)
+	}
+}
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.log")
+	f, err := OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := map[string]string{
+		path:        "fourth\n",
+		path + ".1": "third\n",
+		path + ".2": "second\n",
+	}
+	for p, content := range want {
+		got, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", p, err)
+		}
+		if string(got) != content {
+			t.Errorf("%s = %q, want %q", p, got, content)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 backups to be kept")
+	}
+}
+
+func TestSetupFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.log")
+	if err := Setup(Options{Level: "debug", Format: "json", File: path}); err != nil {
+		t.Fatal(err)
+	}
+	defer Setup(Options{Level: "warn"})
+	Debug("to file", "n", 1)
+	Close()
+	Debug("after close")
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"msg":"to file"`) {
+		t.Errorf("Expected debug entry in log file, got %q", data)
+	}
+	if strings.Contains(string(data), "after close") {
+		t.Errorf("Expected no entries after Close, got %q", data)
+	}
+	if Logger() != fallback {
+		t.Errorf("Expected the stderr logger after Close")
+	}
+}
+
+func TestWriteAfterClose(t *testing.T) {
+	f, err := OpenRotatingFile(filepath.Join(t.TempDir(), "todo.log"), 10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if _, err := f.Write([]byte("late\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("Expected os.ErrClosed writing after Close, got %v", err)
+	}
+}
@@ -0,0 +1,387 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environments. Development fills in local defaults for everything;
+// production refuses to start without real secrets.
+const (
+	Development = "development"
+	Production  = "production"
+)
+
+// Supported database drivers.
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// DevJWTSecret is only used in development, when JWT_SECRET is not set.
+const DevJWTSecret = "my_secret_key"
+
+const devMySQLDSN = "root:@tcp(127.0.0.1:3306)/task_db?charset=utf8mb4&parseTime=True&loc=Local"
+
+// Config holds the settings of the API server.
+type Config struct {
+	Env             string        `json:"env"`
+	Addr            string        `json:"addr"`
+	DBDriver        string        `json:"db_driver"`
+	DBDSN           string        `json:"db_dsn"`
+	JWTSecret       string        `json:"jwt_secret"`
+	TokenTTL        time.Duration `json:"-"`
+	RefreshTokenTTL time.Duration `json:"-"`
+	CORSOrigins     []string      `json:"cors_origins"`
+	AdminUsername   string        `json:"admin_username"`
+	TrustProxy      bool          `json:"trust_proxy"`
+
+	// Requests per minute, per client IP on the login, registration and
+	// refresh endpoints and per user on the others. Negative disables.
+	AuthRateLimit int `json:"auth_rate_limit"`
+	APIRateLimit  int `json:"api_rate_limit"`
+
+	// Failed logins of a username before it is locked out for
+	// LoginLockoutBase, doubling with every further failure up to
+	// LoginLockoutMax. Negative disables.
+	LoginLockoutThreshold int           `json:"login_lockout_threshold"`
+	LoginLockoutBase      time.Duration `json:"-"`
+	LoginLockoutMax       time.Duration `json:"-"`
+
+	// MetricsAddr serves /metrics on a separate listener, such as an admin
+	// port, instead of next to the API. MetricsToken, if set, is the bearer
+	// token that scrapers must send.
+	MetricsAddr  string `json:"metrics_addr"`
+	MetricsToken string `json:"metrics_token"`
+
+	// Limits of the HTTP server: reading a request, writing its response,
+	// and keeping an idle connection open.
+	ReadTimeout  time.Duration `json:"-"`
+	WriteTimeout time.Duration `json:"-"`
+	IdleTimeout  time.Duration `json:"-"`
+	// ShutdownTimeout is how long requests in flight may finish after
+	// SIGINT or SIGTERM.
+	ShutdownTimeout time.Duration `json:"-"`
+	// DBConnectTimeout is how long startup retries to reach the database.
+	DBConnectTimeout time.Duration `json:"-"`
+}
+
+// fileConfig is the JSON layout of the config file. The durations are
+// strings such as "15m".
+type fileConfig struct {
+	Config
+	TokenTTL         string `json:"token_ttl"`
+	RefreshTokenTTL  string `json:"refresh_token_ttl"`
+	LoginLockoutBase string `json:"login_lockout_base"`
+	LoginLockoutMax  string `json:"login_lockout_max"`
+	ReadTimeout      string `json:"read_timeout"`
+	WriteTimeout     string `json:"write_timeout"`
+	IdleTimeout      string `json:"idle_timeout"`
+	ShutdownTimeout  string `json:"shutdown_timeout"`
+	DBConnectTimeout string `json:"db_connect_timeout"`
+}
+
+// Load reads the config file named by CONFIG_FILE, if any, then applies the
+// environment variables on top:
+//
+//	APP_ENV            development (default) or production
+//	HTTP_ADDR          listen address, default :8080
+//	DB_DRIVER          mysql (default), postgres or sqlite
+//	DB_DSN             data source name for the driver
+//	JWT_SECRET         key used to sign tokens
+//	TOKEN_TTL          access token lifetime, default 15m
+//	REFRESH_TOKEN_TTL  refresh token lifetime, default 720h (30 days)
+//	CORS_ORIGINS       comma-separated allowed origins, default *
+//	ADMIN_USERNAME     user that becomes admin on registration or startup
+//	TRUST_PROXY        true to take client IPs from X-Forwarded-For
+//	AUTH_RATE_LIMIT    requests per minute and IP to /login, /register and
+//	                   /token/refresh, default 20; negative disables
+//	API_RATE_LIMIT     requests per minute and user elsewhere, default 300
+//	LOGIN_LOCKOUT_THRESHOLD  failed logins before a lockout, default 5
+//	LOGIN_LOCKOUT_BASE       first lockout, default 1m
+//	LOGIN_LOCKOUT_MAX        longest lockout, default 1h
+//	METRICS_ADDR       separate listen address for /metrics, e.g. :9090
+//	METRICS_TOKEN      bearer token required to scrape /metrics
+//	HTTP_READ_TIMEOUT  time to read a request, default 15s
+//	HTTP_WRITE_TIMEOUT time to write a response, default 30s
+//	HTTP_IDLE_TIMEOUT  keep-alive of idle connections, default 60s
+//	SHUTDOWN_TIMEOUT   time for requests in flight on shutdown, default 30s
+//	DB_CONNECT_TIMEOUT time to retry the database at startup, default 60s
+func Load() (Config, error) {
+	var cfg Config
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		var err error
+		if cfg, err = loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	setString(&cfg.Env, "APP_ENV")
+	setString(&cfg.Addr, "HTTP_ADDR")
+	setString(&cfg.DBDriver, "DB_DRIVER")
+	setString(&cfg.DBDSN, "DB_DSN")
+	setString(&cfg.JWTSecret, "JWT_SECRET")
+	if err := setDuration(&cfg.TokenTTL, "TOKEN_TTL"); err != nil {
+		return Config{}, err
+	}
+	if err := setDuration(&cfg.RefreshTokenTTL, "REFRESH_TOKEN_TTL"); err != nil {
+		return Config{}, err
+	}
+	if v := os.Getenv("CORS_ORIGINS"); v != "" {
+		cfg.CORSOrigins = splitList(v)
+	}
+	setString(&cfg.AdminUsername, "ADMIN_USERNAME")
+	if err := setBool(&cfg.TrustProxy, "TRUST_PROXY"); err != nil {
+		return Config{}, err
+	}
+	for _, v := range []struct {
+		dst *int
+		env string
+	}{
+		{&cfg.AuthRateLimit, "AUTH_RATE_LIMIT"},
+		{&cfg.APIRateLimit, "API_RATE_LIMIT"},
+		{&cfg.LoginLockoutThreshold, "LOGIN_LOCKOUT_THRESHOLD"},
+	} {
+		if err := setInt(v.dst, v.env); err != nil {
+			return Config{}, err
+		}
+	}
+	for _, v := range []struct {
+		dst *time.Duration
+		env string
+	}{
+		{&cfg.LoginLockoutBase, "LOGIN_LOCKOUT_BASE"},
+		{&cfg.LoginLockoutMax, "LOGIN_LOCKOUT_MAX"},
+		{&cfg.ReadTimeout, "HTTP_READ_TIMEOUT"},
+		{&cfg.WriteTimeout, "HTTP_WRITE_TIMEOUT"},
+		{&cfg.IdleTimeout, "HTTP_IDLE_TIMEOUT"},
+		{&cfg.ShutdownTimeout, "SHUTDOWN_TIMEOUT"},
+		{&cfg.DBConnectTimeout, "DB_CONNECT_TIMEOUT"},
+	} {
+		if err := setDuration(v.dst, v.env); err != nil {
+			return Config{}, err
+		}
+	}
+	setString(&cfg.MetricsAddr, "METRICS_ADDR")
+	setString(&cfg.MetricsToken, "METRICS_TOKEN")
+
+	cfg.applyDefaults()
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func loadFile(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("reading config file: %w", err)
+	}
+	var fc fileConfig
+	if err := json.Unmarshal(data, &fc); err != nil {
+		return Config{}, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	cfg := fc.Config
+	for _, d := range []struct {
+		dst   *time.Duration
+		value string
+		key   string
+	}{
+		{&cfg.TokenTTL, fc.TokenTTL, "token_ttl"},
+		{&cfg.RefreshTokenTTL, fc.RefreshTokenTTL, "refresh_token_ttl"},
+		{&cfg.LoginLockoutBase, fc.LoginLockoutBase, "login_lockout_base"},
+		{&cfg.LoginLockoutMax, fc.LoginLockoutMax, "login_lockout_max"},
+		{&cfg.ReadTimeout, fc.ReadTimeout, "read_timeout"},
+		{&cfg.WriteTimeout, fc.WriteTimeout, "write_timeout"},
+		{&cfg.IdleTimeout, fc.IdleTimeout, "idle_timeout"},
+		{&cfg.ShutdownTimeout, fc.ShutdownTimeout, "shutdown_timeout"},
+		{&cfg.DBConnectTimeout, fc.DBConnectTimeout, "db_connect_timeout"},
+	} {
+		if d.value == "" {
+			continue
+		}
+		if *d.dst, err = time.ParseDuration(d.value); err != nil {
+			return Config{}, fmt.Errorf("invalid %s %q in %s: %v", d.key, d.value, path, err)
+		}
+	}
+	return cfg, nil
+}
+
+// applyDefaults fills in unset values. Secrets and the DSN only get
+// defaults in development.
+func (c *Config) applyDefaults() {
+	if c.Env == "" {
+		c.Env = Development
+	}
+	if c.Addr == "" {
+		c.Addr = ":8080"
+	}
+	if c.DBDriver == "" {
+		c.DBDriver = DriverMySQL
+	}
+	if c.TokenTTL == 0 {
+		c.TokenTTL = 15 * time.Minute
+	}
+	if c.RefreshTokenTTL == 0 {
+		c.RefreshTokenTTL = 30 * 24 * time.Hour
+	}
+	if len(c.CORSOrigins) == 0 {
+		c.CORSOrigins = []string{"*"}
+	}
+	if c.AuthRateLimit == 0 {
+		c.AuthRateLimit = 20
+	}
+	if c.APIRateLimit == 0 {
+		c.APIRateLimit = 300
+	}
+	if c.LoginLockoutThreshold == 0 {
+		c.LoginLockoutThreshold = 5
+	}
+	if c.LoginLockoutBase == 0 {
+		c.LoginLockoutBase = time.Minute
+	}
+	if c.LoginLockoutMax == 0 {
+		c.LoginLockoutMax = time.Hour
+	}
+	if c.ReadTimeout == 0 {
+		c.ReadTimeout = 15 * time.Second
+	}
+	if c.WriteTimeout == 0 {
+		c.WriteTimeout = 30 * time.Second
+	}
+	if c.IdleTimeout == 0 {
+		c.IdleTimeout = time.Minute
+	}
+	if c.ShutdownTimeout == 0 {
+		c.ShutdownTimeout = 30 * time.Second
+	}
+	if c.DBConnectTimeout == 0 {
+		c.DBConnectTimeout = time.Minute
+	}
+	if c.IsProduction() {
+		return
+	}
+	if c.JWTSecret == "" {
+		c.JWTSecret = DevJWTSecret
+	}
+	if c.DBDSN == "" {
+		switch c.DBDriver {
+		case DriverMySQL:
+			c.DBDSN = devMySQLDSN
+		case DriverSQLite:
+			c.DBDSN = "task_db.sqlite"
+		case DriverPostgres:
+			c.DBDSN = "host=localhost user=postgres dbname=task_db sslmode=disable"
+		}
+	}
+}
+
+// IsProduction reports whether the server runs in production mode.
+func (c Config) IsProduction() bool {
+	return c.Env == Production
+}
+
+// Validate checks the settings, including the secrets required in
+// production.
+func (c Config) Validate() error {
+	switch c.Env {
+	case Development, Production:
+	default:
+		return fmt.Errorf("invalid APP_ENV %q, expected %s or %s", c.Env, Development, Production)
+	}
+	switch c.DBDriver {
+	case DriverMySQL, DriverPostgres, DriverSQLite:
+	default:
+		return fmt.Errorf("unsupported DB_DRIVER %q, expected mysql, postgres or sqlite", c.DBDriver)
+	}
+	if c.TokenTTL < 0 {
+		return errors.New("TOKEN_TTL must be positive")
+	}
+	if c.RefreshTokenTTL < c.TokenTTL {
+		return errors.New("REFRESH_TOKEN_TTL must not be shorter than TOKEN_TTL")
+	}
+	if c.LoginLockoutBase < 0 || c.LoginLockoutMax < c.LoginLockoutBase {
+		return errors.New("LOGIN_LOCKOUT_MAX must not be shorter than LOGIN_LOCKOUT_BASE")
+	}
+	if c.ReadTimeout < 0 || c.WriteTimeout < 0 || c.IdleTimeout < 0 || c.ShutdownTimeout < 0 || c.DBConnectTimeout < 0 {
+		return errors.New("timeouts must be positive")
+	}
+	if c.MetricsAddr != "" && c.MetricsAddr == c.Addr {
+		return errors.New("METRICS_ADDR must differ from HTTP_ADDR")
+	}
+
+	if c.IsProduction() {
+		var missing []string
+		if c.JWTSecret == "" || c.JWTSecret == DevJWTSecret {
+			missing = append(missing, "JWT_SECRET")
+		}
+		if c.DBDSN == "" {
+			missing = append(missing, "DB_DSN")
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("production mode requires %s to be set", strings.Join(missing, " and "))
+		}
+	}
+	return nil
+}
+
+func setString(dst *string, env string) {
+	if v := os.Getenv(env); v != "" {
+		*dst = v
+	}
+}
+
+func setInt(dst *int, env string) error {
+	v := os.Getenv(env)
+	if v == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: expected a whole number", env, v)
+	}
+	*dst = n
+	return nil
+}
+
+func setBool(dst *bool, env string) error {
+	v := os.Getenv(env)
+	if v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: expected true or false", env, v)
+	}
+	*dst = b
+	return nil
+}
+
+func setDuration(dst *time.Duration, env string) error {
+	v := os.Getenv(env)
+	if v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %v", env, v, err)
+	}
+	*dst = d
+	return nil
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets every variable Load reads, for the duration of the test.
+func clearEnv(t *testing.T) {
+	for _, name := range []string{"CONFIG_FILE", "APP_ENV", "HTTP_ADDR", "DB_DRIVER", "DB_DSN", "JWT_SECRET", "TOKEN_TTL", "REFRESH_TOKEN_TTL", "CORS_ORIGINS", "ADMIN_USERNAME",
+		"TRUST_PROXY", "AUTH_RATE_LIMIT", "API_RATE_LIMIT", "LOGIN_LOCKOUT_THRESHOLD", "LOGIN_LOCKOUT_BASE", "LOGIN_LOCKOUT_MAX", "METRICS_ADDR", "METRICS_TOKEN",
+		"HTTP_READ_TIMEOUT", "HTTP_WRITE_TIMEOUT", "HTTP_IDLE_TIMEOUT", "SHUTDOWN_TIMEOUT", "DB_CONNECT_TIMEOUT"} {
+		t.Setenv(name, "")
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	clearEnv(t)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Env != Development || cfg.Addr != ":8080" || cfg.DBDriver != DriverMySQL {
+		t.Errorf("unexpected defaults: %+v", cfg)
+	}
+	if cfg.JWTSecret != DevJWTSecret || cfg.DBDSN == "" {
+		t.Errorf("expected development secrets, got %+v", cfg)
+	}
+	if cfg.TokenTTL != 15*time.Minute || cfg.RefreshTokenTTL != 30*24*time.Hour || len(cfg.CORSOrigins) != 1 || cfg.CORSOrigins[0] != "*" {
+		t.Errorf("unexpected token TTL or CORS defaults: %+v", cfg)
+	}
+	if cfg.TrustProxy || cfg.AuthRateLimit != 20 || cfg.APIRateLimit != 300 {
+		t.Errorf("unexpected rate limit defaults: %+v", cfg)
+	}
+	if cfg.LoginLockoutThreshold != 5 || cfg.LoginLockoutBase != time.Minute || cfg.LoginLockoutMax != time.Hour {
+		t.Errorf("unexpected lockout defaults: %+v", cfg)
+	}
+	if cfg.ReadTimeout != 15*time.Second || cfg.WriteTimeout != 30*time.Second || cfg.IdleTimeout != time.Minute ||
+		cfg.ShutdownTimeout != 30*time.Second || cfg.DBConnectTimeout != time.Minute {
+		t.Errorf("unexpected timeout defaults: %+v", cfg)
+	}
+}
+
+func TestLoadFileAndEnv(t *testing.T) {
+	clearEnv(t)
+	path := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(path, []byte(`{"addr": ":9000", "metrics_addr": ":9090", "db_driver": "sqlite", "db_dsn": "file.db", "token_ttl": "1h", "refresh_token_ttl": "168h", "login_lockout_max": "2h", "shutdown_timeout": "5s"}`), 0644)
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DB_DSN", ":memory:")
+	t.Setenv("CORS_ORIGINS", "https://a.example, https://b.example")
+	t.Setenv("ADMIN_USERNAME", "root")
+	t.Setenv("TRUST_PROXY", "true")
+	t.Setenv("AUTH_RATE_LIMIT", "-1")
+	t.Setenv("LOGIN_LOCKOUT_BASE", "30s")
+	t.Setenv("METRICS_TOKEN", "scrape")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "2m")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if cfg.Addr != ":9000" || cfg.DBDriver != DriverSQLite || cfg.TokenTTL != time.Hour || cfg.RefreshTokenTTL != 7*24*time.Hour {
+		t.Errorf("expected values from the file, got %+v", cfg)
+	}
+	if cfg.DBDSN != ":memory:" {
+		t.Errorf("expected DB_DSN to override the file, got %q", cfg.DBDSN)
+	}
+	if strings.Join(cfg.CORSOrigins, " ") != "https://a.example https://b.example" {
+		t.Errorf("unexpected CORS origins: %q", cfg.CORSOrigins)
+	}
+	if cfg.AdminUsername != "root" {
+		t.Errorf("expected ADMIN_USERNAME to be read, got %q", cfg.AdminUsername)
+	}
+	if !cfg.TrustProxy || cfg.AuthRateLimit != -1 || cfg.LoginLockoutBase != 30*time.Second || cfg.LoginLockoutMax != 2*time.Hour {
+		t.Errorf("unexpected proxy, rate limit or lockout settings: %+v", cfg)
+	}
+	if cfg.MetricsAddr != ":9090" || cfg.MetricsToken != "scrape" {
+		t.Errorf("unexpected metrics settings: %+v", cfg)
+	}
+	if cfg.ShutdownTimeout != 5*time.Second || cfg.WriteTimeout != 2*time.Minute {
+		t.Errorf("unexpected timeouts: %+v", cfg)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		env     map[string]string
+		wantErr string
+	}{
+		{map[string]string{"DB_DRIVER": "oracle"}, "unsupported DB_DRIVER"},
+		{map[string]string{"APP_ENV": "staging"}, "invalid APP_ENV"},
+		{map[string]string{"TOKEN_TTL": "soon"}, "invalid TOKEN_TTL"},
+		{map[string]string{"REFRESH_TOKEN_TTL": "later"}, "invalid REFRESH_TOKEN_TTL"},
+		{map[string]string{"TOKEN_TTL": "2h", "REFRESH_TOKEN_TTL": "1h"}, "must not be shorter"},
+		{map[string]string{"TRUST_PROXY": "maybe"}, "invalid TRUST_PROXY"},
+		{map[string]string{"API_RATE_LIMIT": "lots"}, "invalid API_RATE_LIMIT"},
+		{map[string]string{"LOGIN_LOCKOUT_BASE": "2h", "LOGIN_LOCKOUT_MAX": "1h"}, "LOGIN_LOCKOUT_MAX must not be shorter"},
+		{map[string]string{"METRICS_ADDR": ":8080"}, "METRICS_ADDR must differ"},
+		{map[string]string{"HTTP_READ_TIMEOUT": "-1s"}, "timeouts must be positive"},
+		{map[string]string{"SHUTDOWN_TIMEOUT": "never"}, "invalid SHUTDOWN_TIMEOUT"},
+		{map[string]string{"APP_ENV": "production"}, "production mode requires JWT_SECRET and DB_DSN to be set"},
+		{map[string]string{"APP_ENV": "production", "JWT_SECRET": DevJWTSecret, "DB_DSN": "dsn"}, "requires JWT_SECRET"},
+		{map[string]string{"APP_ENV": "production", "JWT_SECRET": "s3cret", "DB_DRIVER": "postgres"}, "requires DB_DSN"},
+	}
+
+	for _, tt := range tests {
+		clearEnv(t)
+		for k, v := range tt.env {
+			t.Setenv(k, v)
+		}
+		_, err := Load()
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Load() with %v = %v, want error containing %q", tt.env, err, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadProduction(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("JWT_SECRET", "a-long-random-secret")
+	t.Setenv("DB_DRIVER", "postgres")
+	t.Setenv("DB_DSN", "host=db user=api dbname=tasks")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !cfg.IsProduction() || cfg.JWTSecret != "a-long-random-secret" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/middleware"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/services"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type AdminController struct {
+	admin *services.AdminService
+}
+
+func NewAdminController(admin *services.AdminService) *AdminController {
+	return &AdminController{admin: admin}
+}
+
+type RoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin member" enums:"admin,member" example:"admin"`
+}
+
+type PasswordResetRequest struct {
+	Password string `json:"password" validate:"required,max=72" example:"NewPass1!"`
+}
+
+// ListUsers godoc
+// @Summary      List users
+// @Description  Lists every user account. Admin only.
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   models.User
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      403  {object}  utils.Problem  "Forbidden"
+// @Router       /admin/users [get]
+func (c *AdminController) ListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := c.admin.ListUsers()
+	if err != nil {
+		serverError(w, r, err, "Failed to load users")
+		return
+	}
+	utils.JSON(w, http.StatusOK, users)
+}
+
+// DisableUser godoc
+// @Summary      Disable a user
+// @Description  Disables an account and revokes all its sessions. Admin only.
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  models.User
+// @Failure      400  {object}  utils.Problem  "Bad request"
+// @Failure      403  {object}  utils.Problem  "Forbidden"
+// @Failure      404  {object}  utils.Problem  "User not found"
+// @Router       /admin/users/{id}/disable [post]
+func (c *AdminController) DisableUser(w http.ResponseWriter, r *http.Request) {
+	c.setDisabled(w, r, true)
+}
+
+// EnableUser godoc
+// @Summary      Enable a user
+// @Description  Enables a disabled account. Admin only.
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {object}  models.User
+// @Failure      403  {object}  utils.Problem  "Forbidden"
+// @Failure      404  {object}  utils.Problem  "User not found"
+// @Router       /admin/users/{id}/enable [post]
+func (c *AdminController) EnableUser(w http.ResponseWriter, r *http.Request) {
+	c.setDisabled(w, r, false)
+}
+
+func (c *AdminController) setDisabled(w http.ResponseWriter, r *http.Request, disabled bool) {
+	adminID, _ := middleware.UserID(r)
+	user, err := c.admin.SetDisabled(adminID, userIDParam(r), disabled)
+	if err != nil {
+		adminError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusOK, user)
+}
+
+// SetRole godoc
+// @Summary      Change a user's role
+// @Description  Sets the role (admin or member) of a user. A promotion applies from the user's next login or token refresh; a demotion revokes the user's sessions. Admin only.
+// @Tags         admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int          true  "User ID"
+// @Param        role  body      RoleRequest  true  "New role"
+// @Success      200   {object}  models.User
+// @Failure      400   {object}  utils.Problem  "Bad request"
+// @Failure      403   {object}  utils.Problem  "Forbidden"
+// @Failure      404   {object}  utils.Problem  "User not found"
+// @Router       /admin/users/{id}/role [put]
+func (c *AdminController) SetRole(w http.ResponseWriter, r *http.Request) {
+	var req RoleRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	adminID, _ := middleware.UserID(r)
+	user, err := c.admin.SetRole(adminID, userIDParam(r), req.Role)
+	if err != nil {
+		adminError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusOK, user)
+}
+
+// ResetPassword godoc
+// @Summary      Reset a user's password
+// @Description  Sets a new password and revokes all sessions of the user. Admin only.
+// @Tags         admin
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int                   true  "User ID"
+// @Param        password  body      PasswordResetRequest  true  "New password"
+// @Success      200       {object}  map[string]string
+// @Failure      400       {object}  utils.Problem  "Bad request"
+// @Failure      403       {object}  utils.Problem  "Forbidden"
+// @Failure      404       {object}  utils.Problem  "User not found"
+// @Router       /admin/users/{id}/password [post]
+func (c *AdminController) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req PasswordResetRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	if err := c.admin.ResetPassword(userIDParam(r), req.Password); err != nil {
+		adminError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Password reset"})
+}
+
+// GetUserTasks godoc
+// @Summary      List a user's tasks
+// @Description  Lists the tasks of any user. Admin only.
+// @Tags         admin
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "User ID"
+// @Success      200  {array}   models.Task
+// @Failure      403  {object}  utils.Problem  "Forbidden"
+// @Failure      404  {object}  utils.Problem  "User not found"
+// @Router       /admin/users/{id}/tasks [get]
+func (c *AdminController) GetUserTasks(w http.ResponseWriter, r *http.Request) {
+	tasks, err := c.admin.UserTasks(userIDParam(r))
+	if err != nil {
+		adminError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusOK, tasks)
+}
+
+func userIDParam(r *http.Request) uint {
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	return uint(id)
+}
+
+// adminError writes 404 for missing users, 400 for rejected changes and 500
+// for anything else.
+func adminError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		utils.Error(w, r, http.StatusNotFound, "User not found")
+	case errors.Is(err, services.ErrInvalidInput):
+		invalidInput(w, r, err)
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrSelfLockout):
+		utils.Error(w, r, http.StatusBadRequest, err.Error())
+	default:
+		serverError(w, r, err, "Database error")
+	}
+}
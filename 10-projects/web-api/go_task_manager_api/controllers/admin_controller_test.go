@@ -0,0 +1,180 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/config"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/testutil"
+)
+
+func TestFirstUserIsAdmin(t *testing.T) {
+	srv := testutil.NewServer(t)
+	adminToken := srv.Register(t, "first_admin")
+	memberToken := srv.Register(t, "second_member")
+
+	var profile map[string]interface{}
+	srv.Do(t, "GET", "/me", adminToken, nil).Decode(t, &profile)
+	if profile["role"] != models.RoleAdmin {
+		t.Errorf("Expected the first user to be admin, got %v", profile["role"])
+	}
+	srv.Do(t, "GET", "/me", memberToken, nil).Decode(t, &profile)
+	if profile["role"] != models.RoleMember {
+		t.Errorf("Expected later users to be members, got %v", profile["role"])
+	}
+}
+
+func TestAdminUsernameBootstrap(t *testing.T) {
+	srv := testutil.NewServerWithConfig(t, config.Config{AdminUsername: "boss"})
+	srv.Register(t, "someone")
+	bossToken := srv.Register(t, "boss")
+
+	if resp := srv.Do(t, "GET", "/admin/users", bossToken, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected ADMIN_USERNAME to be admin, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminRoutesRequireAdmin(t *testing.T) {
+	srv := testutil.NewServer(t)
+	srv.Register(t, "the_admin")
+	memberToken := srv.Register(t, "just_member")
+
+	if resp := srv.Do(t, "GET", "/admin/users", "", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "GET", "/admin/users", memberToken, nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a member, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "POST", "/admin/users/1/disable", memberToken, nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a member, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminListUsersAndTasks(t *testing.T) {
+	srv := testutil.NewServer(t)
+	adminToken := srv.Register(t, "list_admin")
+	memberToken := srv.Register(t, "list_member")
+	member := userID(t, srv, memberToken)
+	srv.Do(t, "POST", "/tasks", memberToken, controllers.TaskRequest{Title: "Member task"})
+
+	resp := srv.Do(t, "GET", "/admin/users", adminToken, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if strings.Contains(string(resp.Body), "password") {
+		t.Errorf("Expected no password hashes in %s", resp.Body)
+	}
+	var users []models.User
+	resp.Decode(t, &users)
+	if len(users) != 2 || users[1].Username != "list_member" || users[1].Role != models.RoleMember {
+		t.Errorf("Unexpected users: %+v", users)
+	}
+
+	resp = srv.Do(t, "GET", fmt.Sprintf("/admin/users/%d/tasks", member), adminToken, nil)
+	var tasks []models.Task
+	resp.Decode(t, &tasks)
+	if len(tasks) != 1 || tasks[0].Title != "Member task" {
+		t.Errorf("Expected the member's task, got %+v", tasks)
+	}
+	if resp = srv.Do(t, "GET", "/admin/users/999/tasks", adminToken, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown user, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminDisableAndEnableUser(t *testing.T) {
+	srv := testutil.NewServer(t)
+	adminToken := srv.Register(t, "disable_admin")
+	memberToken := srv.Register(t, "disable_member")
+	session := srv.Login(t, "disable_member")
+	member := userID(t, srv, memberToken)
+
+	resp := srv.Do(t, "POST", fmt.Sprintf("/admin/users/%d/disable", member), adminToken, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d %s", resp.StatusCode, resp.Body)
+	}
+
+	// Every session of the disabled user is gone, and it cannot log in.
+	if resp = srv.Do(t, "GET", "/tasks", memberToken, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the disabled user's token to be revoked, got %d", resp.StatusCode)
+	}
+	resp = srv.Do(t, "POST", "/token/refresh", "", map[string]string{"refresh_token": session.RefreshToken})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the disabled user's refresh token to be revoked, got %d", resp.StatusCode)
+	}
+	login := map[string]string{"username": "disable_member", "password": testutil.Password}
+	if resp = srv.Do(t, "POST", "/login", "", login); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 logging in to a disabled account, got %d", resp.StatusCode)
+	}
+
+	srv.Do(t, "POST", fmt.Sprintf("/admin/users/%d/enable", member), adminToken, nil)
+	if resp = srv.Do(t, "POST", "/login", "", login); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected login after enabling, got %d", resp.StatusCode)
+	}
+
+	// Admins cannot lock themselves out.
+	admin := userID(t, srv, adminToken)
+	if resp = srv.Do(t, "POST", fmt.Sprintf("/admin/users/%d/disable", admin), adminToken, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 disabling yourself, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminSetRole(t *testing.T) {
+	srv := testutil.NewServer(t)
+	adminToken := srv.Register(t, "role_admin")
+	memberToken := srv.Register(t, "role_member")
+	member := userID(t, srv, memberToken)
+	path := fmt.Sprintf("/admin/users/%d/role", member)
+
+	if resp := srv.Do(t, "PUT", path, adminToken, map[string]string{"role": "owner"}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown role, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "PUT", path, adminToken, map[string]string{"role": models.RoleAdmin}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	// The new role is in the next token.
+	promoted := srv.Login(t, "role_member")
+	if resp := srv.Do(t, "GET", "/admin/users", promoted.Token, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the promoted user to reach admin routes, got %d", resp.StatusCode)
+	}
+
+	// A demotion ends the sessions that still carry the admin role.
+	if resp := srv.Do(t, "PUT", path, adminToken, map[string]string{"role": models.RoleMember}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "GET", "/admin/users", promoted.Token, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the demoted admin's token to be revoked, got %d", resp.StatusCode)
+	}
+	resp := srv.Do(t, "POST", "/token/refresh", "", map[string]string{"refresh_token": promoted.RefreshToken})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the demoted admin's refresh token to be revoked, got %d", resp.StatusCode)
+	}
+}
+
+func TestAdminResetPassword(t *testing.T) {
+	srv := testutil.NewServer(t)
+	adminToken := srv.Register(t, "reset_admin")
+	memberToken := srv.Register(t, "reset_member")
+	path := fmt.Sprintf("/admin/users/%d/password", userID(t, srv, memberToken))
+
+	if resp := srv.Do(t, "POST", path, adminToken, map[string]string{"password": "weak"}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a weak password, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "POST", path, adminToken, map[string]string{"password": "NewPass1!"}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	if resp := srv.Do(t, "GET", "/tasks", memberToken, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the old sessions to be revoked, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "POST", "/login", "", map[string]string{"username": "reset_member", "password": testutil.Password}); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the old password to fail, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "POST", "/login", "", map[string]string{"username": "reset_member", "password": "NewPass1!"}); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the new password to work, got %d", resp.StatusCode)
+	}
+}
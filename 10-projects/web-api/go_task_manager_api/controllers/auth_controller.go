@@ -1,50 +1,104 @@
 package controllers
 
 import (
-	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/metrics"
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/middleware"
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/services"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
 )
 
+type AuthController struct {
+	auth    *services.AuthService
+	tokens  *services.TokenService
+	metrics *metrics.Metrics // counts logins; may be nil
+}
+
+func NewAuthController(auth *services.AuthService, tokens *services.TokenService, m *metrics.Metrics) *AuthController {
+	return &AuthController{auth: auth, tokens: tokens, metrics: m}
+}
+
 type AuthRequest struct {
-	Username string `json:"username" example:"john_doe"`
-	Password string `json:"password" example:"StrongPass1!"`
+	Username string `json:"username" validate:"required,max=50" example:"john_doe"`
+	Password string `json:"password" validate:"required,max=72" example:"StrongPass1!"` // bcrypt uses 72 bytes at most
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// TokenResponse carries a short-lived access token and the refresh token to
+// get the next one with.
+type TokenResponse struct {
+	Message      string `json:"message,omitempty"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in" example:"900"` // seconds
+}
+
+func newTokenResponse(pair services.TokenPair) TokenResponse {
+	return TokenResponse{
+		Token:        pair.AccessToken,
+		RefreshToken: pair.RefreshToken,
+		ExpiresIn:    int64(time.Until(pair.ExpiresAt).Round(time.Second).Seconds()),
+	}
 }
 
 // Login godoc
 // @Summary      User login
-// @Description  Logs user in and returns a JWT token
+// @Description  Logs user in and returns an access token and a refresh token
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        credentials  body  AuthRequest  true  "Login credentials"
-// @Success      200  {object}  map[string]string
-// @Failure      401  {string}  string  "Unauthorized"
+// @Success      200  {object}  TokenResponse
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      403  {object}  utils.Problem  "Account disabled"
+// @Failure      429  {object}  utils.Problem  "Too many failed logins or requests"
+// @Header       429  {string}  Retry-After  "Seconds until the next attempt is allowed"
 // @Router       /login [post]
-func Login(w http.ResponseWriter, r *http.Request) {
+func (c *AuthController) Login(w http.ResponseWriter, r *http.Request) {
 	var req AuthRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
-	user, err := services.AuthenticateUser(req.Username, req.Password)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+	user, err := c.auth.AuthenticateUser(req.Username, req.Password)
+	var locked *services.LockedOutError
+	switch {
+	case errors.As(err, &locked):
+		c.metrics.Login(metrics.LoginLocked)
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		utils.Error(w, r, http.StatusTooManyRequests, err.Error())
+		return
+	case errors.Is(err, services.ErrAccountDisabled):
+		c.metrics.Login(metrics.LoginDisabled)
+		utils.Error(w, r, http.StatusForbidden, err.Error())
+		return
+	case errors.Is(err, services.ErrInvalidCredentials):
+		c.metrics.Login(metrics.LoginFailure)
+		utils.Error(w, r, http.StatusUnauthorized, err.Error())
+		return
+	case err != nil:
+		c.metrics.Login(metrics.LoginError)
+		serverError(w, r, err, "Login failed")
 		return
 	}
 
-	token, err := middleware.GenerateToken(user.Username, user.ID)
+	pair, err := c.tokens.Issue(user)
 	if err != nil {
-		http.Error(w, "Token generation failed", http.StatusInternalServerError)
+		c.metrics.Login(metrics.LoginError)
+		serverError(w, r, err, "Token generation failed")
 		return
 	}
+	c.metrics.Login(metrics.LoginSuccess)
 
-	json.NewEncoder(w).Encode(map[string]string{"token": token})
+	utils.JSON(w, http.StatusOK, newTokenResponse(pair))
 }
 
 /* func Login(w http.ResponseWriter, r *http.Request) {
@@ -85,42 +139,115 @@ func Login(w http.ResponseWriter, r *http.Request) {
 // @Tags         auth
 // @Accept       json
 // @Produce      json
-// @Param register body models.RegisterRequest true
-// @Success      201  {object}  map[string]string
-// @Failure      400  {string}  string  "Bad request"
+// @Param        register  body  AuthRequest  true  "Registration info"
+// @Success      201  {object}  TokenResponse
+// @Failure      400  {object}  utils.Problem  "Bad request"
 // @Router       /register [post]
-func Register(w http.ResponseWriter, r *http.Request) {
-	var req models.User
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" || req.Password == "" {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+func (c *AuthController) Register(w http.ResponseWriter, r *http.Request) {
+	var req AuthRequest
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
-	if err := services.RegisterUser(req.Username, req.Password); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// 🔑 The created user carries the ID for the token
+	user, err := c.auth.RegisterUser(req.Username, req.Password)
+	if errors.Is(err, services.ErrInvalidInput) {
+		invalidInput(w, r, err)
+		return
+	} else if err != nil {
+		serverError(w, r, err, "Failed to register user")
 		return
 	}
 
-	// 🔑 Fetch the newly created user to get ID for token
-	var user models.User
-	if err := database.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-		http.Error(w, "User lookup failed after registration", http.StatusInternalServerError)
+	// 🔐 Start a session
+	pair, err := c.tokens.Issue(user)
+	if err != nil {
+		serverError(w, r, err, "Failed to generate token")
 		return
 	}
 
-	// 🔐 Generate JWT token
-	token, err := middleware.GenerateToken(user.Username, user.ID)
-	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+	// ✅ Respond with tokens
+	resp := newTokenResponse(pair)
+	resp.Message = "Registered and logged in successfully"
+	utils.JSON(w, http.StatusCreated, resp)
+}
+
+// RefreshToken godoc
+// @Summary      Refresh the access token
+// @Description  Exchanges a refresh token for a new access token and refresh token. Each refresh token works once; reusing one revokes the whole session.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        refresh  body  RefreshRequest  true  "Refresh token"
+// @Success      200  {object}  TokenResponse
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      403  {object}  utils.Problem  "Account disabled"
+// @Router       /token/refresh [post]
+func (c *AuthController) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req RefreshRequest
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
-	// ✅ Respond with token
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Registered and logged in successfully",
-		"token":   token,
-	})
+	pair, err := c.tokens.Refresh(req.RefreshToken)
+	if errors.Is(err, services.ErrAccountDisabled) {
+		utils.Error(w, r, http.StatusForbidden, err.Error())
+		return
+	} else if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+		utils.Error(w, r, http.StatusUnauthorized, err.Error())
+		return
+	} else if err != nil {
+		serverError(w, r, err, "Token generation failed")
+		return
+	}
+
+	utils.JSON(w, http.StatusOK, newTokenResponse(pair))
+}
+
+// Logout godoc
+// @Summary      Log out
+// @Description  Revokes the current session: its refresh token and access tokens.
+// @Tags         auth
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Router       /logout [post]
+func (c *AuthController) Logout(w http.ResponseWriter, r *http.Request) {
+	sessionID, ok := middleware.SessionID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := c.tokens.Logout(sessionID); err != nil {
+		serverError(w, r, err, "Failed to log out")
+		return
+	}
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Logged out"})
+}
+
+// LogoutAll godoc
+// @Summary      Log out everywhere
+// @Description  Revokes every session of the logged-in user.
+// @Tags         auth
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Router       /logout-all [post]
+func (c *AuthController) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := c.tokens.LogoutAll(userID); err != nil {
+		serverError(w, r, err, "Failed to log out")
+		return
+	}
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Logged out of all sessions"})
 }
 
 /* func Register(w http.ResponseWriter, r *http.Request) {
@@ -189,14 +316,14 @@ func Register(w http.ResponseWriter, r *http.Request) {
 
 // GetProfile godoc
 // @Summary      Get logged-in user info
-// @Description  Returns the authenticated user's username and ID from the JWT token.
+// @Description  Returns the authenticated user's username, ID and role from the JWT token.
 // @Tags         auth
 // @Security     BearerAuth
 // @Produce      json
 // @Success      200  {object}  map[string]interface{}
-// @Failure      401  {object}  map[string]string
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
 // @Router       /me [get]
-func GetProfile(w http.ResponseWriter, r *http.Request) {
+func (c *AuthController) GetProfile(w http.ResponseWriter, r *http.Request) {
 	username := r.Context().Value("username")
 	userID := r.Context().Value("userID")
 
@@ -204,15 +331,17 @@ func GetProfile(w http.ResponseWriter, r *http.Request) {
 	// username := r.Context().Value("username").(string)
 
 	if username == nil || userID == nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	/* json.NewEncoder(w).Encode(map[string]string{
 		"username": username.(string),
 	}) */
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	role, _ := middleware.Role(r)
+	utils.JSON(w, http.StatusOK, map[string]interface{}{
 		"username": username.(string),
 		"userID":   userID.(uint),
+		"role":     role,
 	})
 }
@@ -1,153 +1,221 @@
-package controllers
+package controllers_test
 
 import (
-	"bytes"
-	"encoding/json"
 	"net/http"
-	"net/http/httptest"
 	"testing"
+	"time"
 
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/middleware"
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
-
-	"github.com/gorilla/mux"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/config"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/testutil"
 )
 
 func TestLoginUser(t *testing.T) {
-	database.Connect()
-	models.InitUserModel()
-
-	// Ensure the user exists first
-	username := "loginuser"
-	password := "testpass"
-
-	// Remove user if exists (clean slate)
-	database.DB.Where("username = ?", username).Delete(&models.User{})
+	srv := testutil.NewServer(t)
+	srv.Register(t, "loginuser")
 
-	// Hash password
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-
-	// Create user directly in DB
-	user := models.User{
-		Username: username,
-		Password: string(hashedPassword),
+	// Prepare login request
+	resp := srv.Do(t, "POST", "/login", "", map[string]string{
+		"username": "loginuser",
+		"password": testutil.Password,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
 	}
-	database.DB.Create(&user)
 
-	// Prepare login request
-	loginPayload := map[string]string{
-		"username": username,
-		"password": password,
+	var response map[string]interface{}
+	resp.Decode(t, &response)
+	if token, _ := response["token"].(string); token == "" {
+		t.Errorf("Expected token in response, got %v", response)
+	}
+	if refresh, _ := response["refresh_token"].(string); refresh == "" {
+		t.Errorf("Expected refresh token in response, got %v", response)
 	}
-	body, _ := json.Marshal(loginPayload)
 
-	req, err := http.NewRequest("POST", "/login", bytes.NewBuffer(body))
-	if err != nil {
-		t.Fatal(err)
+	resp = srv.Do(t, "POST", "/login", "", map[string]string{
+		"username": "loginuser",
+		"password": "WrongPass1!",
+	})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a wrong password, got %d", resp.StatusCode)
 	}
-	req.Header.Set("Content-Type", "application/json")
+}
 
-	router := mux.NewRouter()
-	router.HandleFunc("/login", Login).Methods("POST")
+func TestRegisterUser(t *testing.T) {
+	srv := testutil.NewServer(t)
 
-	rr := httptest.NewRecorder()
-	router.ServeHTTP(rr, req)
+	payload := map[string]string{
+		"username": "test_register_user",
+		"password": "Pass123!",
+	}
+	resp := srv.Do(t, "POST", "/register", "", payload)
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected status code %d, got %d", http.StatusCreated, resp.StatusCode)
+	}
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("Expected status 200, got %d", rr.Code)
+	// The username is now taken.
+	resp = srv.Do(t, "POST", "/register", "", payload)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for a duplicate, got %d", http.StatusBadRequest, resp.StatusCode)
 	}
+}
 
-	var response map[string]string
-	json.NewDecoder(rr.Body).Decode(&response)
+func TestGetProfile(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "testuser_me")
 
-	token, ok := response["token"]
-	if !ok || token == "" {
-		t.Errorf("Expected token in response, got %v", response)
+	resp := srv.Do(t, "GET", "/me", token, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d", resp.StatusCode)
+	}
+
+	var body map[string]interface{}
+	resp.Decode(t, &body)
+	if body["username"] != "testuser_me" {
+		t.Errorf("expected username 'testuser_me', got '%v'", body["username"])
+	}
+	if id, _ := body["userID"].(float64); id == 0 {
+		t.Errorf("expected a userID, got %v", body["userID"])
 	}
 }
 
-func TestRegisterUser(t *testing.T) {
-	database.Connect()     // Ensure DB is connected
-	models.InitUserModel() // Ensure migration has run
+func TestRefreshTokenRotation(t *testing.T) {
+	srv := testutil.NewServer(t)
+	srv.Register(t, "refresh_user")
+	first := srv.Login(t, "refresh_user")
 
-	router := mux.NewRouter()
-	router.HandleFunc("/register", Register).Methods("POST")
+	resp := srv.Do(t, "POST", "/token/refresh", "", map[string]string{"refresh_token": first.RefreshToken})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d %s", resp.StatusCode, resp.Body)
+	}
+	var second testutil.Tokens
+	resp.Decode(t, &second)
+	if second.Token == "" || second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Fatalf("Expected a new token pair, got %+v", second)
+	}
+	if resp = srv.Do(t, "GET", "/me", second.Token, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the refreshed access token to work, got %d", resp.StatusCode)
+	}
 
-	//Optional Enhancement (clean DB before test)
-	database.DB.Where("username = ?", "test_register_user").Delete(&models.User{})
+	// Reusing the first refresh token kills the whole family.
+	resp = srv.Do(t, "POST", "/token/refresh", "", map[string]string{"refresh_token": first.RefreshToken})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a reused refresh token, got %d", resp.StatusCode)
+	}
+	resp = srv.Do(t, "POST", "/token/refresh", "", map[string]string{"refresh_token": second.RefreshToken})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the latest refresh token to be revoked, got %d", resp.StatusCode)
+	}
+	if resp = srv.Do(t, "GET", "/me", second.Token, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the access token of the family to be revoked, got %d", resp.StatusCode)
+	}
 
-	// Define request payload
-	payload := models.User{
-		Username: "test_register_user",
-		Password: "Pass123!",
+	resp = srv.Do(t, "POST", "/token/refresh", "", map[string]string{"refresh_token": "not-a-token"})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for an unknown refresh token, got %d", resp.StatusCode)
 	}
-	body, _ := json.Marshal(payload)
+}
+
+func TestLogout(t *testing.T) {
+	srv := testutil.NewServer(t)
+	srv.Register(t, "logout_user")
+	phone := srv.Login(t, "logout_user")
+	laptop := srv.Login(t, "logout_user")
 
-	req, err := http.NewRequest("POST", "/register", bytes.NewBuffer(body))
-	if err != nil {
-		t.Fatal(err)
+	if resp := srv.Do(t, "POST", "/logout", phone.Token, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	rr := httptest.NewRecorder()
-	router.ServeHTTP(rr, req)
+	if resp := srv.Do(t, "GET", "/me", phone.Token, nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the logged out access token to be rejected, got %d", resp.StatusCode)
+	}
+	resp := srv.Do(t, "POST", "/token/refresh", "", map[string]string{"refresh_token": phone.RefreshToken})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected the logged out refresh token to be rejected, got %d", resp.StatusCode)
+	}
 
-	if status := rr.Code; status != http.StatusCreated {
-		t.Errorf("Expected status code %d, got %d", http.StatusCreated, status)
+	// The other session is untouched.
+	if resp := srv.Do(t, "GET", "/me", laptop.Token, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the other session to work, got %d", resp.StatusCode)
+	}
+	resp = srv.Do(t, "POST", "/token/refresh", "", map[string]string{"refresh_token": laptop.RefreshToken})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the other session to refresh, got %d", resp.StatusCode)
 	}
 }
 
-func TestGetProfile(t *testing.T) {
-	database.Connect()
-	models.InitUserModel()
-
-	// Step 1: Insert test user
-	username := "testuser_me"
-	password := "StrongPass1!"
-	database.DB.Where("username = ?", username).Delete(&models.User{}) // clean slate
+func TestLogoutAll(t *testing.T) {
+	srv := testutil.NewServer(t)
+	srv.Register(t, "logout_all_user")
+	phone := srv.Login(t, "logout_all_user")
+	laptop := srv.Login(t, "logout_all_user")
+	otherToken := srv.Register(t, "logout_all_other")
 
-	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	user := models.User{
-		Username: username,
-		Password: string(hashedPassword),
+	if resp := srv.Do(t, "POST", "/logout-all", phone.Token, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
 	}
-	database.DB.Create(&user)
 
-	// Step 2: Generate token with user ID
-	token, err := middleware.GenerateToken(user.Username, user.ID)
-	if err != nil {
-		t.Fatalf("failed to generate token: %v", err)
+	for _, session := range []testutil.Tokens{phone, laptop} {
+		if resp := srv.Do(t, "GET", "/me", session.Token, nil); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected every access token to be rejected, got %d", resp.StatusCode)
+		}
+		resp := srv.Do(t, "POST", "/token/refresh", "", map[string]string{"refresh_token": session.RefreshToken})
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected every refresh token to be rejected, got %d", resp.StatusCode)
+		}
 	}
 
-	// Step 3: Prepare request
-	req, err := http.NewRequest("GET", "/me", nil)
-	if err != nil {
-		t.Fatal(err)
+	if resp := srv.Do(t, "GET", "/me", otherToken, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected other users to stay logged in, got %d", resp.StatusCode)
 	}
-	req.Header.Set("Authorization", "Bearer "+token)
-
-	rr := httptest.NewRecorder()
-	router := mux.NewRouter()
-	router.Handle("/me", middleware.JWTMiddleware(http.HandlerFunc(GetProfile))).Methods("GET")
-	router.ServeHTTP(rr, req)
+}
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("expected 200 OK, got %d", rr.Code)
+func TestLoginLockout(t *testing.T) {
+	srv := testutil.NewServerWithConfig(t, config.Config{
+		LoginLockoutThreshold: 3,
+		LoginLockoutBase:      time.Minute,
+		LoginLockoutMax:       time.Hour,
+	})
+	srv.Register(t, "locked_user")
+	srv.Register(t, "other_user")
+	login := func(username, password string) *testutil.Response {
+		return srv.Do(t, "POST", "/login", "", map[string]string{"username": username, "password": password})
 	}
 
-	var resp map[string]interface{}
-	err = json.NewDecoder(rr.Body).Decode(&resp)
-	if err != nil {
-		t.Fatalf("failed to decode response: %v", err)
+	for i := 1; i <= 2; i++ {
+		if resp := login("locked_user", "wrong"); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("failure %d: expected status 401, got %d", i, resp.StatusCode)
+		}
+	}
+	resp := login("LOCKED_USER", "wrong") // usernames are locked regardless of case
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "60" {
+		t.Fatalf("Expected 429 with Retry-After 60 on the third failure, got %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if resp := login("locked_user", testutil.Password); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected the right password to be refused while locked, got %d", resp.StatusCode)
+	}
+	if resp := login("other_user", testutil.Password); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected other users to log in, got %d", resp.StatusCode)
 	}
 
-	if resp["username"] != username {
-		t.Errorf("expected username '%s', got '%s'", username, resp["username"])
+	// Unknown usernames are locked the same way.
+	for i := 0; i < 2; i++ {
+		login("ghost", "wrong")
+	}
+	if resp := login("ghost", "wrong"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected unknown usernames to be locked too, got %d", resp.StatusCode)
 	}
+}
 
-	if uint(resp["userID"].(float64)) != user.ID {
-		t.Errorf("expected userID %d, got %v", user.ID, resp["userID"])
+func TestAuthRateLimit(t *testing.T) {
+	srv := testutil.NewServerWithConfig(t, config.Config{AuthRateLimit: 2})
+	for i := 0; i < 2; i++ {
+		resp := srv.Do(t, "POST", "/login", "", map[string]string{"username": "nobody", "password": "x"})
+		if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("RateLimit-Limit") != "2" {
+			t.Fatalf("request %d: expected 401 with RateLimit headers, got %d %v", i, resp.StatusCode, resp.Header)
+		}
+	}
+	resp := srv.Do(t, "POST", "/register", "", map[string]string{"username": "late_user", "password": testutil.Password})
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("Expected the IP's third auth request to get 429 with Retry-After, got %d %v", resp.StatusCode, resp.Header)
 	}
 }
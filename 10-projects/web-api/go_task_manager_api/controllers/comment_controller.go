@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/middleware"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/services"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+
+	"github.com/gorilla/mux"
+)
+
+type CommentController struct {
+	comments *services.CommentService
+}
+
+func NewCommentController(comments *services.CommentService) *CommentController {
+	return &CommentController{comments: comments}
+}
+
+// CommentRequest is the body of posting or editing a comment.
+type CommentRequest struct {
+	Body string `json:"body" validate:"required,max=5000" example:"Numbers are in the shared folder"`
+}
+
+// CommentListResponse is one page of comments, oldest first.
+type CommentListResponse struct {
+	Data       []models.Comment `json:"data"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// GetComments godoc
+// @Summary      List comments
+// @Description  Lists the comments of a task, oldest first, one page at a time. Pass next_cursor as cursor for the next page.
+// @Tags         comments
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id      path      int     true   "Task ID"
+// @Param        limit   query     int     false  "Page size (1-100)"  default(20)
+// @Param        cursor  query     string  false  "Cursor from the previous page"
+// @Success      200  {object}  CommentListResponse
+// @Header       200  {string}  Link  "URL of the next page, rel=\"next\""
+// @Failure      400  {object}  utils.Problem  "Invalid query"
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      404  {object}  utils.Problem  "Task not found"
+// @Router       /tasks/{id}/comments [get]
+func (c *CommentController) GetComments(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	taskID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	opts := services.CommentListOptions{Cursor: r.URL.Query().Get("cursor")}
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			invalidInput(w, r, &services.FieldError{Field: "limit", Message: fmt.Sprintf("invalid limit %q", v)})
+			return
+		}
+		opts.Limit = limit
+	}
+	page, err := c.comments.ListComments(uint(taskID), userID, opts)
+	if err != nil {
+		commentError(w, r, err)
+		return
+	}
+	setNextLink(w, r, page.NextCursor)
+	utils.JSON(w, http.StatusOK, CommentListResponse{Data: page.Comments, NextCursor: page.NextCursor})
+}
+
+// CreateComment godoc
+// @Summary      Comment on a task
+// @Description  Posts a comment of the logged-in user on a task
+// @Tags         comments
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int             true  "Task ID"
+// @Param        comment  body      CommentRequest  true  "Comment"
+// @Success      201  {object}  models.Comment
+// @Failure      400  {object}  utils.Problem  "Invalid comment"
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      404  {object}  utils.Problem  "Task not found"
+// @Router       /tasks/{id}/comments [post]
+func (c *CommentController) CreateComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	taskID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var req CommentRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	comment, err := c.comments.AddComment(uint(taskID), userID, req.Body)
+	if err != nil {
+		commentError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusCreated, comment)
+}
+
+// UpdateComment godoc
+// @Summary      Edit a comment
+// @Description  Replaces the body of a comment. Only its author may.
+// @Tags         comments
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int             true  "Task ID"
+// @Param        cid      path      int             true  "Comment ID"
+// @Param        comment  body      CommentRequest  true  "Comment"
+// @Success      200  {object}  models.Comment
+// @Failure      400  {object}  utils.Problem  "Invalid comment"
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      403  {object}  utils.Problem  "Not the author"
+// @Failure      404  {object}  utils.Problem  "Task or comment not found"
+// @Router       /tasks/{id}/comments/{cid} [put]
+func (c *CommentController) UpdateComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	params := mux.Vars(r)
+	taskID, _ := strconv.Atoi(params["id"])
+	id, _ := strconv.Atoi(params["cid"])
+
+	var req CommentRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	comment, err := c.comments.EditComment(uint(taskID), uint(id), userID, req.Body)
+	if err != nil {
+		commentError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusOK, comment)
+}
+
+// DeleteComment godoc
+// @Summary      Delete a comment
+// @Description  Deletes a comment. Its author, the owner of the task and the owners of the task's project may.
+// @Tags         comments
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Task ID"
+// @Param        cid  path      int  true  "Comment ID"
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      403  {object}  utils.Problem  "Neither author nor task owner"
+// @Failure      404  {object}  utils.Problem  "Task or comment not found"
+// @Router       /tasks/{id}/comments/{cid} [delete]
+func (c *CommentController) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	params := mux.Vars(r)
+	taskID, _ := strconv.Atoi(params["id"])
+	id, _ := strconv.Atoi(params["cid"])
+
+	if err := c.comments.DeleteComment(uint(taskID), uint(id), userID); err != nil {
+		commentError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Comment deleted"})
+}
+
+// commentError is taskError for comments: 404 for a missing comment, and
+// 403 for changes the user may not make like taskError.
+func commentError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrCommentNotFound):
+		utils.Error(w, r, http.StatusNotFound, "Comment not found")
+	case errors.Is(err, repositories.ErrNotFound):
+		utils.Error(w, r, http.StatusNotFound, "Task not found")
+	default:
+		taskError(w, r, err)
+	}
+}
@@ -0,0 +1,182 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/testutil"
+)
+
+// createTask creates a task of token and returns it.
+func createTask(t *testing.T, srv *testutil.Server, token, title string) models.Task {
+	t.Helper()
+	var task models.Task
+	resp := srv.Do(t, "POST", "/tasks", token, controllers.TaskRequest{Title: title})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("failed to create task: %d %s", resp.StatusCode, resp.Body)
+	}
+	resp.Decode(t, &task)
+	return task
+}
+
+func TestComments(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "comment_user")
+	task := createTask(t, srv, token, "Discuss me")
+	path := fmt.Sprintf("/tasks/%d/comments", task.ID)
+
+	resp := srv.Do(t, "POST", path, token, controllers.CommentRequest{Body: "First thought"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", resp.StatusCode, resp.Body)
+	}
+	var comment models.Comment
+	resp.Decode(t, &comment)
+	if comment.TaskID != task.ID || comment.AuthorID != userID(t, srv, token) || comment.Body != "First thought" || comment.CreatedAt.IsZero() {
+		t.Errorf("Unexpected comment %+v", comment)
+	}
+
+	resp = srv.Do(t, "PUT", fmt.Sprintf("%s/%d", path, comment.ID), token, controllers.CommentRequest{Body: "Second thought"})
+	resp.Decode(t, &comment)
+	if resp.StatusCode != http.StatusOK || comment.Body != "Second thought" {
+		t.Errorf("Expected the edited comment, got %d %+v", resp.StatusCode, comment)
+	}
+
+	var page controllers.CommentListResponse
+	srv.Do(t, "GET", path, token, nil).Decode(t, &page)
+	if len(page.Data) != 1 || page.Data[0].Body != "Second thought" {
+		t.Errorf("Expected the edited comment in the list, got %+v", page.Data)
+	}
+
+	if resp := srv.Do(t, "DELETE", fmt.Sprintf("%s/%d", path, comment.ID), token, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 on delete, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "DELETE", fmt.Sprintf("%s/%d", path, comment.ID), token, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted comment, got %d", resp.StatusCode)
+	}
+
+	for _, body := range []interface{}{controllers.CommentRequest{Body: "  "}, map[string]string{"body": strings.Repeat("x", 5001)}} {
+		if resp := srv.Do(t, "POST", path, token, body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for an invalid comment, got %d", resp.StatusCode)
+		}
+	}
+}
+
+func TestCommentPermissions(t *testing.T) {
+	srv := testutil.NewServer(t)
+	owner := srv.Register(t, "comment_owner")
+	other := srv.Register(t, "comment_other")
+	task := createTask(t, srv, owner, "Private")
+	path := fmt.Sprintf("/tasks/%d/comments", task.ID)
+
+	// Others cannot see the task, so neither its comments.
+	if resp := srv.Do(t, "GET", path, other, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for comments of another user's task, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "POST", path, other, controllers.CommentRequest{Body: "Hi"}); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 commenting on another user's task, got %d", resp.StatusCode)
+	}
+
+	// A comment by somebody else on the owner's task, as once tasks can be
+	// shared.
+	foreign := models.Comment{TaskID: task.ID, AuthorID: userID(t, srv, other), Body: "Not yours"}
+	if err := srv.DB.Create(&foreign).Error; err != nil {
+		t.Fatal(err)
+	}
+	foreignPath := fmt.Sprintf("%s/%d", path, foreign.ID)
+	if resp := srv.Do(t, "PUT", foreignPath, owner, controllers.CommentRequest{Body: "Mine now"}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 editing another author's comment, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "DELETE", foreignPath, other, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an author who lost access to the task, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "DELETE", foreignPath, owner, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the task owner to delete any comment, got %d", resp.StatusCode)
+	}
+
+	// Comment IDs only count under their own task.
+	otherTask := createTask(t, srv, owner, "Other")
+	var comment models.Comment
+	srv.Do(t, "POST", path, owner, controllers.CommentRequest{Body: "Here"}).Decode(t, &comment)
+	if resp := srv.Do(t, "DELETE", fmt.Sprintf("/tasks/%d/comments/%d", otherTask.ID, comment.ID), owner, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a comment of another task, got %d", resp.StatusCode)
+	}
+}
+
+func TestCommentPagination(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "comment_pager")
+	task := createTask(t, srv, token, "Busy")
+	path := fmt.Sprintf("/tasks/%d/comments", task.ID)
+	for i := 1; i <= 5; i++ {
+		srv.Do(t, "POST", path, token, controllers.CommentRequest{Body: fmt.Sprintf("c%d", i)})
+	}
+
+	var bodies []string
+	next := path + "?limit=2"
+	for pages := 0; next != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("Too many pages")
+		}
+		resp := srv.Do(t, "GET", next, token, nil)
+		var page controllers.CommentListResponse
+		resp.Decode(t, &page)
+		for _, c := range page.Data {
+			bodies = append(bodies, c.Body)
+		}
+		next = ""
+		if page.NextCursor != "" {
+			next = path + "?limit=2&cursor=" + page.NextCursor
+			if !strings.Contains(resp.Header.Get("Link"), `rel="next"`) {
+				t.Errorf("Expected a Link header, got %q", resp.Header.Get("Link"))
+			}
+		}
+	}
+	if strings.Join(bodies, ",") != "c1,c2,c3,c4,c5" {
+		t.Errorf("Expected all comments oldest first, got %v", bodies)
+	}
+
+	// Cursors of task lists are not comment cursors.
+	var tasks controllers.TaskListResponse
+	createTask(t, srv, token, "Second")
+	srv.Do(t, "GET", "/tasks?limit=1", token, nil).Decode(t, &tasks)
+	for _, query := range []string{"?limit=0", "?limit=101", "?cursor=nonsense", "?cursor=" + tasks.NextCursor} {
+		if resp := srv.Do(t, "GET", path+query, token, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, resp.StatusCode)
+		}
+	}
+}
+
+func TestTaskListCommentCount(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "comment_counter")
+	busy := createTask(t, srv, token, "Busy")
+	createTask(t, srv, token, "Quiet")
+	for i := 0; i < 3; i++ {
+		srv.Do(t, "POST", fmt.Sprintf("/tasks/%d/comments", busy.ID), token, controllers.CommentRequest{Body: "more"})
+	}
+
+	var page controllers.TaskListResponse
+	srv.Do(t, "GET", "/tasks", token, nil).Decode(t, &page)
+	counts := map[string]int64{}
+	for _, task := range page.Data {
+		if task.CommentCount == nil {
+			t.Fatalf("Expected comment_count on %q", task.Title)
+		}
+		counts[task.Title] = *task.CommentCount
+	}
+	if counts["Busy"] != 3 || counts["Quiet"] != 0 {
+		t.Errorf("Unexpected comment counts %v", counts)
+	}
+
+	// Deleting the task deletes its comments.
+	srv.Do(t, "DELETE", fmt.Sprintf("/tasks/%d", busy.ID), token, nil)
+	var left int64
+	srv.DB.Model(&models.Comment{}).Count(&left)
+	if left != 0 {
+		t.Errorf("Expected the comments to go with the task, %d left", left)
+	}
+}
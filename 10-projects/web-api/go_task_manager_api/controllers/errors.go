@@ -0,0 +1,29 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/services"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+)
+
+// invalidInput writes a 400 problem for a validation error of the services,
+// naming the field it is about if there is one.
+func invalidInput(w http.ResponseWriter, r *http.Request, err error) {
+	var field *services.FieldError
+	if errors.As(err, &field) {
+		utils.ValidationError(w, r, err.Error(), utils.FieldError{Field: field.Field, Message: field.Message})
+		return
+	}
+	utils.ValidationError(w, r, err.Error())
+}
+
+// serverError logs err, which the client must not see, and writes a 500
+// problem with detail. The log record carries the request ID that the
+// client gets in X-Request-ID.
+func serverError(w http.ResponseWriter, r *http.Request, err error, detail string) {
+	slog.ErrorContext(r.Context(), detail, "error", err)
+	utils.Error(w, r, http.StatusInternalServerError, detail)
+}
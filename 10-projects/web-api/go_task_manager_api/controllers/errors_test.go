@@ -0,0 +1,56 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/testutil"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+)
+
+// Every failure, whether from a handler, a middleware or the router, is an
+// RFC 7807 problem.
+func TestErrorsAreProblems(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "problem_user")
+
+	tests := []struct {
+		method, path, token string
+		body                interface{}
+		status              int
+		field               string // of the first field error, if any
+	}{
+		{"GET", "/tasks", "", nil, http.StatusUnauthorized, ""},
+		{"GET", "/admin/users", token + "x", nil, http.StatusUnauthorized, ""},
+		{"POST", "/tasks", token, "not a task", http.StatusBadRequest, ""},
+		{"POST", "/tasks", token, map[string]string{"description": "no title"}, http.StatusBadRequest, "title"},
+		{"POST", "/tasks", token, map[string]string{"title": "x", "priority": "urgent"}, http.StatusBadRequest, "priority"},
+		{"GET", "/tasks?limit=many", token, nil, http.StatusBadRequest, "limit"},
+		{"GET", "/tasks?sort=owner", token, nil, http.StatusBadRequest, "sort"},
+		{"GET", "/tasks/999999", token, nil, http.StatusNotFound, ""},
+		{"POST", "/register", "", map[string]string{"username": "problem_user", "password": testutil.Password}, http.StatusBadRequest, "username"},
+		{"POST", "/register", "", map[string]string{"username": "weak_user", "password": "weak"}, http.StatusBadRequest, "password"},
+		{"POST", "/login", "", map[string]string{"username": "problem_user", "password": "wrong"}, http.StatusUnauthorized, ""},
+		{"GET", "/no/such/route", "", nil, http.StatusNotFound, ""},
+		{"DELETE", "/login", "", nil, http.StatusMethodNotAllowed, ""},
+	}
+	for _, tt := range tests {
+		resp := srv.Do(t, tt.method, tt.path, tt.token, tt.body)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d %s", tt.method, tt.path, tt.status, resp.StatusCode, resp.Body)
+			continue
+		}
+		if ct := resp.Header.Get("Content-Type"); ct != utils.ProblemContentType {
+			t.Errorf("%s %s: expected Content-Type %s, got %q", tt.method, tt.path, utils.ProblemContentType, ct)
+			continue
+		}
+		var problem utils.Problem
+		resp.Decode(t, &problem)
+		if problem.Status != tt.status || problem.Title != http.StatusText(tt.status) || problem.Type != "about:blank" {
+			t.Errorf("%s %s: unexpected problem %+v", tt.method, tt.path, problem)
+		}
+		if tt.field != "" && (len(problem.Errors) == 0 || problem.Errors[0].Field != tt.field) {
+			t.Errorf("%s %s: expected an error for field %s, got %+v", tt.method, tt.path, tt.field, problem.Errors)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/services"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+)
+
+// readyTimeout bounds the checks of /readyz, so that a hanging database
+// fails the probe instead of blocking it.
+const readyTimeout = 2 * time.Second
+
+type HealthController struct {
+	health *services.HealthService
+}
+
+func NewHealthController(health *services.HealthService) *HealthController {
+	return &HealthController{health: health}
+}
+
+// HealthResponse is the body of the probes that pass.
+type HealthResponse struct {
+	Status string `json:"status" example:"ok"`
+}
+
+// Live godoc
+// @Summary      Liveness probe
+// @Description  Answers as long as the server process is able to serve HTTP. It does not check the database, so that a database outage does not get the server restarted.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  HealthResponse
+// @Router       /healthz [get]
+func (c *HealthController) Live(w http.ResponseWriter, r *http.Request) {
+	utils.JSON(w, http.StatusOK, HealthResponse{Status: "ok"})
+}
+
+// Ready godoc
+// @Summary      Readiness probe
+// @Description  Checks that the database answers and has every migration applied. Load balancers should only send traffic while this returns 200.
+// @Tags         health
+// @Produce      json
+// @Success      200  {object}  HealthResponse
+// @Failure      503  {object}  utils.Problem  "Not ready"
+// @Router       /readyz [get]
+func (c *HealthController) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+
+	err := c.health.Ready(ctx)
+	switch {
+	case err == nil:
+		utils.JSON(w, http.StatusOK, HealthResponse{Status: "ready"})
+	case errors.Is(err, services.ErrMigrationsPending):
+		utils.Error(w, r, http.StatusServiceUnavailable, err.Error())
+	default:
+		// The cause may name hosts or users of the database.
+		slog.WarnContext(r.Context(), "Readiness check failed", "error", err)
+		utils.Error(w, r, http.StatusServiceUnavailable, services.ErrDatabaseUnavailable.Error())
+	}
+}
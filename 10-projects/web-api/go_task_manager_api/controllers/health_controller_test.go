@@ -0,0 +1,62 @@
+package controllers_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/testutil"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+)
+
+func TestHealthProbes(t *testing.T) {
+	srv := testutil.NewServer(t)
+
+	var health controllers.HealthResponse
+	resp := srv.Do(t, "GET", "/healthz", "", nil)
+	resp.Decode(t, &health)
+	if resp.StatusCode != http.StatusOK || health.Status != "ok" {
+		t.Errorf("expected live, got %d %+v", resp.StatusCode, health)
+	}
+	resp = srv.Do(t, "GET", "/readyz", "", nil)
+	resp.Decode(t, &health)
+	if resp.StatusCode != http.StatusOK || health.Status != "ready" {
+		t.Errorf("expected ready, got %d %+v", resp.StatusCode, health)
+	}
+}
+
+func TestNotReadyWithPendingMigrations(t *testing.T) {
+	srv := testutil.NewServer(t)
+	srv.DB.Where("id = ?", "0003_task_version").Delete(&models.SchemaMigration{})
+
+	var problem utils.Problem
+	resp := srv.Do(t, "GET", "/readyz", "", nil)
+	resp.Decode(t, &problem)
+	if resp.StatusCode != http.StatusServiceUnavailable || !strings.Contains(problem.Detail, "0003_task_version") {
+		t.Errorf("expected 503 naming the pending migration, got %d %+v", resp.StatusCode, problem)
+	}
+	if resp := srv.Do(t, "GET", "/healthz", "", nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected to stay live, got %d", resp.StatusCode)
+	}
+}
+
+func TestNotReadyWithoutDatabase(t *testing.T) {
+	srv := testutil.NewServer(t)
+	sqlDB, err := srv.DB.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.Close()
+
+	var problem utils.Problem
+	resp := srv.Do(t, "GET", "/readyz", "", nil)
+	resp.Decode(t, &problem)
+	if resp.StatusCode != http.StatusServiceUnavailable || problem.Detail != "database unavailable" {
+		t.Errorf("expected 503 without details of the database, got %d %+v", resp.StatusCode, problem)
+	}
+	if resp := srv.Do(t, "GET", "/healthz", "", nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected to stay live, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,299 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/middleware"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/services"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+
+	"github.com/gorilla/mux"
+)
+
+// ProjectController serves projects and their members. Who may do what is
+// checked by services.ProjectService, not here.
+type ProjectController struct {
+	projects *services.ProjectService
+}
+
+func NewProjectController(projects *services.ProjectService) *ProjectController {
+	return &ProjectController{projects: projects}
+}
+
+// ProjectRequest is the body of creating a project.
+type ProjectRequest struct {
+	Name        string `json:"name" validate:"required,max=200" example:"Q3 report"`
+	Description string `json:"description" validate:"max=5000" example:"Everything for the quarterly report"`
+}
+
+// MemberRequest is the body of adding a member to a project.
+type MemberRequest struct {
+	Username string `json:"username" validate:"required,max=50" example:"jane_doe"`
+	Role     string `json:"role" validate:"required,oneof=owner editor viewer" enums:"owner,editor,viewer" example:"editor"`
+}
+
+// MemberRoleRequest is the body of changing the role of a member.
+type MemberRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner editor viewer" enums:"owner,editor,viewer" example:"viewer"`
+}
+
+// GetProjects godoc
+// @Summary      List projects
+// @Description  Lists the projects the logged-in user is a member of, with their role, by name
+// @Tags         projects
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {array}   models.Project
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Router       /projects [get]
+func (c *ProjectController) GetProjects(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	projects, err := c.projects.ListProjects(userID)
+	if err != nil {
+		serverError(w, r, err, "Failed to list projects")
+		return
+	}
+	utils.JSON(w, http.StatusOK, projects)
+}
+
+// CreateProject godoc
+// @Summary      Create a project
+// @Description  Creates a project with the logged-in user as its owner
+// @Tags         projects
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        project  body      ProjectRequest  true  "Project"
+// @Success      201  {object}  models.Project
+// @Failure      400  {object}  utils.Problem  "Invalid project"
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Router       /projects [post]
+func (c *ProjectController) CreateProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req ProjectRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	project := models.Project{Name: req.Name, Description: req.Description}
+	if err := c.projects.CreateProject(userID, &project); err != nil {
+		projectError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusCreated, project)
+}
+
+// GetProject godoc
+// @Summary      Get a project
+// @Description  Gets a project of the logged-in user with their role. Its tasks are listed by GET /tasks?project_id={id}.
+// @Tags         projects
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  models.Project
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      404  {object}  utils.Problem  "Project not found"
+// @Router       /projects/{id} [get]
+func (c *ProjectController) GetProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	project, err := c.projects.GetProject(uint(id), userID)
+	if err != nil {
+		projectError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusOK, project)
+}
+
+// DeleteProject godoc
+// @Summary      Delete a project
+// @Description  Deletes a project that has no tasks left. Only its owners may.
+// @Tags         projects
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      403  {object}  utils.Problem  "Not an owner"
+// @Failure      404  {object}  utils.Problem  "Project not found"
+// @Failure      409  {object}  utils.Problem  "Project still has tasks"
+// @Router       /projects/{id} [delete]
+func (c *ProjectController) DeleteProject(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	if err := c.projects.DeleteProject(uint(id), userID); err != nil {
+		projectError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Project deleted"})
+}
+
+// GetMembers godoc
+// @Summary      List project members
+// @Description  Lists the members of a project with their roles, owners first
+// @Tags         projects
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {array}   models.ProjectMember
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      404  {object}  utils.Problem  "Project not found"
+// @Router       /projects/{id}/members [get]
+func (c *ProjectController) GetMembers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	members, err := c.projects.ListMembers(uint(id), userID)
+	if err != nil {
+		projectError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusOK, members)
+}
+
+// AddMember godoc
+// @Summary      Add a project member
+// @Description  Gives a user a role in a project. Only its owners may.
+// @Tags         projects
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int            true  "Project ID"
+// @Param        member  body      MemberRequest  true  "User and role"
+// @Success      201  {object}  models.ProjectMember
+// @Failure      400  {object}  utils.Problem  "Invalid role or unknown user"
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      403  {object}  utils.Problem  "Not an owner"
+// @Failure      404  {object}  utils.Problem  "Project not found"
+// @Failure      409  {object}  utils.Problem  "User is a member already"
+// @Router       /projects/{id}/members [post]
+func (c *ProjectController) AddMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+	var req MemberRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	member, err := c.projects.AddMember(uint(id), userID, req.Username, req.Role)
+	if err != nil {
+		projectError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusCreated, member)
+}
+
+// UpdateMember godoc
+// @Summary      Change the role of a project member
+// @Description  Changes the role of a member of a project. Only its owners may, and the last owner cannot step down.
+// @Tags         projects
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int                true  "Project ID"
+// @Param        uid     path      int                true  "User ID of the member"
+// @Param        member  body      MemberRoleRequest  true  "New role"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  utils.Problem  "Invalid role"
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      403  {object}  utils.Problem  "Not an owner"
+// @Failure      404  {object}  utils.Problem  "Project or member not found"
+// @Failure      409  {object}  utils.Problem  "Last owner of the project"
+// @Router       /projects/{id}/members/{uid} [put]
+func (c *ProjectController) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	params := mux.Vars(r)
+	id, _ := strconv.Atoi(params["id"])
+	memberID, _ := strconv.Atoi(params["uid"])
+
+	var req MemberRoleRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if err := c.projects.SetMemberRole(uint(id), userID, uint(memberID), req.Role); err != nil {
+		projectError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Role updated"})
+}
+
+// RemoveMember godoc
+// @Summary      Remove a project member
+// @Description  Removes a member from a project. Owners may remove anybody and every member may leave, except the last owner.
+// @Tags         projects
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Param        uid  path      int  true  "User ID of the member"
+// @Success      200  {object}  map[string]string
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      403  {object}  utils.Problem  "Not an owner"
+// @Failure      404  {object}  utils.Problem  "Project or member not found"
+// @Failure      409  {object}  utils.Problem  "Last owner of the project"
+// @Router       /projects/{id}/members/{uid} [delete]
+func (c *ProjectController) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	params := mux.Vars(r)
+	id, _ := strconv.Atoi(params["id"])
+	memberID, _ := strconv.Atoi(params["uid"])
+
+	if err := c.projects.RemoveMember(uint(id), userID, uint(memberID)); err != nil {
+		projectError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Member removed"})
+}
+
+// projectError is taskError for projects: 404 for a missing project or
+// member and 409 for changes that would break a project.
+func projectError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrMemberNotFound):
+		utils.Error(w, r, http.StatusNotFound, "Member not found")
+	case errors.Is(err, repositories.ErrNotFound):
+		utils.Error(w, r, http.StatusNotFound, "Project not found")
+	case errors.Is(err, services.ErrAlreadyMember), errors.Is(err, services.ErrLastOwner), errors.Is(err, services.ErrProjectNotEmpty):
+		utils.Error(w, r, http.StatusConflict, err.Error())
+	default:
+		taskError(w, r, err)
+	}
+}
@@ -0,0 +1,292 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/testutil"
+)
+
+// createProject creates a project owned by token and returns it.
+func createProject(t *testing.T, srv *testutil.Server, token, name string) models.Project {
+	t.Helper()
+	var project models.Project
+	resp := srv.Do(t, "POST", "/projects", token, controllers.ProjectRequest{Name: name})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("failed to create project: %d %s", resp.StatusCode, resp.Body)
+	}
+	resp.Decode(t, &project)
+	return project
+}
+
+// addMember adds username to project with role.
+func addMember(t *testing.T, srv *testutil.Server, token string, project models.Project, username, role string) {
+	t.Helper()
+	resp := srv.Do(t, "POST", fmt.Sprintf("/projects/%d/members", project.ID), token, controllers.MemberRequest{Username: username, Role: role})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("failed to add %s: %d %s", username, resp.StatusCode, resp.Body)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	srv := testutil.NewServer(t)
+	owner := srv.Register(t, "project_owner")
+	other := srv.Register(t, "project_other")
+
+	project := createProject(t, srv, owner, "Launch")
+	if project.ID == 0 || project.Role != models.ProjectOwner {
+		t.Errorf("Expected a new project owned by its creator, got %+v", project)
+	}
+	if resp := srv.Do(t, "POST", "/projects", owner, controllers.ProjectRequest{Name: "  "}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a blank name, got %d", resp.StatusCode)
+	}
+
+	var projects []models.Project
+	srv.Do(t, "GET", "/projects", owner, nil).Decode(t, &projects)
+	if len(projects) != 1 || projects[0].Name != "Launch" || projects[0].Role != models.ProjectOwner {
+		t.Errorf("Expected the project with the owner role, got %+v", projects)
+	}
+	srv.Do(t, "GET", "/projects", other, nil).Decode(t, &projects)
+	if len(projects) != 0 {
+		t.Errorf("Expected no projects for a non-member, got %+v", projects)
+	}
+
+	// Non-members cannot tell that the project exists.
+	path := fmt.Sprintf("/projects/%d", project.ID)
+	for _, req := range []struct{ method, path string }{
+		{"GET", path}, {"DELETE", path}, {"GET", path + "/members"},
+	} {
+		if resp := srv.Do(t, req.method, req.path, other, nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s %s by a non-member: expected 404, got %d", req.method, req.path, resp.StatusCode)
+		}
+	}
+	if resp := srv.Do(t, "POST", path+"/tasks", other, controllers.TaskRequest{Title: "Sneak in"}); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 creating a task in another user's project, got %d", resp.StatusCode)
+	}
+
+	resp := srv.Do(t, "POST", path+"/tasks", owner, controllers.TaskRequest{Title: "Press release"})
+	var task models.Task
+	resp.Decode(t, &task)
+	if resp.StatusCode != http.StatusCreated || task.ProjectID == nil || *task.ProjectID != project.ID {
+		t.Fatalf("Expected a task in the project, got %d %+v", resp.StatusCode, task)
+	}
+
+	if resp := srv.Do(t, "DELETE", path, owner, nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 deleting a project with tasks, got %d", resp.StatusCode)
+	}
+	srv.Do(t, "DELETE", fmt.Sprintf("/tasks/%d", task.ID), owner, nil)
+	if resp := srv.Do(t, "DELETE", path, owner, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 deleting an empty project, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if resp := srv.Do(t, "GET", path, owner, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a deleted project, got %d", resp.StatusCode)
+	}
+}
+
+func TestProjectRoles(t *testing.T) {
+	srv := testutil.NewServer(t)
+	owner := srv.Register(t, "roles_owner")
+	editor := srv.Register(t, "roles_editor")
+	viewer := srv.Register(t, "roles_viewer")
+	outsider := srv.Register(t, "roles_outsider")
+
+	project := createProject(t, srv, owner, "Shared")
+	addMember(t, srv, owner, project, "roles_editor", models.ProjectEditor)
+	addMember(t, srv, owner, project, "roles_viewer", models.ProjectViewer)
+	var task models.Task
+	srv.Do(t, "POST", fmt.Sprintf("/projects/%d/tasks", project.ID), owner, controllers.TaskRequest{Title: "Shared task"}).Decode(t, &task)
+	taskPath := fmt.Sprintf("/tasks/%d", task.ID)
+
+	// Every member sees the task, in GET /tasks too.
+	for _, token := range []string{owner, editor, viewer} {
+		var page controllers.TaskListResponse
+		srv.Do(t, "GET", fmt.Sprintf("/tasks?project_id=%d", project.ID), token, nil).Decode(t, &page)
+		if len(page.Data) != 1 || page.Data[0].ID != task.ID {
+			t.Errorf("Expected members to list the project's task, got %+v", page.Data)
+		}
+	}
+	if resp := srv.Do(t, "GET", taskPath, outsider, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a non-member, got %d", resp.StatusCode)
+	}
+
+	// Viewers may look and comment, but not change anything.
+	if resp := srv.Do(t, "PUT", taskPath, viewer, controllers.TaskRequest{Title: "Mine now"}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for an update by a viewer, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "DELETE", taskPath, viewer, nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a delete by a viewer, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "POST", fmt.Sprintf("/projects/%d/tasks", project.ID), viewer, controllers.TaskRequest{Title: "More"}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a task created by a viewer, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "POST", taskPath+"/comments", viewer, controllers.CommentRequest{Body: "Looks good"}); resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected viewers to comment, got %d %s", resp.StatusCode, resp.Body)
+	}
+
+	// Editors change tasks but not the members.
+	if resp := srv.Do(t, "PUT", taskPath, editor, controllers.TaskRequest{Title: "Edited", Status: models.StatusInProgress}); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for an update by an editor, got %d %s", resp.StatusCode, resp.Body)
+	}
+	var updated models.Task
+	srv.Do(t, "GET", taskPath, owner, nil).Decode(t, &updated)
+	if updated.Title != "Edited" || updated.UserID != task.UserID || updated.ProjectID == nil || *updated.ProjectID != project.ID {
+		t.Errorf("Expected the edit to keep owner and project, got %+v", updated)
+	}
+	membersPath := fmt.Sprintf("/projects/%d/members", project.ID)
+	if resp := srv.Do(t, "POST", membersPath, editor, controllers.MemberRequest{Username: "roles_outsider", Role: models.ProjectViewer}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for an editor adding members, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "DELETE", fmt.Sprintf("/projects/%d", project.ID), editor, nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for an editor deleting the project, got %d", resp.StatusCode)
+	}
+
+	// A demoted editor loses write access at once.
+	viewerPath := fmt.Sprintf("%s/%d", membersPath, userID(t, srv, editor))
+	if resp := srv.Do(t, "PUT", viewerPath, owner, controllers.MemberRoleRequest{Role: models.ProjectViewer}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 changing a role, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if resp := srv.Do(t, "PUT", taskPath, editor, controllers.TaskRequest{Title: "Again"}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 after the demotion, got %d", resp.StatusCode)
+	}
+
+	// Removed members no longer see the task.
+	if resp := srv.Do(t, "DELETE", fmt.Sprintf("%s/%d", membersPath, userID(t, srv, viewer)), owner, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 removing a member, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "GET", taskPath, viewer, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for a removed member, got %d", resp.StatusCode)
+	}
+}
+
+func TestProjectMembers(t *testing.T) {
+	srv := testutil.NewServer(t)
+	owner := srv.Register(t, "members_owner")
+	member := srv.Register(t, "members_member")
+	project := createProject(t, srv, owner, "Team")
+	membersPath := fmt.Sprintf("/projects/%d/members", project.ID)
+	ownerPath := fmt.Sprintf("%s/%d", membersPath, userID(t, srv, owner))
+	memberPath := fmt.Sprintf("%s/%d", membersPath, userID(t, srv, member))
+
+	for _, req := range []controllers.MemberRequest{
+		{Username: "nobody", Role: models.ProjectViewer},
+		{Username: "members_member", Role: "admin"},
+	} {
+		if resp := srv.Do(t, "POST", membersPath, owner, req); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 adding %+v, got %d", req, resp.StatusCode)
+		}
+	}
+	addMember(t, srv, owner, project, "members_member", models.ProjectEditor)
+	if resp := srv.Do(t, "POST", membersPath, owner, controllers.MemberRequest{Username: "members_member", Role: models.ProjectViewer}); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 adding a member twice, got %d", resp.StatusCode)
+	}
+
+	var members []models.ProjectMember
+	srv.Do(t, "GET", membersPath, member, nil).Decode(t, &members)
+	if len(members) != 2 || members[0].Username != "members_owner" || members[0].Role != models.ProjectOwner ||
+		members[1].Username != "members_member" || members[1].Role != models.ProjectEditor {
+		t.Errorf("Expected the owner, then the editor, got %+v", members)
+	}
+
+	// The last owner can neither step down nor leave.
+	if resp := srv.Do(t, "PUT", ownerPath, owner, controllers.MemberRoleRequest{Role: models.ProjectEditor}); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 demoting the last owner, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "DELETE", ownerPath, owner, nil); resp.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 for the last owner leaving, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "DELETE", ownerPath, member, nil); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for an editor removing the owner, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "PUT", membersPath+"/999", owner, controllers.MemberRoleRequest{Role: models.ProjectViewer}); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown member, got %d", resp.StatusCode)
+	}
+
+	// Members may leave on their own.
+	if resp := srv.Do(t, "DELETE", memberPath, member, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for a member leaving, got %d %s", resp.StatusCode, resp.Body)
+	}
+	if resp := srv.Do(t, "GET", membersPath, member, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 after leaving, got %d", resp.StatusCode)
+	}
+}
+
+func TestMoveTask(t *testing.T) {
+	srv := testutil.NewServer(t)
+	owner := srv.Register(t, "move_owner")
+	viewer := srv.Register(t, "move_viewer")
+	project := createProject(t, srv, owner, "Inbox")
+	other := createProject(t, srv, viewer, "Elsewhere")
+	addMember(t, srv, owner, project, "move_viewer", models.ProjectViewer)
+	task := createTask(t, srv, owner, "Personal first")
+	movePath := fmt.Sprintf("/tasks/%d/project", task.ID)
+
+	resp := srv.Do(t, "PUT", movePath, owner, controllers.MoveTaskRequest{ProjectID: &project.ID})
+	var moved models.Task
+	resp.Decode(t, &moved)
+	if resp.StatusCode != http.StatusOK || moved.ProjectID == nil || *moved.ProjectID != project.ID || moved.Version != task.Version+1 {
+		t.Fatalf("Expected the task in the project with a new version, got %d %+v", resp.StatusCode, moved)
+	}
+	if resp := srv.Do(t, "GET", fmt.Sprintf("/tasks/%d", task.ID), viewer, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected members to see a moved task, got %d", resp.StatusCode)
+	}
+
+	// Moving needs editor rights on both ends.
+	if resp := srv.Do(t, "PUT", movePath, viewer, controllers.MoveTaskRequest{ProjectID: &other.ID}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a viewer moving a task away, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "PUT", movePath, owner, controllers.MoveTaskRequest{ProjectID: &other.ID}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 moving into a project of others, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "PUT", movePath, owner, controllers.MoveTaskRequest{}); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 moving the task out of the project, got %d %s", resp.StatusCode, resp.Body)
+	}
+
+	var page controllers.TaskListResponse
+	srv.Do(t, "GET", "/tasks?project_id=none", owner, nil).Decode(t, &page)
+	if len(page.Data) != 1 || page.Data[0].ID != task.ID || page.Data[0].ProjectID != nil {
+		t.Errorf("Expected the task among the personal tasks again, got %+v", page.Data)
+	}
+	if resp := srv.Do(t, "GET", fmt.Sprintf("/tasks/%d", task.ID), viewer, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a personal task to be hidden from former members, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "GET", "/tasks?project_id=x", owner, nil); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid project_id, got %d", resp.StatusCode)
+	}
+}
+
+func TestMoveTaskOutOfProject(t *testing.T) {
+	srv := testutil.NewServer(t)
+	owner := srv.Register(t, "takeout_owner")
+	editor := srv.Register(t, "takeout_editor")
+	member := srv.Register(t, "takeout_member")
+	project := createProject(t, srv, owner, "Shared")
+	addMember(t, srv, owner, project, "takeout_editor", models.ProjectEditor)
+	addMember(t, srv, owner, project, "takeout_member", models.ProjectEditor)
+	var task models.Task
+	srv.Do(t, "POST", fmt.Sprintf("/projects/%d/tasks", project.ID), member, controllers.TaskRequest{Title: "Not yours"}).Decode(t, &task)
+	movePath := fmt.Sprintf("/tasks/%d/project", task.ID)
+
+	// Editors cannot take the tasks of others out of the project.
+	if resp := srv.Do(t, "PUT", movePath, editor, controllers.MoveTaskRequest{}); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected 403 for an editor taking out another member's task, got %d", resp.StatusCode)
+	}
+
+	// A project owner can, and the task goes back to its owner.
+	resp := srv.Do(t, "PUT", movePath, owner, controllers.MoveTaskRequest{})
+	var moved models.Task
+	resp.Decode(t, &moved)
+	if resp.StatusCode != http.StatusOK || moved.ProjectID != nil || moved.UserID != userID(t, srv, member) {
+		t.Errorf("Expected the task to stay with its owner, got %d %+v", resp.StatusCode, moved)
+	}
+	for _, token := range []string{owner, editor} {
+		if resp := srv.Do(t, "GET", fmt.Sprintf("/tasks/%d", task.ID), token, nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected the personal task to be hidden from other members, got %d", resp.StatusCode)
+		}
+	}
+	if resp := srv.Do(t, "GET", fmt.Sprintf("/tasks/%d", task.ID), member, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the owner to see the task, got %d", resp.StatusCode)
+	}
+}
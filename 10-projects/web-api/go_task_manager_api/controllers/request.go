@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils/validators"
+)
+
+// maxBodyBytes limits the size of request bodies.
+const maxBodyBytes = 1 << 20
+
+// errTrailingData is returned for bodies with more than one JSON value.
+var errTrailingData = errors.New("body must contain a single JSON object")
+
+// decodeRequest decodes the JSON body of r into req, a pointer to one of the
+// request types, and checks its validate rules. On failure it writes the
+// problem and returns false.
+func decodeRequest(w http.ResponseWriter, r *http.Request, req interface{}) bool {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	if err := decodeJSON(r.Body, req); err != nil {
+		requestError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// decodeJSON is decodeRequest without the response. Fields that req does
+// not have are an error, so that clients learn about typos and read-only
+// fields instead of having them silently ignored.
+func decodeJSON(body io.Reader, req interface{}) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(req); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errTrailingData
+	}
+	return validators.Struct(req)
+}
+
+// requestError writes the problem for an error of decodeJSON, with field
+// errors where the field is known.
+func requestError(w http.ResponseWriter, r *http.Request, err error) {
+	var invalid validators.Errors
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &invalid):
+		fields := make([]utils.FieldError, len(invalid))
+		for i, fe := range invalid {
+			fields[i] = utils.FieldError{Field: fe.Field, Message: fe.Message}
+		}
+		utils.ValidationError(w, r, invalid.Error(), fields...)
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		msg := fmt.Sprintf("%s cannot be a JSON %s", typeErr.Field, typeErr.Value)
+		utils.ValidationError(w, r, msg, utils.FieldError{Field: typeErr.Field, Message: msg})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field, _ := strconv.Unquote(strings.TrimPrefix(err.Error(), "json: unknown field "))
+		msg := field + " is not a field of this request"
+		utils.ValidationError(w, r, msg, utils.FieldError{Field: field, Message: msg})
+	case errors.As(err, &tooLarge):
+		utils.Error(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Request body is larger than %d bytes", tooLarge.Limit))
+	default:
+		utils.Error(w, r, http.StatusBadRequest, "Invalid request body: "+strings.TrimPrefix(err.Error(), "json: "))
+	}
+}
@@ -0,0 +1,106 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/testutil"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+)
+
+// Request bodies are checked against the validate rules of their types
+// before any handler logic runs.
+func TestRequestValidation(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "validation_user")
+	var created models.Task
+	srv.Do(t, "POST", "/tasks", token, controllers.TaskRequest{Title: "Untouched"}).Decode(t, &created)
+	taskPath := fmt.Sprintf("/tasks/%d", created.ID)
+
+	tests := []struct {
+		name         string
+		method, path string
+		body         interface{}
+		field        string // expected in the field errors
+	}{
+		{"title required", "POST", "/tasks", map[string]interface{}{"title": " "}, "title"},
+		{"title too long", "POST", "/tasks", map[string]interface{}{"title": strings.Repeat("x", 201)}, "title"},
+		{"description too long", "POST", "/tasks", map[string]interface{}{"title": "x", "description": strings.Repeat("x", 5001)}, "description"},
+		{"status enum", "POST", "/tasks", map[string]interface{}{"title": "x", "status": "later"}, "status"},
+		{"priority enum", "PUT", taskPath, map[string]interface{}{"title": "x", "priority": "urgent"}, "priority"},
+		{"due date too early", "POST", "/tasks", map[string]interface{}{"title": "x", "due_date": "1999-01-01T00:00:00Z"}, "due_date"},
+		{"due date too late", "PUT", taskPath, map[string]interface{}{"title": "x", "due_date": "2100-06-01T00:00:00Z"}, "due_date"},
+		{"unknown field", "POST", "/tasks", map[string]interface{}{"title": "x", "owner": "bob"}, "owner"},
+		{"read-only field", "PUT", taskPath, map[string]interface{}{"title": "x", "id": 1}, "id"},
+		{"wrong type", "POST", "/tasks", map[string]interface{}{"title": 42}, "title"},
+		{"patch removes title", "PATCH", taskPath, map[string]interface{}{"title": nil}, "title"},
+		{"patch unknown field", "PATCH", taskPath, map[string]interface{}{"version": 7}, "version"},
+		{"username required", "POST", "/register", map[string]interface{}{"password": testutil.Password}, "username"},
+		{"username too long", "POST", "/register", map[string]interface{}{"username": strings.Repeat("u", 51), "password": testutil.Password}, "username"},
+		{"password too long", "POST", "/login", map[string]interface{}{"username": "validation_user", "password": strings.Repeat("p", 73)}, "password"},
+		{"refresh token required", "POST", "/token/refresh", map[string]interface{}{}, "refresh_token"},
+		{"role enum", "PUT", "/admin/users/1/role", map[string]interface{}{"role": "owner"}, "role"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := srv.Do(t, tt.method, tt.path, token, tt.body)
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d %s", resp.StatusCode, resp.Body)
+			}
+			var problem utils.Problem
+			resp.Decode(t, &problem)
+			for _, fe := range problem.Errors {
+				if fe.Field == tt.field {
+					return
+				}
+			}
+			t.Errorf("expected an error for %s, got %+v", tt.field, problem.Errors)
+		})
+	}
+
+	// Nothing was changed by the rejected requests.
+	var stored models.Task
+	srv.Do(t, "GET", taskPath, token, nil).Decode(t, &stored)
+	if stored.Title != "Untouched" || stored.Version != created.Version {
+		t.Errorf("Expected task unchanged, got %+v", stored)
+	}
+}
+
+func TestRequestBodyErrors(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "body_user")
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"not JSON", `title=x`, http.StatusBadRequest},
+		{"not an object", `["x"]`, http.StatusBadRequest},
+		{"two objects", `{"title":"a"}{"title":"b"}`, http.StatusBadRequest},
+		{"bad date", `{"title":"a","due_date":"tomorrow"}`, http.StatusBadRequest},
+		{"too large", `{"title":"a","description":"` + strings.Repeat("x", 1<<20) + `"}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", srv.URL+"/tasks", strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, resp.StatusCode)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != utils.ProblemContentType {
+				t.Errorf("expected a problem, got %q", ct)
+			}
+		})
+	}
+}
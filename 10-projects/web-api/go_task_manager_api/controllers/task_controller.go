@@ -1,114 +1,652 @@
 package controllers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/middleware"
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/services"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
 
 	"github.com/gorilla/mux"
 )
 
+type TaskController struct {
+	tasks *services.TaskService
+}
+
+func NewTaskController(tasks *services.TaskService) *TaskController {
+	return &TaskController{tasks: tasks}
+}
+
+// TaskRequest is the body of creating or replacing a task, and the document
+// a merge patch applies to. The other fields of a task, like id, are set by
+// the server and rejected here.
+type TaskRequest struct {
+	Title       string     `json:"title" validate:"required,max=200" example:"Write report"`
+	Description string     `json:"description" validate:"max=5000" example:"Q3 numbers"`
+	Completed   bool       `json:"completed"`
+	Status      string     `json:"status,omitempty" validate:"oneof=todo in_progress done" enums:"todo,in_progress,done" example:"todo"`
+	Priority    string     `json:"priority,omitempty" validate:"oneof=low medium high" enums:"low,medium,high" example:"medium"`
+	DueDate     *time.Time `json:"due_date,omitempty" validate:"after=2000-01-01,before=2100-01-01"`
+}
+
+func newTaskRequest(task models.Task) TaskRequest {
+	return TaskRequest{
+		Title:       task.Title,
+		Description: task.Description,
+		Completed:   task.Completed,
+		Status:      task.Status,
+		Priority:    task.Priority,
+		DueDate:     task.DueDate,
+	}
+}
+
+// apply copies the fields of req to task.
+func (req TaskRequest) apply(task *models.Task) {
+	task.Title = req.Title
+	task.Description = req.Description
+	task.Completed = req.Completed
+	task.Status = req.Status
+	task.Priority = req.Priority
+	task.DueDate = req.DueDate
+}
+
+// TaskListResponse is one page of tasks. NextCursor is empty on the last
+// page; the same URL is also sent in a Link header with rel="next".
+type TaskListResponse struct {
+	Data       []models.Task `json:"data"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
 // GetTasks godoc
-// @Summary      List all tasks
-// @Description  Get all tasks
+// @Summary      List tasks
+// @Description  Lists the tasks the logged-in user can see, their personal tasks and those of their projects, one page at a time. Pass next_cursor as cursor for the next page, keeping the other parameters.
 // @Tags         tasks
+// @Security     BearerAuth
 // @Produce      json
-// @Success      200  {array}  models.Task
+// @Param        limit           query     int     false  "Page size (1-100)"  default(20)
+// @Param        cursor          query     string  false  "Cursor from the previous page"
+// @Param        sort            query     string  false  "Sort field, prefix with - for descending"  Enums(id, -id, title, -title, created_at, -created_at, updated_at, -updated_at, due_date, -due_date)  default(id)
+// @Param        completed       query     bool    false  "Only completed or open tasks"
+// @Param        status          query     string  false  "Only tasks with this status"  Enums(todo, in_progress, done)
+// @Param        priority        query     string  false  "Only tasks with this priority"  Enums(low, medium, high)
+// @Param        project_id      query     string  false  "Only tasks of this project, or none for personal tasks"
+// @Param        overdue         query     bool    false  "Only tasks past their due date that are not done"
+// @Param        q               query     string  false  "Text search in title and description"
+// @Param        created_after   query     string  false  "Created at or after (RFC 3339)"
+// @Param        created_before  query     string  false  "Created before (RFC 3339)"
+// @Param        updated_after   query     string  false  "Updated at or after (RFC 3339)"
+// @Param        updated_before  query     string  false  "Updated before (RFC 3339)"
+// @Param        due_after       query     string  false  "Due at or after (RFC 3339)"
+// @Param        due_before      query     string  false  "Due before (RFC 3339)"
+// @Success      200  {object}  TaskListResponse
+// @Header       200  {string}  Link  "URL of the next page, rel=\"next\""
+// @Failure      400  {object}  utils.Problem  "Invalid query"
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
 // @Router       /tasks [get]
-func GetTasks(w http.ResponseWriter, r *http.Request) {
-	var tasks []models.Task
-	database.DB.Find(&tasks)
-	json.NewEncoder(w).Encode(tasks)
+func (c *TaskController) GetTasks(w http.ResponseWriter, r *http.Request) {
+	c.listTasks(w, r, c.tasks.ListTasks)
+}
+
+// GetOverdueTasks godoc
+// @Summary      List overdue tasks
+// @Description  Lists the tasks the logged-in user can see that are past their due date and not done, earliest due first. Takes the same parameters as GET /tasks.
+// @Tags         tasks
+// @Security     BearerAuth
+// @Produce      json
+// @Param        limit   query     int     false  "Page size (1-100)"  default(20)
+// @Param        cursor  query     string  false  "Cursor from the previous page"
+// @Param        sort    query     string  false  "Sort field, prefix with - for descending"  default(due_date)
+// @Success      200  {object}  TaskListResponse
+// @Header       200  {string}  Link  "URL of the next page, rel=\"next\""
+// @Failure      400  {object}  utils.Problem  "Invalid query"
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Router       /tasks/overdue [get]
+func (c *TaskController) GetOverdueTasks(w http.ResponseWriter, r *http.Request) {
+	c.listTasks(w, r, c.tasks.ListOverdueTasks)
+}
+
+// listTasks writes the page of tasks that list returns for the query of r.
+func (c *TaskController) listTasks(w http.ResponseWriter, r *http.Request, list func(uint, services.TaskListOptions) (services.TaskList, error)) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	opts, err := taskListOptions(r.URL.Query())
+	if err != nil {
+		invalidInput(w, r, err)
+		return
+	}
+	page, err := list(userID, opts)
+	if errors.Is(err, services.ErrInvalidInput) {
+		invalidInput(w, r, err)
+		return
+	} else if err != nil {
+		serverError(w, r, err, "Failed to load tasks")
+		return
+	}
+
+	setNextLink(w, r, page.NextCursor)
+	utils.JSON(w, http.StatusOK, TaskListResponse{Data: page.Tasks, NextCursor: page.NextCursor})
+}
+
+// setNextLink sends the URL of the page after the one r asked for in a Link
+// header, unless nextCursor is empty.
+func setNextLink(w http.ResponseWriter, r *http.Request, nextCursor string) {
+	if nextCursor == "" {
+		return
+	}
+	next := *r.URL
+	query := next.Query()
+	query.Set("cursor", nextCursor)
+	next.RawQuery = query.Encode()
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next.RequestURI()))
+}
+
+// taskListOptions reads the paging, sorting and filter parameters of
+// GET /tasks.
+func taskListOptions(query url.Values) (services.TaskListOptions, error) {
+	opts := services.TaskListOptions{
+		Sort:   query.Get("sort"),
+		Cursor: query.Get("cursor"),
+	}
+	opts.Filter.Search = strings.TrimSpace(query.Get("q"))
+	opts.Filter.Status = query.Get("status")
+	opts.Filter.Priority = query.Get("priority")
+
+	if v := query.Get("project_id"); v == "none" {
+		opts.Filter.ProjectID = new(uint)
+	} else if v != "" {
+		projectID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || projectID == 0 {
+			return opts, &services.FieldError{Field: "project_id", Message: fmt.Sprintf("invalid project_id %q, expected a project ID or none", v)}
+		}
+		id := uint(projectID)
+		opts.Filter.ProjectID = &id
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return opts, &services.FieldError{Field: "limit", Message: fmt.Sprintf("invalid limit %q", v)}
+		}
+		opts.Limit = limit
+	}
+	if v := query.Get("completed"); v != "" {
+		completed, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, &services.FieldError{Field: "completed", Message: fmt.Sprintf("invalid completed %q, expected true or false", v)}
+		}
+		opts.Filter.Completed = &completed
+	}
+	if v := query.Get("overdue"); v != "" {
+		overdue, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, &services.FieldError{Field: "overdue", Message: fmt.Sprintf("invalid overdue %q, expected true or false", v)}
+		}
+		if overdue {
+			now := time.Now()
+			opts.Filter.OverdueAt = &now
+		}
+	}
+
+	times := []struct {
+		param string
+		dst   **time.Time
+	}{
+		{"created_after", &opts.Filter.CreatedAfter},
+		{"created_before", &opts.Filter.CreatedBefore},
+		{"updated_after", &opts.Filter.UpdatedAfter},
+		{"updated_before", &opts.Filter.UpdatedBefore},
+		{"due_after", &opts.Filter.DueAfter},
+		{"due_before", &opts.Filter.DueBefore},
+	}
+	for _, tt := range times {
+		v := query.Get(tt.param)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return opts, &services.FieldError{Field: tt.param,
+				Message: fmt.Sprintf("invalid %s %q, expected RFC 3339 like 2024-01-31T15:04:05Z", tt.param, v)}
+		}
+		*tt.dst = &t
+	}
+	return opts, nil
 }
 
 // GetTask godoc
 // @Summary      Get a task by ID
-// @Description  Get a task by its ID
+// @Description  Get a task the logged-in user can see by its ID
 // @Tags         tasks
+// @Security     BearerAuth
 // @Produce      json
 // @Param        id   path      int  true  "Task ID"
+// @Param        If-None-Match  header  string  false  "ETag of a cached copy"
 // @Success      200  {object}  models.Task
-// @Failure      404  {string}  string  "Task not found"
+// @Header       200  {string}  ETag  "Version of the task"
+// @Success      304  "Not modified"
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      404  {object}  utils.Problem  "Task not found"
 // @Router       /tasks/{id} [get]
-func GetTask(w http.ResponseWriter, r *http.Request) {
+func (c *TaskController) GetTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
 	params := mux.Vars(r)
 	id, _ := strconv.Atoi(params["id"])
 
-	var task models.Task
-	result := database.DB.First(&task, id)
-	if result.Error != nil {
-		http.Error(w, "Task not found", http.StatusNotFound)
+	task, err := c.tasks.GetTask(uint(id), userID)
+	if err != nil {
+		taskError(w, r, err)
 		return
 	}
-	json.NewEncoder(w).Encode(task)
+	setETag(w, task)
+	if noneMatch(r.Header.Get("If-None-Match"), w.Header().Get("ETag")) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	utils.JSON(w, http.StatusOK, task)
 }
 
 // CreateTask godoc
 // @Summary      Create a new task
-// @Description  Create a new task
+// @Description  Create a new task owned by the logged-in user
 // @Tags         tasks
+// @Security     BearerAuth
 // @Accept       json
 // @Produce      json
-// @Param        task  body      models.Task  true  "Task Body"
+// @Param        task  body      TaskRequest  true  "Task Body"
 // @Success      201   {object}  models.Task
+// @Header       201   {string}  ETag  "Version of the task"
+// @Failure      400   {object}  utils.Problem  "Invalid task"
+// @Failure      401   {object}  utils.Problem  "Unauthorized"
 // @Router       /tasks [post]
-func CreateTask(w http.ResponseWriter, r *http.Request) {
+func (c *TaskController) CreateTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req TaskRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
 	var task models.Task
-	json.NewDecoder(r.Body).Decode(&task)
-	database.DB.Create(&task)
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(task)
+	req.apply(&task)
+	if err := c.tasks.CreateTask(userID, &task); errors.Is(err, services.ErrInvalidInput) {
+		invalidInput(w, r, err)
+		return
+	} else if err != nil {
+		serverError(w, r, err, "Failed to create task")
+		return
+	}
+	setETag(w, task)
+	utils.JSON(w, http.StatusCreated, task)
 
 }
 
+// CreateProjectTask godoc
+// @Summary      Create a task in a project
+// @Description  Create a new task in a project, shared with its members. Needs the editor or owner role.
+// @Tags         projects
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id    path      int          true  "Project ID"
+// @Param        task  body      TaskRequest  true  "Task Body"
+// @Success      201   {object}  models.Task
+// @Header       201   {string}  ETag  "Version of the task"
+// @Failure      400   {object}  utils.Problem  "Invalid task"
+// @Failure      401   {object}  utils.Problem  "Unauthorized"
+// @Failure      403   {object}  utils.Problem  "Viewer of the project"
+// @Failure      404   {object}  utils.Problem  "Project not found"
+// @Router       /projects/{id}/tasks [post]
+func (c *TaskController) CreateProjectTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	projectID := uint(id)
+
+	var req TaskRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	task := models.Task{ProjectID: &projectID}
+	req.apply(&task)
+	if err := c.tasks.CreateTask(userID, &task); err != nil {
+		projectError(w, r, err)
+		return
+	}
+	setETag(w, task)
+	utils.JSON(w, http.StatusCreated, task)
+}
+
 // UpdateTask godoc
-// @Summary      Update a task
-// @Description  Update a task by its ID
+// @Summary      Replace a task
+// @Description  Replace a task by its ID. Viewers of a project cannot change its tasks. Fields left out get their defaults, as on create; use PATCH to change single fields. With If-Match, the task is only replaced if it is still at that version.
 // @Tags         tasks
+// @Security     BearerAuth
 // @Accept       json
 // @Produce      json
-// @Param        id    path      int         true  "Task ID"
-// @Param        task  body      models.Task true  "Task Body"
+// @Param        id        path      int         true   "Task ID"
+// @Param        If-Match  header    string      false  "ETag the change is based on"
+// @Param        task      body      TaskRequest true   "Task Body"
 // @Success      200   {object}  models.Task
-// @Failure      404   {string}  string  "Task not found"
+// @Header       200   {string}  ETag  "New version of the task"
+// @Failure      400   {object}  utils.Problem  "Invalid task"
+// @Failure      401   {object}  utils.Problem  "Unauthorized"
+// @Failure      403   {object}  utils.Problem  "Viewer of the task's project"
+// @Failure      404   {object}  utils.Problem  "Task not found"
+// @Failure      409   {object}  utils.Problem  "Task was modified concurrently"
+// @Failure      412   {object}  utils.Problem  "Task was modified since If-Match"
 // @Router       /tasks/{id} [put]
-func UpdateTask(w http.ResponseWriter, r *http.Request) {
+func (c *TaskController) UpdateTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
 	params := mux.Vars(r)
 	id, _ := strconv.Atoi(params["id"])
+	version, ok := ifMatch(r)
+	if !ok {
+		utils.Error(w, r, http.StatusPreconditionFailed, "Invalid If-Match header")
+		return
+	}
 
+	var req TaskRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
 	var task models.Task
-	result := database.DB.First(&task, id)
-	if result.Error != nil {
-		http.Error(w, "Task not found", http.StatusNotFound)
+	req.apply(&task)
+	if err := c.tasks.UpdateTask(uint(id), userID, &task, version); err != nil {
+		updateError(w, r, err, version)
+		return
+	}
+	setETag(w, task)
+	utils.JSON(w, http.StatusOK, task)
+}
+
+// PatchTask godoc
+// @Summary      Change a task
+// @Description  Change fields of a task with a JSON Merge Patch (RFC 7396): fields in the body replace the stored ones, null clears them, the rest is kept. With If-Match, the task is only changed if it is still at that version.
+// @Tags         tasks
+// @Security     BearerAuth
+// @Accept       application/merge-patch+json
+// @Produce      json
+// @Param        id        path      int         true   "Task ID"
+// @Param        If-Match  header    string      false  "ETag the change is based on"
+// @Param        patch     body      TaskRequest true   "Fields to change"
+// @Success      200   {object}  models.Task
+// @Header       200   {string}  ETag  "New version of the task"
+// @Failure      400   {object}  utils.Problem  "Invalid patch or task"
+// @Failure      401   {object}  utils.Problem  "Unauthorized"
+// @Failure      403   {object}  utils.Problem  "Viewer of the task's project"
+// @Failure      404   {object}  utils.Problem  "Task not found"
+// @Failure      409   {object}  utils.Problem  "Task was modified concurrently"
+// @Failure      412   {object}  utils.Problem  "Task was modified since If-Match"
+// @Failure      415   {object}  utils.Problem  "Unsupported content type"
+// @Router       /tasks/{id} [patch]
+func (c *TaskController) PatchTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	params := mux.Vars(r)
+	id, _ := strconv.Atoi(params["id"])
+	if !isMergePatch(r.Header.Get("Content-Type")) {
+		utils.Error(w, r, http.StatusUnsupportedMediaType, "Content-Type must be application/merge-patch+json")
+		return
+	}
+	version, ok := ifMatch(r)
+	if !ok {
+		utils.Error(w, r, http.StatusPreconditionFailed, "Invalid If-Match header")
+		return
+	}
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		requestError(w, r, err)
+		return
+	}
+
+	// The patch applies to the task as a TaskRequest, so the result is
+	// checked like the body of a PUT.
+	var invalid error
+	task, err := c.tasks.PatchTask(uint(id), userID, version, func(task *models.Task) error {
+		doc, err := json.Marshal(newTaskRequest(*task))
+		if err != nil {
+			return err
+		}
+		merged, err := utils.MergePatch(doc, patch)
+		if err != nil {
+			return err
+		}
+		var req TaskRequest
+		if invalid = decodeJSON(bytes.NewReader(merged), &req); invalid != nil {
+			return invalid
+		}
+		req.apply(task)
+		return nil
+	})
+	switch {
+	case errors.Is(err, utils.ErrInvalidPatch):
+		utils.Error(w, r, http.StatusBadRequest, "Invalid merge patch: body must be JSON")
+		return
+	case invalid != nil:
+		requestError(w, r, invalid)
+		return
+	case err != nil:
+		updateError(w, r, err, version)
+		return
+	}
+	setETag(w, task)
+	utils.JSON(w, http.StatusOK, task)
+}
+
+// MoveTaskRequest is the body of moving a task. A null project_id moves the
+// task out of its project.
+type MoveTaskRequest struct {
+	ProjectID *uint `json:"project_id" example:"3"`
+}
+
+// MoveTask godoc
+// @Summary      Move a task between projects
+// @Description  Move a task into a project, or with a null project_id out of its project into the personal tasks of its owner. Needs the editor or owner role in both projects; only the task's owner and the project's owners may take a task out. With If-Match, the task is only moved if it is still at that version.
+// @Tags         tasks
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int              true   "Task ID"
+// @Param        If-Match  header    string           false  "ETag the change is based on"
+// @Param        move      body      MoveTaskRequest  true   "Target project"
+// @Success      200   {object}  models.Task
+// @Header       200   {string}  ETag  "New version of the task"
+// @Failure      400   {object}  utils.Problem  "Invalid body or unknown project"
+// @Failure      401   {object}  utils.Problem  "Unauthorized"
+// @Failure      403   {object}  utils.Problem  "Viewer of either project, or an editor taking out another user's task"
+// @Failure      404   {object}  utils.Problem  "Task not found"
+// @Failure      409   {object}  utils.Problem  "Task was modified concurrently"
+// @Failure      412   {object}  utils.Problem  "Task was modified since If-Match"
+// @Router       /tasks/{id}/project [put]
+func (c *TaskController) MoveTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	id, _ := strconv.Atoi(mux.Vars(r)["id"])
+	version, ok := ifMatch(r)
+	if !ok {
+		utils.Error(w, r, http.StatusPreconditionFailed, "Invalid If-Match header")
 		return
 	}
 
-	json.NewDecoder(r.Body).Decode(&task)
-	database.DB.Save(&task)
-	json.NewEncoder(w).Encode(task)
+	var req MoveTaskRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	task, err := c.tasks.MoveTask(uint(id), userID, req.ProjectID, version)
+	if err != nil {
+		updateError(w, r, err, version)
+		return
+	}
+	setETag(w, task)
+	utils.JSON(w, http.StatusOK, task)
 }
 
 // DeleteTask godoc
 // @Summary      Delete a task
-// @Description  Delete a task by its ID
+// @Description  Delete a task by its ID. Viewers of a project cannot delete its tasks.
 // @Tags         tasks
+// @Security     BearerAuth
 // @Produce      json
 // @Param        id   path      int  true  "Task ID"
 // @Success      200  {object}  map[string]string
-// @Failure      404  {string}  string  "Task not found"
+// @Failure      401  {object}  utils.Problem  "Unauthorized"
+// @Failure      403  {object}  utils.Problem  "Viewer of the task's project"
+// @Failure      404  {object}  utils.Problem  "Task not found"
 // @Router       /tasks/{id} [delete]
-func DeleteTask(w http.ResponseWriter, r *http.Request) {
+func (c *TaskController) DeleteTask(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.UserID(r)
+	if !ok {
+		utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
 	params := mux.Vars(r)
 	id, _ := strconv.Atoi(params["id"])
 
-	var task models.Task
-	result := database.DB.Delete(&task, id)
-	if result.RowsAffected == 0 {
-		http.Error(w, "Task not found", http.StatusNotFound)
+	if err := c.tasks.DeleteTask(uint(id), userID); err != nil {
+		taskError(w, r, err)
+		return
+	}
+	utils.JSON(w, http.StatusOK, map[string]string{"message": "Task deleted"})
+}
+
+// taskError writes 404 for missing tasks, 400 for invalid ones, 403 for
+// changes the user may not make and 500 for anything else.
+func taskError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, repositories.ErrNotFound) {
+		utils.Error(w, r, http.StatusNotFound, "Task not found")
+		return
+	}
+	if errors.Is(err, services.ErrInvalidInput) {
+		invalidInput(w, r, err)
 		return
 	}
-	json.NewEncoder(w).Encode(map[string]string{"message": "Task deleted"})
+	if errors.Is(err, services.ErrForbidden) {
+		utils.Error(w, r, http.StatusForbidden, err.Error())
+		return
+	}
+	serverError(w, r, err, "Database error")
+}
+
+// updateError is taskError for updates: a version conflict is 412 when the
+// client asked for a version with If-Match, and 409 when another update
+// just won the race.
+func updateError(w http.ResponseWriter, r *http.Request, err error, version uint) {
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		if version != 0 {
+			utils.Error(w, r, http.StatusPreconditionFailed, "Task was modified, fetch it again")
+		} else {
+			utils.Error(w, r, http.StatusConflict, "Task was modified concurrently, retry")
+		}
+		return
+	}
+	taskError(w, r, err)
+}
+
+// setETag sends the version of task as a strong ETag.
+func setETag(w http.ResponseWriter, task models.Task) {
+	w.Header().Set("ETag", strconv.Quote(strconv.FormatUint(uint64(task.Version), 10)))
+}
+
+// ifMatch returns the task version of the If-Match header, 0 if there is
+// none or it is "*", and false if it is not a strong ETag of this API.
+// If-Match uses the strong comparison (RFC 9110, section 13.1.1), so a
+// weak ETag never matches.
+func ifMatch(r *http.Request) (uint, bool) {
+	match := strings.TrimSpace(r.Header.Get("If-Match"))
+	if match == "" || match == "*" {
+		return 0, true
+	}
+	unquoted, err := strconv.Unquote(match)
+	if err != nil {
+		return 0, false
+	}
+	version, err := strconv.ParseUint(unquoted, 10, 32)
+	if err != nil || version == 0 {
+		return 0, false
+	}
+	return uint(version), true
+}
+
+// noneMatch reports whether an If-None-Match header matches etag. It uses
+// the weak comparison (RFC 9110, section 13.1.2): the header is a list of
+// entity tags, W/ is ignored and "*" matches any existing task.
+func noneMatch(header, etag string) bool {
+	for _, tag := range entityTags(header) {
+		if tag == "*" || tag == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// entityTags splits an If-None-Match header into its entity tags, quoted
+// and without the W/ prefix. A malformed rest of the header is ignored.
+func entityTags(header string) []string {
+	var tags []string
+	rest := header
+	for {
+		rest = strings.TrimLeft(rest, " \t,")
+		if rest == "" {
+			return tags
+		}
+		if rest[0] == '*' {
+			tags = append(tags, "*")
+			rest = rest[1:]
+			continue
+		}
+		rest = strings.TrimPrefix(rest, "W/")
+		if rest == "" || rest[0] != '"' {
+			return tags
+		}
+		end := strings.IndexByte(rest[1:], '"')
+		if end < 0 {
+			return tags
+		}
+		tags = append(tags, rest[:end+2])
+		rest = rest[end+2:]
+	}
+}
+
+// isMergePatch reports whether a PATCH body of contentType is a merge patch.
+// Plain JSON is accepted too, as its meaning is the same for tasks.
+func isMergePatch(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/merge-patch+json" || mediaType == "application/json")
 }
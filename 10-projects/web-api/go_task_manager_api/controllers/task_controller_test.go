@@ -1,61 +1,468 @@
-package controllers
+package controllers_test
 
 import (
-	"bytes"
-	"encoding/json"
+	"fmt"
 	"net/http"
-	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
-
-	"github.com/gorilla/mux"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/testutil"
 )
 
-func init() {
-	database.Connect()
-	models.InitTaskModel()
+// userID returns the ID of the user behind token.
+func userID(t *testing.T, srv *testutil.Server, token string) uint {
+	t.Helper()
+	var profile map[string]interface{}
+	srv.Do(t, "GET", "/me", token, nil).Decode(t, &profile)
+	id, _ := profile["userID"].(float64)
+	return uint(id)
 }
 
 func TestCreateTask(t *testing.T) {
-	payload := models.Task{
-		Title:       "Test Task",
-		Description: "Testing task creation",
-		Completed:   false,
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "task_create_user")
+	owner := userID(t, srv, token)
+
+	resp := srv.Do(t, "POST", "/tasks", token, controllers.TaskRequest{Title: "Test Task", Description: "Testing task creation"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %v, got %v", http.StatusCreated, resp.StatusCode)
+	}
+	var created models.Task
+	resp.Decode(t, &created)
+	if created.UserID != owner {
+		t.Errorf("Expected task owned by %d, got %d", owner, created.UserID)
 	}
-	body, _ := json.Marshal(payload)
 
-	req, err := http.NewRequest("POST", "/tasks", bytes.NewBuffer(body))
-	if err != nil {
-		t.Fatal(err)
+	// Fields set by the server are rejected, not silently ignored.
+	for _, field := range []string{"id", "user_id", "version", "created_at"} {
+		body := map[string]interface{}{"title": "Sneaky", field: 1}
+		if resp := srv.Do(t, "POST", "/tasks", token, body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected 400 for a task with %s, got %d", field, resp.StatusCode)
+		}
 	}
-	req.Header.Set("Content-Type", "application/json")
+}
 
-	rr := httptest.NewRecorder()
+func TestGetTasks(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "task_list_user")
+	srv.Do(t, "POST", "/tasks", token, controllers.TaskRequest{Title: "First"})
+	srv.Do(t, "POST", "/tasks", token, controllers.TaskRequest{Title: "Second"})
 
-	router := mux.NewRouter()
-	router.HandleFunc("/tasks", CreateTask).Methods("POST")
-	router.ServeHTTP(rr, req)
+	resp := srv.Do(t, "GET", "/tasks", token, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %v, got %v", http.StatusOK, resp.StatusCode)
+	}
+	var page controllers.TaskListResponse
+	resp.Decode(t, &page)
+	if len(page.Data) != 2 || page.NextCursor != "" {
+		t.Errorf("Expected 2 tasks on one page, got %+v", page)
+	}
+}
 
-	if status := rr.Code; status != http.StatusCreated {
-		t.Errorf("Expected status code %v, got %v", http.StatusCreated, status)
+func TestTasksRequireToken(t *testing.T) {
+	srv := testutil.NewServer(t)
+	resp := srv.Do(t, "GET", "/tasks", "", nil)
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected status code %v, got %v", http.StatusUnauthorized, resp.StatusCode)
 	}
 }
 
-func TestGetTasks(t *testing.T) {
-	req, err := http.NewRequest("GET", "/tasks", nil)
-	if err != nil {
-		t.Fatal(err)
+func TestTaskOwnership(t *testing.T) {
+	srv := testutil.NewServer(t)
+	aliceToken := srv.Register(t, "task_owner_alice")
+	bobToken := srv.Register(t, "task_owner_bob")
+	alice := userID(t, srv, aliceToken)
+
+	resp := srv.Do(t, "POST", "/tasks", aliceToken, controllers.TaskRequest{Title: "Alice's task"})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status code %v, got %v", http.StatusCreated, resp.StatusCode)
+	}
+	var task models.Task
+	resp.Decode(t, &task)
+	path := fmt.Sprintf("/tasks/%d", task.ID)
+
+	// Bob cannot list, read, update or delete Alice's task.
+	var bobTasks controllers.TaskListResponse
+	srv.Do(t, "GET", "/tasks", bobToken, nil).Decode(t, &bobTasks)
+	for _, bt := range bobTasks.Data {
+		if bt.ID == task.ID {
+			t.Errorf("Expected Bob not to see task %d", task.ID)
+		}
+	}
+	if resp = srv.Do(t, "GET", path, bobToken, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 reading another user's task, got %v", resp.StatusCode)
+	}
+	if resp = srv.Do(t, "PUT", path, bobToken, controllers.TaskRequest{Title: "Hijacked"}); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 updating another user's task, got %v", resp.StatusCode)
+	}
+	if resp = srv.Do(t, "DELETE", path, bobToken, nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting another user's task, got %v", resp.StatusCode)
+	}
+
+	// Alice's task is untouched and still hers.
+	var stored models.Task
+	if err := srv.DB.First(&stored, task.ID).Error; err != nil {
+		t.Fatalf("Expected task to still exist: %v", err)
+	}
+	if stored.Title != "Alice's task" || stored.UserID != alice {
+		t.Errorf("Expected task unchanged, got %+v", stored)
+	}
+
+	// Alice cannot hand the task to someone else through the body.
+	resp = srv.Do(t, "PUT", path, aliceToken, map[string]interface{}{"title": "Renamed", "user_id": alice + 1000})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected status code %v, got %v", http.StatusBadRequest, resp.StatusCode)
+	}
+	srv.DB.First(&stored, task.ID)
+	if stored.Title != "Alice's task" || stored.UserID != alice {
+		t.Errorf("Expected task unchanged, got %+v", stored)
+	}
+
+	if resp = srv.Do(t, "DELETE", path, aliceToken, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected owner to delete the task, got %v", resp.StatusCode)
+	}
+}
+
+// listTitles fetches path and returns the titles of the page and its
+// next cursor.
+func listTitles(t *testing.T, srv *testutil.Server, token, path string) ([]string, string) {
+	t.Helper()
+	resp := srv.Do(t, "GET", path, token, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: expected status 200, got %d %s", path, resp.StatusCode, resp.Body)
+	}
+	var page controllers.TaskListResponse
+	resp.Decode(t, &page)
+	var titles []string
+	for _, task := range page.Data {
+		titles = append(titles, task.Title)
+	}
+	return titles, page.NextCursor
+}
+
+func TestGetTasksPagination(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "page_user")
+	for _, title := range []string{"c", "a", "e", "b", "d"} {
+		srv.Do(t, "POST", "/tasks", token, controllers.TaskRequest{Title: title})
+	}
+
+	// Walk all pages, two at a time.
+	tests := []struct {
+		sort string
+		want string
+	}{
+		{"-title", "edcba"},
+		{"title", "abcde"},
+		{"created_at", "caebd"},
+		{"-updated_at", "dbeac"},
+		{"-id", "dbeac"},
+	}
+	for _, tt := range tests {
+		var all []string
+		path := "/tasks?limit=2&sort=" + tt.sort
+		for pages := 0; ; pages++ {
+			if pages > 5 {
+				t.Fatalf("sort=%s: expected pagination to end", tt.sort)
+			}
+			titles, next := listTitles(t, srv, token, path)
+			all = append(all, titles...)
+			if next == "" {
+				break
+			}
+			path = "/tasks?limit=2&sort=" + tt.sort + "&cursor=" + url.QueryEscape(next)
+		}
+		if got := strings.Join(all, ""); got != tt.want {
+			t.Errorf("sort=%s: expected %s, got %s", tt.sort, tt.want, got)
+		}
+	}
+
+	resp := srv.Do(t, "GET", "/tasks?limit=2", token, nil)
+	link := resp.Header.Get("Link")
+	if !strings.HasPrefix(link, "</tasks?") || !strings.Contains(link, "cursor=") || !strings.HasSuffix(link, `>; rel="next"`) {
+		t.Errorf("Expected a Link header to the next page, got %q", link)
+	}
+}
+
+func TestGetTasksFilters(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "filter_user")
+	before := time.Now().Add(-time.Second).UTC().Format(time.RFC3339)
+	srv.Do(t, "POST", "/tasks", token, controllers.TaskRequest{Title: "Buy milk", Completed: true})
+	srv.Do(t, "POST", "/tasks", token, controllers.TaskRequest{Title: "Write report", Description: "100% done by Friday"})
+	srv.Do(t, "POST", "/tasks", token, controllers.TaskRequest{Title: "Call mom"})
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"completed=true", "Buy milk"},
+		{"completed=false&sort=title", "Call mom,Write report"},
+		{"q=MILK", "Buy milk"},
+		{"q=100%25", "Write report"},
+		{"q=50%25", ""},
+		{"created_after=" + before + "&sort=-id", "Call mom,Write report,Buy milk"},
+		{"created_before=" + before, ""},
+		{"updated_before=2999-01-01T00:00:00Z&completed=true", "Buy milk"},
+	}
+	for _, tt := range tests {
+		titles, _ := listTitles(t, srv, token, "/tasks?"+tt.query)
+		if got := strings.Join(titles, ","); got != tt.want {
+			t.Errorf("GET /tasks?%s = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestGetTasksInvalidQuery(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "invalid_query_user")
+	for i := 0; i < 3; i++ {
+		srv.Do(t, "POST", "/tasks", token, controllers.TaskRequest{Title: "task"})
+	}
+	var page controllers.TaskListResponse
+	srv.Do(t, "GET", "/tasks?limit=1&sort=title", token, nil).Decode(t, &page)
+
+	for _, query := range []string{
+		"sort=password",
+		"limit=0",
+		"limit=1000",
+		"completed=maybe",
+		"created_after=yesterday",
+		"cursor=garbage",
+		"sort=id&cursor=" + url.QueryEscape(page.NextCursor), // cursor of another sort
+	} {
+		if resp := srv.Do(t, "GET", "/tasks?"+query, token, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET /tasks?%s: expected status 400, got %d", query, resp.StatusCode)
+		}
 	}
+}
+
+func TestTaskFields(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "fields_user")
+	due := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	resp := srv.Do(t, "POST", "/tasks", token, map[string]interface{}{
+		"title": "Plan trip", "priority": "high", "status": "in_progress", "due_date": due,
+	})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d %s", resp.StatusCode, resp.Body)
+	}
+	var task models.Task
+	resp.Decode(t, &task)
+	if task.Priority != models.PriorityHigh || task.Status != models.StatusInProgress || task.Completed {
+		t.Errorf("Unexpected task: %+v", task)
+	}
+	if task.DueDate == nil || !task.DueDate.Equal(due) || task.CreatedAt.IsZero() || task.UpdatedAt.IsZero() {
+		t.Errorf("Expected due date and timestamps, got %+v", task)
+	}
+	path := fmt.Sprintf("/tasks/%d", task.ID)
 
-	rr := httptest.NewRecorder()
+	// Defaults for a bare task.
+	var bare models.Task
+	srv.Do(t, "POST", "/tasks", token, map[string]string{"title": "Bare"}).Decode(t, &bare)
+	if bare.Status != models.StatusTodo || bare.Priority != models.PriorityMedium || bare.DueDate != nil {
+		t.Errorf("Expected todo/medium without due date, got %+v", bare)
+	}
+
+	// Completing moves the status to done, and reopening back to todo.
+	srv.Do(t, "PATCH", path, token, map[string]interface{}{"completed": true}).Decode(t, &task)
+	if task.Status != models.StatusDone || !task.Completed {
+		t.Errorf("Expected done after completing, got %+v", task)
+	}
+	srv.Do(t, "PATCH", path, token, map[string]interface{}{"completed": false}).Decode(t, &task)
+	if task.Status != models.StatusTodo || task.Completed {
+		t.Errorf("Expected todo after reopening, got %+v", task)
+	}
+	srv.Do(t, "PATCH", path, token, map[string]interface{}{"status": "done"}).Decode(t, &task)
+	if !task.Completed {
+		t.Errorf("Expected completed after status done, got %+v", task)
+	}
+	if task.Title != "Plan trip" || task.Priority != models.PriorityHigh {
+		t.Errorf("Expected other fields kept, got %+v", task)
+	}
 
-	router := mux.NewRouter()
-	router.HandleFunc("/tasks", GetTasks).Methods("GET")
-	router.ServeHTTP(rr, req)
+	for _, body := range []map[string]interface{}{{"status": "later"}, {"priority": "urgent"}} {
+		if resp := srv.Do(t, "POST", "/tasks", token, body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("POST %v: expected status 400, got %d", body, resp.StatusCode)
+		}
+		if resp := srv.Do(t, "PATCH", path, token, body); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("PATCH %v: expected status 400, got %d", body, resp.StatusCode)
+		}
+	}
+}
+
+func TestUpdateTaskVersions(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "versions_user")
+	due := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	resp := srv.Do(t, "POST", "/tasks", token, map[string]interface{}{
+		"title": "Write report", "description": "Q3", "priority": "high", "due_date": due,
+	})
+	var task models.Task
+	resp.Decode(t, &task)
+	if task.Version != 1 || resp.Header.Get("ETag") != `"1"` {
+		t.Fatalf("Expected version 1 and its ETag, got %d and %q", task.Version, resp.Header.Get("ETag"))
+	}
+	path := fmt.Sprintf("/tasks/%d", task.ID)
+
+	resp = srv.DoWithHeaders(t, "GET", path, token, nil, map[string]string{"If-None-Match": `"1"`})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("Expected 304 for a current If-None-Match, got %d", resp.StatusCode)
+	}
+	for _, header := range []string{`W/"1"`, `"7", "1"`, `"a,b", W/"1"`, `*`} {
+		resp = srv.DoWithHeaders(t, "GET", path, token, nil, map[string]string{"If-None-Match": header})
+		if resp.StatusCode != http.StatusNotModified {
+			t.Errorf("Expected 304 for If-None-Match %s, got %d", header, resp.StatusCode)
+		}
+	}
+	resp = srv.DoWithHeaders(t, "GET", path, token, nil, map[string]string{"If-None-Match": `"2", W/"3"`})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for a stale If-None-Match, got %d", resp.StatusCode)
+	}
+
+	// PATCH changes only the fields of the patch; null clears the due date.
+	patch := map[string]string{"Content-Type": "application/merge-patch+json", "If-Match": `"1"`}
+	resp = srv.DoWithHeaders(t, "PATCH", path, token, map[string]interface{}{"status": "in_progress", "due_date": nil}, patch)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d %s", resp.StatusCode, resp.Body)
+	}
+	var patched models.Task
+	resp.Decode(t, &patched)
+	if patched.Status != models.StatusInProgress || patched.DueDate != nil ||
+		patched.Title != "Write report" || patched.Description != "Q3" || patched.Priority != models.PriorityHigh {
+		t.Errorf("Unexpected patched task: %+v", patched)
+	}
+	if patched.Version != 2 || resp.Header.Get("ETag") != `"2"` {
+		t.Errorf("Expected version 2 and its ETag, got %d and %q", patched.Version, resp.Header.Get("ETag"))
+	}
+	if !patched.CreatedAt.Equal(task.CreatedAt) || patched.UpdatedAt.Before(task.UpdatedAt) {
+		t.Errorf("Expected created_at kept and updated_at moved, got %+v", patched)
+	}
+
+	// Updates based on the old version fail without changing anything.
+	resp = srv.DoWithHeaders(t, "PATCH", path, token, map[string]string{"title": "Stale"}, patch)
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale PATCH, got %d", resp.StatusCode)
+	}
+	resp = srv.DoWithHeaders(t, "PUT", path, token, map[string]string{"title": "Stale"}, map[string]string{"If-Match": `"1"`})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale PUT, got %d", resp.StatusCode)
+	}
+	resp = srv.DoWithHeaders(t, "PUT", path, token, map[string]string{"title": "Stale"}, map[string]string{"If-Match": "v1"})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for an invalid If-Match, got %d", resp.StatusCode)
+	}
+	resp = srv.DoWithHeaders(t, "PUT", path, token, map[string]string{"title": "Weak"}, map[string]string{"If-Match": `W/"2"`})
+	if resp.StatusCode != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a weak ETag in If-Match, got %d", resp.StatusCode)
+	}
+	var stored models.Task
+	srv.DB.First(&stored, task.ID)
+	if stored.Title != "Write report" || stored.Version != 2 {
+		t.Errorf("Expected task unchanged by stale or weak updates, got %+v", stored)
+	}
+
+	// PUT replaces the whole task: omitted fields get their defaults.
+	resp = srv.DoWithHeaders(t, "PUT", path, token, map[string]string{"title": "Rewritten"}, map[string]string{"If-Match": `"2"`})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d %s", resp.StatusCode, resp.Body)
+	}
+	var replaced models.Task
+	resp.Decode(t, &replaced)
+	if replaced.Title != "Rewritten" || replaced.Description != "" || replaced.Status != models.StatusTodo ||
+		replaced.Priority != models.PriorityMedium || replaced.Version != 3 {
+		t.Errorf("Expected a full replacement, got %+v", replaced)
+	}
+	if resp = srv.Do(t, "PUT", path, token, map[string]string{"description": "No title"}); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a PUT without title, got %d", resp.StatusCode)
+	}
+
+	for _, tt := range []struct {
+		contentType, body string
+		want              int
+	}{
+		{"text/plain", `{"title":"x"}`, http.StatusUnsupportedMediaType},
+		{"application/merge-patch+json", `{"title":`, http.StatusBadRequest},
+		{"application/merge-patch+json", `{"title":null}`, http.StatusBadRequest},
+		{"application/merge-patch+json", `{"priority":7}`, http.StatusBadRequest},
+	} {
+		req, _ := http.NewRequest("PATCH", srv.URL+path, strings.NewReader(tt.body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", tt.contentType)
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.want {
+			t.Errorf("PATCH %s %s: expected status %d, got %d", tt.contentType, tt.body, tt.want, resp.StatusCode)
+		}
+	}
+}
+
+func TestOverdueTasks(t *testing.T) {
+	srv := testutil.NewServer(t)
+	token := srv.Register(t, "overdue_user")
+	now := time.Now().UTC()
+	tasks := []map[string]interface{}{
+		{"title": "late", "due_date": now.Add(-48 * time.Hour), "priority": "high"},
+		{"title": "later", "due_date": now.Add(-time.Hour)},
+		{"title": "late but done", "due_date": now.Add(-72 * time.Hour), "status": "done"},
+		{"title": "upcoming", "due_date": now.Add(24 * time.Hour), "priority": "low"},
+		{"title": "someday"},
+	}
+	for _, task := range tasks {
+		if resp := srv.Do(t, "POST", "/tasks", token, task); resp.StatusCode != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d %s", resp.StatusCode, resp.Body)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/tasks/overdue", "late,later"},
+		{"/tasks/overdue?sort=-due_date", "later,late"},
+		{"/tasks?overdue=true&priority=high", "late"},
+		{"/tasks?status=done", "late but done"},
+		{"/tasks?priority=low", "upcoming"},
+		{"/tasks?due_after=" + now.Format(time.RFC3339), "upcoming"},
+		{"/tasks?sort=due_date&due_before=" + now.Format(time.RFC3339), "late but done,late,later"},
+		// Tasks without a due date come last, also across pages.
+		{"/tasks?sort=due_date&limit=2", "late but done,late,later,upcoming,someday"},
+		{"/tasks?sort=-due_date&limit=2", "upcoming,later,late,late but done,someday"},
+	}
+	for _, tt := range tests {
+		var all []string
+		path := tt.path
+		for pages := 0; path != ""; pages++ {
+			if pages > 5 {
+				t.Fatalf("GET %s: expected pagination to end", tt.path)
+			}
+			titles, next := listTitles(t, srv, token, path)
+			all = append(all, titles...)
+			path = ""
+			if next != "" {
+				u, _ := url.Parse(tt.path)
+				query := u.Query()
+				query.Set("cursor", next)
+				path = u.Path + "?" + query.Encode()
+			}
+		}
+		if got := strings.Join(all, ","); got != tt.want {
+			t.Errorf("GET %s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
 
-	if status := rr.Code; status != http.StatusOK {
-		t.Errorf("Expected status code %v, got %v", http.StatusOK, status)
+	for _, query := range []string{"status=later", "priority=urgent", "overdue=soon", "due_before=tomorrow"} {
+		if resp := srv.Do(t, "GET", "/tasks?"+query, token, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET /tasks?%s: expected status 400, got %d", query, resp.StatusCode)
+		}
 	}
 }
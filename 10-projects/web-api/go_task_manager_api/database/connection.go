@@ -1,22 +1,113 @@
 package database
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
+	"time"
 
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/config"
+
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 var DB *gorm.DB
 
+// ErrUnsupportedDriver is returned for drivers other than mysql, postgres
+// and sqlite.
+var ErrUnsupportedDriver = errors.New("unsupported database driver")
+
+// Pauses between connection attempts: doubling from retryWait up to
+// maxRetryWait.
+var (
+	retryWait    = 500 * time.Millisecond
+	maxRetryWait = 10 * time.Second
+)
+
+// Connect opens the database from the environment configuration (see
+// config.Load), retrying for DB_CONNECT_TIMEOUT, and exits if that fails.
 func Connect() {
-	// dsn := "username:password@tcp(127.0.0.1:3306)/task_db?charset=utf8mb4&parseTime=True&loc=Local"
-	dsn := "root:@tcp(127.0.0.1:3306)/task_db?charset=utf8mb4&parseTime=True&loc=Local"
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	cfg, err := config.Load()
 	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if err := ConnectWithRetry(context.Background(), cfg.DBDriver, cfg.DBDSN, cfg.DBConnectTimeout); err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
+}
+
+// ConnectWithRetry is ConnectTo that tries again, with growing pauses,
+// until the database answers, timeout passes or ctx ends. A database that
+// starts together with the API is often not up yet.
+func ConnectWithRetry(ctx context.Context, driver, dsn string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	wait := retryWait
+	for attempt := 1; ; attempt++ {
+		err := ConnectTo(driver, dsn)
+		if err == nil || errors.Is(err, ErrUnsupportedDriver) {
+			return err
+		}
+		slog.Warn("Database not reachable, retrying", "attempt", attempt, "retry_in", wait.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+		case <-time.After(wait):
+		}
+		if wait *= 2; wait > maxRetryWait {
+			wait = maxRetryWait
+		}
+	}
+}
+
+// ConnectTo opens the given database and stores it in DB.
+func ConnectTo(driver, dsn string) error {
+	db, err := Open(driver, dsn)
+	if err != nil {
+		return err
+	}
 	DB = db
-	fmt.Println("Connected to MySQL database")
+	slog.Info("Connected to database", "driver", driver)
+	return nil
+}
+
+// OpenInMemory opens a private, empty in-memory SQLite database with
+// foreign keys enabled. Tests use it for a fresh database each.
+func OpenInMemory() (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(":memory:?_pragma=foreign_keys(1)"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	// Every new connection would see its own empty database.
+	sqlDB.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// Open opens a database with one of the supported drivers: mysql, postgres
+// or sqlite (pure Go, no cgo needed).
+func Open(driver, dsn string) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch driver {
+	case config.DriverMySQL:
+		dialector = mysql.Open(dsn)
+	case config.DriverPostgres:
+		dialector = postgres.Open(dsn)
+	case config.DriverSQLite:
+		dialector = sqlite.Open(dsn)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedDriver, driver)
+	}
+	return gorm.Open(dialector, &gorm.Config{})
 }
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConnectWithRetry(t *testing.T) {
+	defer func(wait time.Duration) { retryWait = wait }(retryWait)
+	retryWait = 10 * time.Millisecond
+
+	// The directory of the database only appears after a while, like a
+	// database server that is still starting.
+	dir := filepath.Join(t.TempDir(), "later")
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		os.Mkdir(dir, 0755)
+	}()
+	if err := ConnectWithRetry(context.Background(), "sqlite", filepath.Join(dir, "tasks.db"), 5*time.Second); err != nil {
+		t.Fatalf("expected to connect once the database is there, got: %v", err)
+	}
+	if sqlDB, err := DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+func TestConnectWithRetryGivesUp(t *testing.T) {
+	defer func(wait time.Duration) { retryWait = wait }(retryWait)
+	retryWait = 10 * time.Millisecond
+
+	start := time.Now()
+	err := ConnectWithRetry(context.Background(), "sqlite", filepath.Join(t.TempDir(), "missing", "tasks.db"), 100*time.Millisecond)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("expected to give up after the timeout, took %s", elapsed)
+	}
+
+	if err := ConnectWithRetry(context.Background(), "oracle", "", time.Minute); !errors.Is(err, ErrUnsupportedDriver) {
+		t.Errorf("expected ErrUnsupportedDriver at once, got %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"errors"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// sqliteBusy is the primary SQLite result code of a database locked by
+// another writer.
+const sqliteBusy = 5
+
+// IsSerializationFailure reports whether err means that a transaction lost
+// a race with a concurrent one and may succeed when run again: SQLSTATE
+// 40001 or 40P01 on PostgreSQL, a deadlock on MySQL or a busy SQLite file.
+func IsSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01"
+	}
+	var myErr *mysqldriver.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1213 // ER_LOCK_DEADLOCK
+	}
+	var liteErr interface{ Code() int }
+	if errors.As(err, &liteErr) {
+		return liteErr.Code()&0xff == sqliteBusy
+	}
+	return false
+}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type sqliteError int
+
+func (e sqliteError) Error() string { return fmt.Sprintf("sqlite error %d", int(e)) }
+func (e sqliteError) Code() int     { return int(e) }
+
+func TestIsSerializationFailure(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&pgconn.PgError{Code: "40001"}, true},
+		{fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40P01"}), true},
+		{&pgconn.PgError{Code: "23505"}, false},
+		{&mysqldriver.MySQLError{Number: 1213}, true},
+		{&mysqldriver.MySQLError{Number: 1062}, false},
+		{sqliteError(5), true},
+		{sqliteError(517), true}, // SQLITE_BUSY_SNAPSHOT
+		{sqliteError(19), false},
+		{errors.New("database is down"), false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsSerializationFailure(tt.err); got != tt.want {
+			t.Errorf("IsSerializationFailure(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestRequestIDInLogs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf).With("component", "test")
+
+	logger.InfoContext(WithRequestID(context.Background(), "abc123"), "with id")
+	logger.InfoContext(context.Background(), "without id")
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	var first, second map[string]interface{}
+	if err := json.Unmarshal(lines[0], &first); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(lines[1], &second); err != nil {
+		t.Fatal(err)
+	}
+	if first["request_id"] != "abc123" || first["component"] != "test" || first[slog.MessageKey] != "with id" {
+		t.Errorf("unexpected record %v", first)
+	}
+	if _, ok := second["request_id"]; ok {
+		t.Errorf("expected no request_id, got %v", second)
+	}
+}
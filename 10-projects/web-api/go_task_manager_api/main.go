@@ -1,18 +1,26 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/config"
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/logging"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/metrics"
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/middleware"
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/routes"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/services"
 
 	_ "github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/docs" // docs generated by swag
-
-	"github.com/gorilla/mux"
-	httpSwagger "github.com/swaggo/http-swagger"
 )
 
 // @title           Go Task Manager API
@@ -26,20 +34,92 @@ import (
 // @name Authorization
 
 func main() {
-	database.Connect() // Step 2: Connect to MySQL database
+	// JSON logs on stdout, the log package included; records logged with a
+	// request context carry its request_id
+	slog.SetDefault(logging.NewLogger(os.Stdout))
+
+	cfg, err := config.Load() // Step 1: Read env vars / CONFIG_FILE
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	middleware.Configure(cfg)
+
+	// Ctrl+C, or SIGTERM from Docker or Kubernetes, starts a graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Step 2: Connect to MySQL, Postgres or SQLite, waiting for it to start
+	if err := database.ConnectWithRetry(ctx, cfg.DBDriver, cfg.DBDSN, cfg.DBConnectTimeout); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
 	// One-liner for all DB models
 	models.InitModels() // ✅ single call to migrate all models
 
-	r := mux.NewRouter()
-	// One-liner for all routes
-	routes.InitRoutes(r) // Register everything from one place
+	// Promote ADMIN_USERNAME if that user already exists
+	auth := services.NewAuthService(repositories.NewUserRepository(database.DB), cfg.AdminUsername, nil)
+	if err := auth.BootstrapAdmin(); err != nil {
+		log.Fatalf("Failed to bootstrap admin: %v", err)
+	}
 
-	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+	// One-liner for all routes, Swagger and global middlewares
+	m := metrics.New()
+	servers := []*http.Server{newServer(cfg.Addr, routes.NewRouter(database.DB, cfg, m), cfg)}
 
-	log.Println("Server starting at http://localhost:8080")
-	wrappedRouter := middleware.WrapWithMiddlewares(r)
-	http.ListenAndServe(":8080", wrappedRouter)
+	// /metrics on its own port, e.g. one that is not exposed publicly
+	if cfg.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", routes.MetricsHandler(m, cfg))
+		servers = append(servers, newServer(cfg.MetricsAddr, mux, cfg))
+	}
 
-	// http.ListenAndServe(":8080", r)
+	failed := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func(srv *http.Server) {
+			log.Printf("Server listening at %s (%s)", srv.Addr, cfg.Env)
+			if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				failed <- err
+			}
+		}(srv)
+	}
+
+	exitCode := 0
+	select {
+	case err := <-failed:
+		log.Printf("Server failed: %v", err)
+		exitCode = 1
+	case <-ctx.Done():
+		log.Printf("Shutting down, waiting up to %s for requests in flight", cfg.ShutdownTimeout)
+	}
+	stop() // a second signal kills the process at once
+
+	// Step 3: Stop accepting connections and let the requests in flight finish
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Shutdown of %s incomplete: %v", srv.Addr, err)
+			exitCode = 1
+		}
+	}
+	if sqlDB, err := database.DB.DB(); err == nil {
+		sqlDB.Close()
+	}
+	log.Printf("Server stopped")
+	if exitCode != 0 {
+		cancel()
+		os.Exit(exitCode)
+	}
+}
 
+// newServer serves handler at addr with the timeouts of cfg, so that slow
+// or idle clients cannot hold connections forever.
+func newServer(addr string, handler http.Handler, cfg config.Config) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
 }
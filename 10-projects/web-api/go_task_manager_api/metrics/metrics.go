@@ -0,0 +1,105 @@
+// Package metrics collects the Prometheus metrics of the API: HTTP requests
+// by route and status, logins, database queries and the Go runtime.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Login results, the values of the result label of auth_logins_total.
+const (
+	LoginSuccess  = "success"
+	LoginFailure  = "failure"  // wrong username or password
+	LoginLocked   = "locked"   // locked out after too many failures
+	LoginDisabled = "disabled" // right password, disabled account
+	LoginError    = "error"
+)
+
+// Metrics holds the collectors of one API server in their own registry. A
+// nil Metrics records nothing.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	logins   *prometheus.CounterVec
+	queries  *prometheus.HistogramVec
+}
+
+// New returns Metrics with the Go runtime and process collectors
+// registered.
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Time to answer HTTP requests by method, route template and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_logins_total",
+			Help: "Login attempts by result.",
+		}, []string{"result"}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Time of database queries by operation and table.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"operation", "table"}),
+	}
+	m.Registry.MustRegister(
+		m.requests, m.duration, m.logins, m.queries,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	// Results that have not happened yet are still listed, at 0.
+	for _, result := range []string{LoginSuccess, LoginFailure, LoginLocked, LoginDisabled, LoginError} {
+		m.logins.WithLabelValues(result)
+	}
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{Registry: m.Registry})
+}
+
+// ObserveRequest records an answered HTTP request. route is the template of
+// the matched route, such as /tasks/{id}, never the path itself, so that
+// the number of series stays bounded.
+func (m *Metrics) ObserveRequest(method, route string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	if route == "" {
+		route = "unmatched"
+	}
+	code := strconv.Itoa(status)
+	m.requests.WithLabelValues(method, route, code).Inc()
+	m.duration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+// Login records a login attempt with one of the Login results.
+func (m *Metrics) Login(result string) {
+	if m == nil {
+		return
+	}
+	m.logins.WithLabelValues(result).Inc()
+}
+
+// ObserveQuery records a database query.
+func (m *Metrics) ObserveQuery(operation, table string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.queries.WithLabelValues(operation, table).Observe(d.Seconds())
+}
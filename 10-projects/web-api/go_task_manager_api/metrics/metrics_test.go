@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserve(t *testing.T) {
+	m := New()
+	m.ObserveRequest("GET", "/tasks/{id}", 200, 20*time.Millisecond)
+	m.ObserveRequest("GET", "/tasks/{id}", 200, 30*time.Millisecond)
+	m.ObserveRequest("GET", "", 404, time.Millisecond)
+	m.Login(LoginFailure)
+
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("GET", "/tasks/{id}", "200")); got != 2 {
+		t.Errorf("expected 2 requests, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Errorf("expected 1 unmatched request, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.logins.WithLabelValues(LoginFailure)); got != 1 {
+		t.Errorf("expected 1 failed login, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.logins.WithLabelValues(LoginSuccess)); got != 0 {
+		t.Errorf("expected no successful login, got %v", got)
+	}
+
+	var nilMetrics *Metrics
+	nilMetrics.ObserveRequest("GET", "/", 200, time.Second) // must not panic
+	nilMetrics.Login(LoginSuccess)
+}
+
+func TestGormPlugin(t *testing.T) {
+	db, err := database.OpenInMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := New()
+	if err := db.Use(GormPlugin{Metrics: m}); err != nil {
+		t.Fatal(err)
+	}
+
+	type Widget struct {
+		ID   uint
+		Name string
+	}
+	if err := db.AutoMigrate(&Widget{}); err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&Widget{Name: "a"})
+	var widgets []Widget
+	db.Find(&widgets)
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`db_query_duration_seconds_count{operation="create",table="widgets"} 1`,
+		`db_query_duration_seconds_count{operation="query",table="widgets"} 1`,
+		"go_sql_open_connections",
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in\n%s", want, body)
+		}
+	}
+}
+
+// scrape returns what m.Handler serves.
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != 200 {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	return w.Body.String()
+}
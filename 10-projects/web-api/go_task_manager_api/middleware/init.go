@@ -1,12 +1,25 @@
 package middleware
 
 import (
+	"log/slog"
 	"net/http"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/config"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/metrics"
 )
 
-// WrapWithMiddlewares applies all global middlewares to the router
-func WrapWithMiddlewares(next http.Handler) http.Handler {
-	// You can chain more middlewares here like logging, CORS, etc.
-	return CORSMiddleware(LoggingMiddleware(next))
-	// return LoggingMiddleware(next) // example: wrap with logging
+// Configure applies the JWT, CORS and proxy settings from cfg.
+func Configure(cfg config.Config) {
+	jwtKey = []byte(cfg.JWTSecret)
+	tokenTTL = cfg.TokenTTL
+	refreshTokenTTL = cfg.RefreshTokenTTL
+	allowedOrigins = cfg.CORSOrigins
+	trustProxy = cfg.TrustProxy
+}
+
+// WrapWithMiddlewares applies all global middlewares to the router: every
+// request gets an ID first, so that the access log of slog.Default() and
+// everything logged while serving it carry that ID. Then it is counted in m.
+func WrapWithMiddlewares(next http.Handler, m *metrics.Metrics) http.Handler {
+	return RequestIDMiddleware(AccessLog(slog.Default())(Metrics(m)(CORSMiddleware(next))))
 }
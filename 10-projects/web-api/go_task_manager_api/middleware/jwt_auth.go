@@ -2,14 +2,24 @@ package middleware
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/config"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtKey = []byte("my_secret_key") // Replace with env config in production (JwtSecret)
+var jwtKey = []byte(config.DevJWTSecret) // set from JWT_SECRET by Configure
+var tokenTTL = 15 * time.Minute
+var refreshTokenTTL = 30 * 24 * time.Hour
 
 /* func JWTMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -41,36 +51,86 @@ var jwtKey = []byte("my_secret_key") // Replace with env config in production (J
 	})
 } */
 
-func JWTMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			http.Error(w, "Missing or invalid Authorization header", http.StatusUnauthorized)
-			return
-		}
+// RevocationList tells whether an access token was revoked before it
+// expired, by its token ID (jti).
+type RevocationList interface {
+	IsRevoked(jti string) (bool, error)
+}
 
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+// JWTMiddleware accepts requests with a valid access token that is not on
+// the revocation list, and stores the user and session in the context.
+func JWTMiddleware(revoked RevocationList) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+				utils.Error(w, r, http.StatusUnauthorized, "Missing or invalid Authorization header")
+				return
+			}
+
+			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+
+			token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+				return jwtKey, nil
+			}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+			if err != nil || !token.Valid {
+				utils.Error(w, r, http.StatusUnauthorized, "Invalid token")
+				return
+			}
+
+			claims, ok := token.Claims.(jwt.MapClaims)
+			if !ok || claims["username"] == nil {
+				utils.Error(w, r, http.StatusUnauthorized, "Invalid claims")
+				return
+			}
+			userID, ok := claims["userID"].(float64)
+			if !ok || userID <= 0 {
+				utils.Error(w, r, http.StatusUnauthorized, "Invalid claims")
+				return
+			}
+			jti, _ := claims["jti"].(string)
+			sessionID, _ := claims["sid"].(string)
+			role, _ := claims["role"].(string)
+			if jti == "" || sessionID == "" || role == "" {
+				utils.Error(w, r, http.StatusUnauthorized, "Invalid claims")
+				return
+			}
+
+			// ⛔ Reject tokens revoked by a logout
+			if isRevoked, err := revoked.IsRevoked(jti); err != nil {
+				slog.ErrorContext(r.Context(), "revocation check failed", "error", err)
+				utils.Error(w, r, http.StatusInternalServerError, "Failed to check token")
+				return
+			} else if isRevoked {
+				utils.Error(w, r, http.StatusUnauthorized, "Token revoked")
+				return
+			}
+
+			// ✅ Inject username into request context
+			ctx := context.WithValue(r.Context(), "username", claims["username"])
+			ctx = context.WithValue(ctx, "userID", uint(userID))
+			ctx = context.WithValue(ctx, "sessionID", sessionID)
+			ctx = context.WithValue(ctx, "role", role)
+			if entry := entryOf(r); entry != nil {
+				entry.userID = uint(userID)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
 
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			return jwtKey, nil
 		})
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok || claims["username"] == nil {
-			http.Error(w, "Invalid claims", http.StatusUnauthorized)
-			return
-		}
+	}
+}
 
-		// ✅ Inject username into request context
-		ctx := context.WithValue(r.Context(), "username", claims["username"])
-		ctx = context.WithValue(ctx, "userID", uint(claims["userID"].(float64)))
-		next.ServeHTTP(w, r.WithContext(ctx))
+// UserID returns the ID of the authenticated user, as set by JWTMiddleware.
+func UserID(r *http.Request) (uint, bool) {
+	userID, ok := r.Context().Value("userID").(uint)
+	return userID, ok && userID > 0
+}
 
-	})
+// SessionID returns the refresh token family of the access token, as set
+// by JWTMiddleware.
+func SessionID(r *http.Request) (string, bool) {
+	sessionID, ok := r.Context().Value("sessionID").(string)
+	return sessionID, ok && sessionID != ""
 }
 
 /* func GenerateToken(username string) (string, error) {
@@ -81,12 +141,71 @@ func JWTMiddleware(next http.Handler) http.Handler {
 	return token.SignedString(jwtKey)
 } */
 
-func GenerateToken(username string, userID uint) (string, error) {
+// AccessToken is a signed access token with its ID and expiry.
+type AccessToken struct {
+	Token     string
+	JTI       string
+	ExpiresAt time.Time
+}
+
+// GenerateToken issues a short-lived access token for the user, tied to a
+// session (refresh token family).
+func GenerateToken(username string, userID uint, role, sessionID string) (AccessToken, error) {
+	jti, err := NewTokenID()
+	if err != nil {
+		return AccessToken{}, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(tokenTTL)
 	claims := jwt.MapClaims{
 		"username": username,
 		"userID":   userID,
-		"exp":      time.Now().Add(72 * time.Hour).Unix(),
+		"role":     role,
+		"sid":      sessionID,
+		"jti":      jti,
+		"iat":      now.Unix(),
+		"exp":      expiresAt.Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	signed, err := token.SignedString(jwtKey)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	return AccessToken{Token: signed, JTI: jti, ExpiresAt: expiresAt}, nil
+}
+
+// RefreshToken is an opaque refresh token. Only its Hash is stored.
+type RefreshToken struct {
+	Token     string
+	Hash      string
+	ExpiresAt time.Time
+}
+
+// GenerateRefreshToken returns a new random refresh token.
+func GenerateRefreshToken() (RefreshToken, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return RefreshToken{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return RefreshToken{
+		Token:     token,
+		Hash:      HashRefreshToken(token),
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	}, nil
+}
+
+// HashRefreshToken returns the hex SHA-256 of a refresh token.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewTokenID returns a random ID for a token or token family.
+func NewTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
@@ -1,15 +1,159 @@
 package middleware
 
 import (
-	"log"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/logging"
+
+	"github.com/gorilla/mux"
 )
 
-func LoggingMiddleware(next http.Handler) http.Handler {
+// RequestIDHeader carries the ID of a request, from the client or a proxy
+// in front of the API, and back in the response.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDMiddleware keeps a sensible X-Request-ID of the request or makes
+// up a new one, stores it in the context (see logging.RequestID) and sends
+// it back in the response.
+func RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		log.Printf("[%s] %s %s", r.Method, r.RequestURI, time.Since(start))
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID accepts up to 128 printable ASCII characters, so that
+// clients cannot inject anything into the logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// accessEntry collects what the handlers inside the router learn about a
+// request for the access log and metrics recorded outside of it.
+type accessEntry struct {
+	route  string
+	userID uint
+}
+
+type accessEntryKey struct{}
+
+func entryOf(r *http.Request) *accessEntry {
+	entry, _ := r.Context().Value(accessEntryKey{}).(*accessEntry)
+	return entry
+}
+
+// withEntry returns r with an accessEntry, the one of an outer middleware
+// if there is one.
+func withEntry(r *http.Request) (*http.Request, *accessEntry) {
+	if entry := entryOf(r); entry != nil {
+		return r, entry
+	}
+	entry := &accessEntry{}
+	return r.WithContext(context.WithValue(r.Context(), accessEntryKey{}, entry)), entry
+}
+
+// AccessLog writes one record per request to logger once it is answered:
+// method, route template, path, status, bytes written, latency, user and
+// client address, and the request ID when RequestIDMiddleware runs first.
+func AccessLog(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			r, entry := withEntry(r)
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			status := rec.Status()
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("route", entry.route),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int64("bytes", rec.bytes),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+				slog.String("remote_ip", ClientIP(r)),
+			}
+			if entry.userID != 0 {
+				attrs = append(attrs, slog.Uint64("user_id", uint64(entry.userID)))
+			}
+			logger.LogAttrs(r.Context(), level, "request", attrs...)
+		})
+	}
+}
+
+// RouteTemplate records the path template of the matched route, such as
+// /tasks/{id}, for the access log and metrics. It is a middleware of the
+// router.
+func RouteTemplate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if entry := entryOf(r); entry != nil {
+			if route := mux.CurrentRoute(r); route != nil {
+				entry.route, _ = route.GetPathTemplate()
+			}
+		}
 		next.ServeHTTP(w, r)
 	})
 }
+
+// statusRecorder remembers the status and size of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+// Status is 200 if the handler wrote nothing at all.
+func (rec *statusRecorder) Status() int {
+	if rec.status == 0 {
+		return http.StatusOK
+	}
+	return rec.status
+}
+
+// Unwrap lets http.ResponseController reach the original writer.
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
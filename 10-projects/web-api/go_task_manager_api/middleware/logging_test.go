@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/logging"
+
+	"github.com/gorilla/mux"
+)
+
+type noRevocations struct{}
+
+func (noRevocations) IsRevoked(string) (bool, error) { return false, nil }
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	var seenID string
+	r := mux.NewRouter()
+	r.Use(RouteTemplate)
+	r.Handle("/tasks/{id}", JWTMiddleware(noRevocations{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seenID = logging.RequestID(r.Context())
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	})))
+	handler := RequestIDMiddleware(AccessLog(logging.NewLogger(&buf))(r))
+
+	token, err := GenerateToken("alice", 7, "member", "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest("GET", "/tasks/42", nil)
+	req.Header.Set("Authorization", "Bearer "+token.Token)
+	req.Header.Set(RequestIDHeader, "client-id-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if got := w.Header().Get(RequestIDHeader); got != "client-id-1" || seenID != "client-id-1" {
+		t.Errorf("expected request ID client-id-1 in response and context, got %q and %q", got, seenID)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("access log is not JSON: %q", buf.String())
+	}
+	want := map[string]interface{}{
+		"msg":        "request",
+		"method":     "GET",
+		"route":      "/tasks/{id}",
+		"path":       "/tasks/42",
+		"status":     float64(201),
+		"bytes":      float64(5),
+		"user_id":    float64(7),
+		"request_id": "client-id-1",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("expected %s %v, got %v", key, value, entry[key])
+		}
+	}
+	if _, ok := entry["latency_ms"].(float64); !ok {
+		t.Errorf("expected latency_ms, got %v", entry)
+	}
+}
+
+func TestRequestIDGenerated(t *testing.T) {
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, incoming := range []string{"", "with space", "new\nline", strings.Repeat("x", 129)} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, incoming)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if got := w.Header().Get(RequestIDHeader); len(got) != 32 || got == incoming {
+			t.Errorf("expected a new request ID for %q, got %q", incoming, got)
+		}
+	}
+}
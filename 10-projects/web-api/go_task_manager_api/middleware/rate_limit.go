@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/ratelimit"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+)
+
+// trustProxy is set from TRUST_PROXY by Configure.
+var trustProxy = false
+
+// RateLimit lets each client make limit requests, counted in store under
+// the key that key returns for the request. Every response carries the
+// RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers; requests
+// over the limit get 429 with Retry-After.
+func RateLimit(store ratelimit.Store, limit ratelimit.Limit, key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			result, err := store.Take(key(r), limit, time.Now())
+			if err != nil {
+				// Do not lock everybody out when the store fails.
+				slog.ErrorContext(r.Context(), "rate limit store failed", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", ceilSeconds(result.Reset))
+			if !result.Allowed {
+				w.Header().Set("Retry-After", ceilSeconds(result.RetryAfter))
+				utils.Error(w, r, http.StatusTooManyRequests,
+					fmt.Sprintf("Rate limit of %d requests exceeded, retry in %s seconds", limit.Burst, ceilSeconds(result.RetryAfter)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ByIP keys rate limits by client address.
+func ByIP(r *http.Request) string {
+	return "ip:" + ClientIP(r)
+}
+
+// ByUser keys rate limits by the authenticated user, and by client address
+// for everybody else. It must run after JWTMiddleware.
+func ByUser(r *http.Request) string {
+	if userID, ok := UserID(r); ok {
+		return "user:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	return ByIP(r)
+}
+
+// ClientIP returns the address of the client. Behind a proxy (TRUST_PROXY)
+// that is the last address the proxy appended to X-Forwarded-For; the ones
+// before it come from the client and could be made up.
+func ClientIP(r *http.Request) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			return strings.TrimSpace(hops[len(hops)-1])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ceilSeconds formats d as whole seconds, rounded up, for the headers.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
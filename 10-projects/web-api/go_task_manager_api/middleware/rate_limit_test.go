@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := RateLimit(ratelimit.NewMemoryStore(), ratelimit.PerMinute(2), ByUser)(ok)
+
+	request := func(remoteAddr string, userID uint) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/tasks", nil)
+		r.RemoteAddr = remoteAddr
+		if userID != 0 {
+			r = r.WithContext(context.WithValue(r.Context(), "userID", userID))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		remoteAddr string
+		userID     uint
+		status     int
+		remaining  string
+	}{
+		{"10.0.0.1:1000", 1, http.StatusOK, "1"},
+		{"10.0.0.2:2000", 1, http.StatusOK, "0"}, // same user from elsewhere
+		{"10.0.0.1:1000", 1, http.StatusTooManyRequests, "0"},
+		{"10.0.0.1:1000", 2, http.StatusOK, "1"}, // another user on the same IP
+		{"10.0.0.1:1001", 0, http.StatusOK, "1"}, // anonymous, by IP
+		{"10.0.0.1:1002", 0, http.StatusOK, "0"},
+		{"10.0.0.1:1003", 0, http.StatusTooManyRequests, "0"},
+	}
+	for i, tt := range tests {
+		w := request(tt.remoteAddr, tt.userID)
+		if w.Code != tt.status {
+			t.Errorf("%d: expected status %d, got %d", i, tt.status, w.Code)
+		}
+		if got := w.Header().Get("RateLimit-Remaining"); got != tt.remaining {
+			t.Errorf("%d: expected RateLimit-Remaining %s, got %q", i, tt.remaining, got)
+		}
+		if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Reset") == "" {
+			t.Errorf("%d: missing RateLimit headers: %v", i, w.Header())
+		}
+		if limited := tt.status == http.StatusTooManyRequests; limited != (w.Header().Get("Retry-After") == "30") {
+			t.Errorf("%d: unexpected Retry-After %q", i, w.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	defer func(trust bool) { trustProxy = trust }(trustProxy)
+
+	tests := []struct {
+		trust     bool
+		forwarded string
+		want      string
+	}{
+		{false, "", "192.0.2.1"},
+		{false, "203.0.113.9", "192.0.2.1"}, // not trusted
+		{true, "", "192.0.2.1"},
+		{true, "203.0.113.9", "203.0.113.9"},
+		{true, "198.51.100.7, 203.0.113.9", "203.0.113.9"}, // the first hop is up to the client
+	}
+	for _, tt := range tests {
+		trustProxy = tt.trust
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "192.0.2.1:4321"
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got := ClientIP(r); got != tt.want {
+			t.Errorf("ClientIP(trust=%v, X-Forwarded-For=%q) = %s, want %s", tt.trust, tt.forwarded, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+)
+
+// Role returns the role of the authenticated user, as set by JWTMiddleware.
+func Role(r *http.Request) (string, bool) {
+	role, ok := r.Context().Value("role").(string)
+	return role, ok && role != ""
+}
+
+// RequireRole only lets users with one of roles through. It must run after
+// JWTMiddleware:
+//
+//	admin := r.PathPrefix("/admin").Subrouter()
+//	admin.Use(requireAuth, middleware.RequireRole(models.RoleAdmin))
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, ok := Role(r)
+			if !ok {
+				utils.Error(w, r, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			utils.Error(w, r, http.StatusForbidden, "Forbidden")
+		})
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Comment is a message on a task, for discussing the work in the API.
+type Comment struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TaskID    uint      `json:"task_id" gorm:"index;not null"`
+	Task      Task      `json:"-" swaggerignore:"true" gorm:"constraint:OnDelete:CASCADE"`
+	AuthorID  uint      `json:"author_id" gorm:"index;not null"` // set from the JWT
+	Author    User      `json:"-" swaggerignore:"true" gorm:"constraint:OnDelete:CASCADE"`
+	Body      string    `json:"body" gorm:"type:text;not null" example:"Numbers are in the shared folder"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -1,6 +1,10 @@
 package models
 
+import "github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
+
 func InitModels() {
-	InitTaskModel() // Step 3: Migrate Task model
-	InitUserModel() // Step 4: Migrate User model
+	// Step 3: Apply pending schema migrations (see migrations.go)
+	if err := Migrate(database.DB); err != nil {
+		panic("Failed to migrate models: " + err.Error())
+	}
 }
@@ -0,0 +1,301 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SchemaMigration records a migration that has been applied.
+type SchemaMigration struct {
+	ID        string `gorm:"primaryKey;size:100"`
+	AppliedAt time.Time
+}
+
+// migration is one step of the schema history. Steps are never edited once
+// released; a change to the schema is a new step at the end.
+type migration struct {
+	id string
+	up func(tx *gorm.DB) error
+}
+
+var migrations = []migration{
+	{"0001_initial_schema", migrateInitialSchema},
+	{"0002_task_status_priority_due_date", migrateTaskStatusPriorityDueDate},
+	{"0003_task_version", migrateTaskVersion},
+	{"0004_comments", migrateComments},
+	{"0005_projects", migrateProjects},
+}
+
+// Migrate applies the migrations that db has not seen yet, in order.
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return err
+	}
+	for _, m := range migrations {
+		var count int64
+		if err := db.Model(&SchemaMigration{}).Where("id = ?", m.id).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{ID: m.id, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", m.id, err)
+		}
+	}
+	return nil
+}
+
+// PendingMigrations returns the IDs of the migrations that db has not seen
+// yet, in order. The server must not serve requests until there are none.
+func PendingMigrations(db *gorm.DB) ([]string, error) {
+	var applied []string
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		if err := db.Model(&SchemaMigration{}).Pluck("id", &applied).Error; err != nil {
+			return nil, err
+		}
+	}
+	done := make(map[string]bool, len(applied))
+	for _, id := range applied {
+		done[id] = true
+	}
+	var pending []string
+	for _, m := range migrations {
+		if !done[m.id] {
+			pending = append(pending, m.id)
+		}
+	}
+	return pending, nil
+}
+
+// The tables as they were before versioned migrations. They were created
+// by AutoMigrate, so this step creates or completes them the same way;
+// later steps must not use AutoMigrate on the live models.
+type userV1 struct {
+	ID       uint   `gorm:"primaryKey;autoIncrement"`
+	Username string `gorm:"unique;not null"`
+	Password string
+	Role     string `gorm:"size:20;not null;default:member"`
+	Disabled bool   `gorm:"not null;default:false"`
+}
+
+func (userV1) TableName() string { return "users" }
+
+type taskV1 struct {
+	ID          uint `gorm:"primaryKey;autoIncrement"`
+	Title       string
+	Description string
+	Completed   bool
+	UserID      uint      `gorm:"index;not null"`
+	User        userV1    `gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time `gorm:"index"`
+	UpdatedAt   time.Time `gorm:"index"`
+}
+
+func (taskV1) TableName() string { return "tasks" }
+
+type refreshTokenV1 struct {
+	ID              uint   `gorm:"primaryKey;autoIncrement"`
+	UserID          uint   `gorm:"index;not null"`
+	User            userV1 `gorm:"constraint:OnDelete:CASCADE"`
+	FamilyID        string `gorm:"size:64;index;not null"`
+	TokenHash       string `gorm:"size:64;uniqueIndex;not null"`
+	AccessJTI       string `gorm:"size:64;not null"`
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	UsedAt          *time.Time
+	RevokedAt       *time.Time
+	CreatedAt       time.Time
+}
+
+func (refreshTokenV1) TableName() string { return "refresh_tokens" }
+
+type revokedTokenV1 struct {
+	JTI       string    `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+func (revokedTokenV1) TableName() string { return "revoked_tokens" }
+
+// taskV0 is the owner column missing from the tasks of the first release,
+// added as nullable so that existing rows can be given an owner first.
+type taskV0 struct {
+	UserID *uint
+}
+
+func (taskV0) TableName() string { return "tasks" }
+
+func migrateInitialSchema(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&userV1{}); err != nil {
+		return err
+	}
+	if err := adoptOwnerlessTasks(tx); err != nil {
+		return err
+	}
+	// Only now can user_id become NOT NULL with its foreign key.
+	if err := tx.AutoMigrate(&taskV1{}, &refreshTokenV1{}, &revokedTokenV1{}); err != nil {
+		return err
+	}
+	// Tasks from before the timestamps get the migration time.
+	now := time.Now()
+	return tx.Table("tasks").Where("created_at IS NULL").
+		Updates(map[string]interface{}{"created_at": now, "updated_at": now}).Error
+}
+
+// adoptOwnerlessTasks gives the tasks of the first release, which had no
+// owner, to the first user who registered. Without any user they are moved
+// to legacy_tasks, since every task needs an owner.
+func adoptOwnerlessTasks(tx *gorm.DB) error {
+	m := tx.Migrator()
+	if !m.HasTable("tasks") || m.HasColumn(&taskV0{}, "UserID") {
+		return nil
+	}
+	if err := m.AddColumn(&taskV0{}, "UserID"); err != nil {
+		return err
+	}
+
+	var first userV1
+	if err := tx.Order("id").Limit(1).Find(&first).Error; err != nil {
+		return err
+	}
+	if first.ID != 0 {
+		if err := tx.Table("tasks").Where("user_id IS NULL").Update("user_id", first.ID).Error; err != nil {
+			return err
+		}
+	} else if err := moveAsideTasks(tx); err != nil {
+		return err
+	}
+
+	if err := m.AlterColumn(&taskV1{}, "UserID"); err != nil {
+		return err
+	}
+	return m.CreateConstraint(&taskV1{}, "User")
+}
+
+// moveAsideTasks copies every task to legacy_tasks and empties tasks.
+func moveAsideTasks(tx *gorm.DB) error {
+	var count int64
+	if err := tx.Table("tasks").Count(&count).Error; err != nil || count == 0 {
+		return err
+	}
+	if err := tx.Exec("CREATE TABLE legacy_tasks AS SELECT * FROM tasks").Error; err != nil {
+		return err
+	}
+	return tx.Exec("DELETE FROM tasks").Error
+}
+
+// taskV2 holds the columns added by migration 0002.
+type taskV2 struct {
+	Status   string     `gorm:"size:20;not null;default:todo;index"`
+	Priority string     `gorm:"size:10;not null;default:medium;index"`
+	DueDate  *time.Time `gorm:"index"`
+}
+
+func (taskV2) TableName() string { return "tasks" }
+
+func migrateTaskStatusPriorityDueDate(tx *gorm.DB) error {
+	m := tx.Migrator()
+	for _, field := range []string{"Status", "Priority", "DueDate"} {
+		if m.HasColumn(&taskV2{}, field) {
+			continue
+		}
+		if err := m.AddColumn(&taskV2{}, field); err != nil {
+			return err
+		}
+	}
+	for _, index := range []string{"Status", "Priority", "DueDate"} {
+		if m.HasIndex(&taskV2{}, index) {
+			continue
+		}
+		if err := m.CreateIndex(&taskV2{}, index); err != nil {
+			return err
+		}
+	}
+	// Completed tasks are done; everything else starts as todo.
+	return tx.Table("tasks").Where("completed = ?", true).Update("status", StatusDone).Error
+}
+
+// taskV3 holds the column added by migration 0003.
+type taskV3 struct {
+	Version uint `gorm:"not null;default:1"`
+}
+
+func (taskV3) TableName() string { return "tasks" }
+
+func migrateTaskVersion(tx *gorm.DB) error {
+	if tx.Migrator().HasColumn(&taskV3{}, "Version") {
+		return nil
+	}
+	return tx.Migrator().AddColumn(&taskV3{}, "Version")
+}
+
+// commentV1 is the table created by migration 0004.
+type commentV1 struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	TaskID    uint   `gorm:"index;not null"`
+	Task      taskV1 `gorm:"constraint:OnDelete:CASCADE"`
+	AuthorID  uint   `gorm:"index;not null"`
+	Author    userV1 `gorm:"constraint:OnDelete:CASCADE"`
+	Body      string `gorm:"type:text;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (commentV1) TableName() string { return "comments" }
+
+func migrateComments(tx *gorm.DB) error {
+	return tx.AutoMigrate(&commentV1{})
+}
+
+// The tables created by migration 0005.
+type projectV1 struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	Name        string `gorm:"size:200;not null"`
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (projectV1) TableName() string { return "projects" }
+
+type projectMemberV1 struct {
+	ProjectID uint      `gorm:"primaryKey"`
+	Project   projectV1 `gorm:"constraint:OnDelete:CASCADE"`
+	UserID    uint      `gorm:"primaryKey;index"`
+	User      userV1    `gorm:"constraint:OnDelete:CASCADE"`
+	Role      string    `gorm:"size:20;not null"`
+	CreatedAt time.Time
+}
+
+func (projectMemberV1) TableName() string { return "project_members" }
+
+// taskV5 holds the column added by migration 0005.
+type taskV5 struct {
+	ProjectID *uint `gorm:"index"`
+}
+
+func (taskV5) TableName() string { return "tasks" }
+
+func migrateProjects(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&projectV1{}, &projectMemberV1{}); err != nil {
+		return err
+	}
+	m := tx.Migrator()
+	if !m.HasColumn(&taskV5{}, "ProjectID") {
+		if err := m.AddColumn(&taskV5{}, "ProjectID"); err != nil {
+			return err
+		}
+	}
+	if m.HasIndex(&taskV5{}, "ProjectID") {
+		return nil
+	}
+	return m.CreateIndex(&taskV5{}, "ProjectID")
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
+)
+
+func TestMigrateLegacyTasks(t *testing.T) {
+	db, err := database.OpenInMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The schema of the first release, whose tasks had no owner.
+	statements := []string{
+		"CREATE TABLE users (id integer PRIMARY KEY AUTOINCREMENT, username text NOT NULL UNIQUE, password text)",
+		"CREATE TABLE tasks (id integer PRIMARY KEY AUTOINCREMENT, title text, description text, completed numeric)",
+		"INSERT INTO users (username, password) VALUES ('old_user', 'hash'), ('later_user', 'hash')",
+		"INSERT INTO tasks (title, completed) VALUES ('finished', true), ('open', false)",
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// Running again is a no-op.
+	if err := Migrate(db); err != nil {
+		t.Fatalf("expected second run to succeed, got: %v", err)
+	}
+
+	var tasks []Task
+	db.Order("id").Find(&tasks)
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %d", len(tasks))
+	}
+	if tasks[0].Status != StatusDone || tasks[1].Status != StatusTodo {
+		t.Errorf("expected status from completed, got %q and %q", tasks[0].Status, tasks[1].Status)
+	}
+	for _, task := range tasks {
+		if task.Priority != PriorityMedium || task.DueDate != nil || task.CreatedAt.IsZero() || task.Version != 1 {
+			t.Errorf("unexpected migrated task: %+v", task)
+		}
+		if task.UserID != 1 {
+			t.Errorf("expected the first user to own %q, got user %d", task.Title, task.UserID)
+		}
+	}
+
+	// user_id is now required.
+	if err := db.Exec("INSERT INTO tasks (title) VALUES ('ownerless')").Error; err == nil {
+		t.Error("expected a task without owner to be rejected")
+	}
+
+	var user User
+	db.First(&user)
+	if user.Role != RoleMember || user.Disabled {
+		t.Errorf("unexpected migrated user: %+v", user)
+	}
+
+	var applied int64
+	db.Model(&SchemaMigration{}).Count(&applied)
+	if int(applied) != len(migrations) {
+		t.Errorf("expected %d recorded migrations, got %d", len(migrations), applied)
+	}
+}
+
+func TestMigrateOwnerlessTasksWithoutUsers(t *testing.T) {
+	db, err := database.OpenInMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	statements := []string{
+		"CREATE TABLE tasks (id integer PRIMARY KEY AUTOINCREMENT, title text, description text, completed numeric)",
+		"INSERT INTO tasks (title, completed) VALUES ('nobody''s', false)",
+	}
+	for _, stmt := range statements {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var tasks, legacy int64
+	db.Model(&Task{}).Count(&tasks)
+	db.Table("legacy_tasks").Count(&legacy)
+	if tasks != 0 || legacy != 1 {
+		t.Errorf("expected the task to be moved to legacy_tasks, got %d tasks and %d legacy", tasks, legacy)
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	db, err := database.OpenInMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := PendingMigrations(db)
+	if err != nil || len(pending) != len(migrations) || pending[0] != "0001_initial_schema" {
+		t.Fatalf("expected all migrations pending on an empty database, got %v, %v", pending, err)
+	}
+	if err := Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if pending, err := PendingMigrations(db); err != nil || len(pending) != 0 {
+		t.Errorf("expected no pending migrations, got %v, %v", pending, err)
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Roles of project members. Viewers see the tasks of a project, editors
+// also change them, and owners also manage the project and its members.
+const (
+	ProjectOwner  = "owner"
+	ProjectEditor = "editor"
+	ProjectViewer = "viewer"
+)
+
+// Project groups tasks that its members share.
+type Project struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name        string    `json:"name" gorm:"size:200;not null" example:"Q3 report"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// Role is the role of the requesting user.
+	Role string `json:"role,omitempty" gorm:"->;-:migration" enums:"owner,editor,viewer"`
+}
+
+// ProjectMember gives a user a role in a project.
+type ProjectMember struct {
+	ProjectID uint      `json:"project_id" gorm:"primaryKey"`
+	Project   Project   `json:"-" swaggerignore:"true" gorm:"constraint:OnDelete:CASCADE"`
+	UserID    uint      `json:"user_id" gorm:"primaryKey;index"`
+	User      User      `json:"-" swaggerignore:"true" gorm:"constraint:OnDelete:CASCADE"`
+	Username  string    `json:"username" gorm:"->;-:migration"`
+	Role      string    `json:"role" gorm:"size:20;not null" enums:"owner,editor,viewer" example:"editor"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsValidProjectRole reports whether role is one of the project roles.
+func IsValidProjectRole(role string) bool {
+	return role == ProjectOwner || role == ProjectEditor || role == ProjectViewer
+}
+
+// ProjectRoleRank orders the project roles by what they allow, from 1 for
+// viewers to 3 for owners; unknown roles are 0.
+func ProjectRoleRank(role string) int {
+	switch role {
+	case ProjectViewer:
+		return 1
+	case ProjectEditor:
+		return 2
+	case ProjectOwner:
+		return 3
+	}
+	return 0
+}
@@ -1,26 +1,50 @@
 package models
 
-import (
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
+import "time"
 
-	"gorm.io/gorm"
+// Task statuses. A task is Completed exactly when its status is done.
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+)
+
+// Task priorities.
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
 )
 
 type Task struct {
-	ID          uint   `json:"id" gorm:"primaryKey;autoIncrement"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Completed   bool   `json:"completed"`
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Completed   bool       `json:"completed"`
+	Status      string     `json:"status" gorm:"size:20;not null;default:todo;index" enums:"todo,in_progress,done" example:"todo"`
+	Priority    string     `json:"priority" gorm:"size:10;not null;default:medium;index" enums:"low,medium,high" example:"medium"`
+	DueDate     *time.Time `json:"due_date,omitempty" gorm:"index"`
+	UserID      uint       `json:"user_id" gorm:"index;not null"`     // owner, set from the JWT
+	ProjectID   *uint      `json:"project_id,omitempty" gorm:"index"` // shared with the project's members; nil for personal tasks
+	User        User       `json:"-" swaggerignore:"true" gorm:"constraint:OnDelete:CASCADE"`
+	Version     uint       `json:"version" gorm:"not null;default:1"` // bumped on every update, sent as the ETag
+	CreatedAt   time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"index"`
+	// CommentCount is only filled in task lists.
+	CommentCount *int64 `json:"comment_count,omitempty" gorm:"->;-:migration"`
+}
+
+// IsValidStatus reports whether status is one of the task statuses.
+func IsValidStatus(status string) bool {
+	return status == StatusTodo || status == StatusInProgress || status == StatusDone
 }
 
-func MigrateTask(db *gorm.DB) error {
-	return db.AutoMigrate(&Task{})
+// IsValidPriority reports whether priority is one of the task priorities.
+func IsValidPriority(priority string) bool {
+	return priority == PriorityLow || priority == PriorityMedium || priority == PriorityHigh
 }
 
-func InitTaskModel() {
-	db := database.DB
-	err := MigrateTask(db)
-	if err != nil {
-		panic("Failed to migrate task model")
-	}
+// IsOverdue reports whether the task is past its due date and not done.
+func (t Task) IsOverdue(now time.Time) bool {
+	return t.DueDate != nil && t.DueDate.Before(now) && t.Status != StatusDone
 }
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// RefreshToken is one refresh token of a login session. Each refresh
+// replaces it with a new token of the same family, so a family is one
+// session. Only the SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	UserID    uint   `gorm:"index;not null"`
+	User      User   `gorm:"constraint:OnDelete:CASCADE"`
+	FamilyID  string `gorm:"size:64;index;not null"`
+	TokenHash string `gorm:"size:64;uniqueIndex;not null"`
+	// The access token issued together with this refresh token, so that
+	// revoking the family also revokes it.
+	AccessJTI       string `gorm:"size:64;not null"`
+	AccessExpiresAt time.Time
+	ExpiresAt       time.Time
+	UsedAt          *time.Time // set when the token is exchanged
+	RevokedAt       *time.Time
+	CreatedAt       time.Time
+}
+
+// RevokedToken is an access token that must be rejected until it expires.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey;size:64"`
+	ExpiresAt time.Time `gorm:"index"`
+}
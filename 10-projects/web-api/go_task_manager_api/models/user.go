@@ -1,15 +1,23 @@
 package models
 
-import (
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
-
-	"gorm.io/gorm"
+// Roles of a user. Members manage their own tasks; admins also manage
+// users.
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
 )
 
 type User struct {
 	ID       uint   `json:"id" gorm:"primaryKey;autoIncrement"`
 	Username string `json:"username" gorm:"unique;not null"`
-	Password string `json:"password"`
+	Password string `json:"-"` // bcrypt hash, never sent to clients
+	Role     string `json:"role" gorm:"size:20;not null;default:member"`
+	Disabled bool   `json:"disabled" gorm:"not null;default:false"`
+}
+
+// IsValidRole reports whether role is one of the known roles.
+func IsValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleMember
 }
 
 /*
@@ -22,15 +30,3 @@ func InitUserModel() {
 	DB.AutoMigrate(&User{})
 }
 */
-
-func MigrateUser(db *gorm.DB) error {
-	return db.AutoMigrate(&User{})
-}
-
-func InitUserModel() {
-	db := database.DB
-	err := MigrateUser(db)
-	if err != nil {
-		panic("Failed to migrate user model")
-	}
-}
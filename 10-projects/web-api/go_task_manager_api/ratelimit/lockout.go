@@ -0,0 +1,143 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// DefaultLockoutWindow is how long failed logins count when Lockout.Window
+// is not set.
+const DefaultLockoutWindow = 24 * time.Hour
+
+// Attempts are the failed logins of a key since the last success.
+type Attempts struct {
+	Failures int
+	Last     time.Time
+}
+
+// AttemptStore counts failed logins by key.
+type AttemptStore interface {
+	Get(key string) (Attempts, error)
+	// AddFailure records a failure at at and returns the new count. A
+	// failure more than window after the previous one starts a new count.
+	AddFailure(key string, at time.Time, window time.Duration) (Attempts, error)
+	Reset(key string) error
+}
+
+// Lockout locks a key, such as a username, out after Threshold failed
+// logins in a row: first for Base, then twice as long with every further
+// failure, up to Max. A nil Lockout never locks.
+type Lockout struct {
+	Store     AttemptStore
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+	Window    time.Duration // failures are forgotten after this long without one
+}
+
+// LockedFor returns how long key is still locked out at now.
+func (l *Lockout) LockedFor(key string, now time.Time) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	a, err := l.Store.Get(key)
+	if err != nil || now.Sub(a.Last) > l.window() {
+		return 0, err
+	}
+	return l.remaining(a, now), nil
+}
+
+// Fail records a failed login of key and returns how long it is locked out
+// because of it.
+func (l *Lockout) Fail(key string, now time.Time) (time.Duration, error) {
+	if l == nil {
+		return 0, nil
+	}
+	a, err := l.Store.AddFailure(key, now, l.window())
+	if err != nil {
+		return 0, err
+	}
+	return l.remaining(a, now), nil
+}
+
+// Succeed forgets the failed logins of key.
+func (l *Lockout) Succeed(key string) error {
+	if l == nil {
+		return nil
+	}
+	return l.Store.Reset(key)
+}
+
+func (l *Lockout) window() time.Duration {
+	if l.Window > 0 {
+		return l.Window
+	}
+	return DefaultLockoutWindow
+}
+
+// remaining is the rest of the lock that a ends in, if any.
+func (l *Lockout) remaining(a Attempts, now time.Time) time.Duration {
+	if a.Failures < l.Threshold {
+		return 0
+	}
+	lock := l.Base
+	for i := l.Threshold; i < a.Failures && lock < l.Max; i++ {
+		lock *= 2
+	}
+	if lock > l.Max {
+		lock = l.Max
+	}
+	if until := a.Last.Add(lock); until.After(now) {
+		return until.Sub(now)
+	}
+	return 0
+}
+
+// MemoryAttemptStore is an AttemptStore in the memory of one server.
+type MemoryAttemptStore struct {
+	mu        sync.Mutex
+	attempts  map[string]Attempts
+	lastSweep time.Time
+}
+
+var _ AttemptStore = (*MemoryAttemptStore)(nil)
+
+func NewMemoryAttemptStore() *MemoryAttemptStore {
+	return &MemoryAttemptStore{attempts: make(map[string]Attempts)}
+}
+
+func (s *MemoryAttemptStore) Get(key string) (Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[key], nil
+}
+
+func (s *MemoryAttemptStore) AddFailure(key string, at time.Time, window time.Duration) (Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Failures of random usernames must not pile up forever.
+	if at.Sub(s.lastSweep) > window {
+		for k, a := range s.attempts {
+			if at.Sub(a.Last) > window {
+				delete(s.attempts, k)
+			}
+		}
+		s.lastSweep = at
+	}
+
+	a := s.attempts[key]
+	if at.Sub(a.Last) > window {
+		a = Attempts{}
+	}
+	a.Failures++
+	a.Last = at
+	s.attempts[key] = a
+	return a, nil
+}
+
+func (s *MemoryAttemptStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
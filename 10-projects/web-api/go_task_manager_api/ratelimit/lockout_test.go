@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockout(t *testing.T) {
+	lockout := &Lockout{
+		Store:     NewMemoryAttemptStore(),
+		Threshold: 3,
+		Base:      time.Minute,
+		Max:       5 * time.Minute,
+		Window:    time.Hour,
+	}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		at   time.Duration
+		fail bool          // Fail, or else only LockedFor
+		want time.Duration // lock left
+	}{
+		{"first failure", 0, true, 0},
+		{"second failure", time.Second, true, 0},
+		{"third failure locks", 2 * time.Second, true, time.Minute},
+		{"still locked", 32 * time.Second, false, 30 * time.Second},
+		{"lock over", 62 * time.Second, false, 0},
+		{"fourth failure doubles", 70 * time.Second, true, 2 * time.Minute},
+		{"fifth failure doubles", 70*time.Second + 2*time.Minute, true, 4 * time.Minute},
+		{"sixth failure is capped", 70*time.Second + 6*time.Minute, true, 5 * time.Minute},
+		{"forgotten after the window", 2 * time.Hour, false, 0},
+		{"new series after the window", 2 * time.Hour, true, 0},
+	}
+	for _, tt := range tests {
+		now := start.Add(tt.at)
+		var got time.Duration
+		var err error
+		if tt.fail {
+			got, err = lockout.Fail("alice", now)
+		} else {
+			got, err = lockout.LockedFor("alice", now)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	if wait, _ := lockout.LockedFor("bob", start); wait != 0 {
+		t.Errorf("expected other keys not to be locked, got %s", wait)
+	}
+	for i := 0; i < 3; i++ {
+		lockout.Fail("carol", start)
+	}
+	lockout.Succeed("carol")
+	if wait, _ := lockout.Fail("carol", start); wait != 0 {
+		t.Errorf("expected a success to reset the count, got a lock of %s", wait)
+	}
+}
+
+func TestNilLockout(t *testing.T) {
+	var lockout *Lockout
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		if wait, err := lockout.Fail("alice", now); wait != 0 || err != nil {
+			t.Fatalf("expected a nil Lockout never to lock, got %s, %v", wait, err)
+		}
+	}
+	if wait, err := lockout.LockedFor("alice", now); wait != 0 || err != nil {
+		t.Errorf("expected a nil Lockout never to lock, got %s, %v", wait, err)
+	}
+	if err := lockout.Succeed("alice"); err != nil {
+		t.Error(err)
+	}
+}
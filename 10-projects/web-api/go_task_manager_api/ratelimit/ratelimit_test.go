@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	store := NewMemoryStore()
+	limit := PerMinute(3) // a request every 20s, 3 at once
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		key        string
+		after      time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		reset      time.Duration
+	}{
+		{"a", 0, true, 2, 0, 20 * time.Second},
+		{"a", 0, true, 1, 0, 40 * time.Second},
+		{"a", 0, true, 0, 0, 60 * time.Second},
+		{"a", 0, false, 0, 20 * time.Second, 60 * time.Second},
+		{"b", 0, true, 2, 0, 20 * time.Second}, // keys have their own buckets
+		{"a", 5 * time.Second, false, 0, 15 * time.Second, 55 * time.Second},
+		{"a", 20 * time.Second, true, 0, 0, 60 * time.Second},
+		{"a", 2 * time.Minute, true, 2, 0, 20 * time.Second}, // refilled, but not beyond the burst
+	}
+	for i, tt := range tests {
+		got, err := store.Take(tt.key, limit, start.Add(tt.after))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := Result{Allowed: tt.allowed, Remaining: tt.remaining, RetryAfter: tt.retryAfter, Reset: tt.reset}
+		if got != want {
+			t.Errorf("%d: Take(%s) at +%s = %+v, want %+v", i, tt.key, tt.after, got, want)
+		}
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.Take("a", PerMinute(60), start)
+	store.Take("b", PerMinute(1), start)
+
+	store.Take("c", PerMinute(60), start.Add(2*time.Second))
+	if len(store.buckets) != 3 {
+		t.Fatalf("expected no sweep within a minute, got %d buckets", len(store.buckets))
+	}
+	store.Take("c", PerMinute(60), start.Add(61*time.Second))
+	if _, ok := store.buckets["a"]; ok {
+		t.Error("expected the full bucket a to be dropped")
+	}
+	if _, ok := store.buckets["b"]; ok {
+		t.Error("expected bucket b, full after a minute, to be dropped")
+	}
+	if _, ok := store.buckets["c"]; !ok {
+		t.Error("expected the bucket in use to be kept")
+	}
+}
@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+
+	"gorm.io/gorm"
+)
+
+// CommentRepository stores the comments of tasks. Lookups are scoped to a
+// task; whether the user may see that task is up to the caller.
+type CommentRepository interface {
+	// ListPage returns up to limit comments of taskID with IDs above
+	// afterID, oldest first.
+	ListPage(taskID, afterID uint, limit int) ([]models.Comment, error)
+	Find(id, taskID uint) (models.Comment, error)
+	Create(comment *models.Comment) error
+	// UpdateBody changes the body of comment and its UpdatedAt.
+	UpdateBody(comment *models.Comment) error
+	Delete(id, taskID uint) error
+}
+
+// GormCommentRepository is a CommentRepository backed by GORM.
+type GormCommentRepository struct {
+	db *gorm.DB
+}
+
+var _ CommentRepository = (*GormCommentRepository)(nil)
+
+func NewCommentRepository(db *gorm.DB) *GormCommentRepository {
+	return &GormCommentRepository{db: db}
+}
+
+func (r *GormCommentRepository) ListPage(taskID, afterID uint, limit int) ([]models.Comment, error) {
+	comments := []models.Comment{}
+	err := r.db.Where("task_id = ? AND id > ?", taskID, afterID).Order("id").Limit(limit).Find(&comments).Error
+	return comments, err
+}
+
+func (r *GormCommentRepository) Find(id, taskID uint) (models.Comment, error) {
+	var comment models.Comment
+	err := r.db.Where("task_id = ?", taskID).First(&comment, id).Error
+	return comment, notFound(err)
+}
+
+func (r *GormCommentRepository) Create(comment *models.Comment) error {
+	return r.db.Create(comment).Error
+}
+
+func (r *GormCommentRepository) UpdateBody(comment *models.Comment) error {
+	return r.db.Model(comment).Select("body", "updated_at").Updates(comment).Error
+}
+
+func (r *GormCommentRepository) Delete(id, taskID uint) error {
+	result := r.db.Where("task_id = ?", taskID).Delete(&models.Comment{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
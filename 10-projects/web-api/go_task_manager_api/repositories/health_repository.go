@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+
+	"gorm.io/gorm"
+)
+
+// HealthRepository reports on the database itself.
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	PendingMigrations() ([]string, error)
+}
+
+// GormHealthRepository is a HealthRepository backed by GORM.
+type GormHealthRepository struct {
+	db *gorm.DB
+}
+
+var _ HealthRepository = (*GormHealthRepository)(nil)
+
+func NewHealthRepository(db *gorm.DB) *GormHealthRepository {
+	return &GormHealthRepository{db: db}
+}
+
+func (r *GormHealthRepository) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (r *GormHealthRepository) PendingMigrations() ([]string, error) {
+	return models.PendingMigrations(r.db)
+}
@@ -0,0 +1,141 @@
+package repositories
+
+import (
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+
+	"gorm.io/gorm"
+)
+
+// ProjectRepository stores projects and their members. It does not check
+// who may see or change a project; that is up to the caller.
+type ProjectRepository interface {
+	// Create stores project with ownerID as its first owner.
+	Create(project *models.Project, ownerID uint) error
+	// ListForUser returns the projects userID is a member of, with the Role
+	// of userID, ordered by name.
+	ListForUser(userID uint) ([]models.Project, error)
+	Find(id uint) (models.Project, error)
+	// Delete removes a project and its members.
+	Delete(id uint) error
+	CountTasks(id uint) (int64, error)
+	// Role returns the role of userID in the project, or ErrNotFound if
+	// userID is not a member.
+	Role(projectID, userID uint) (string, error)
+	// Members returns the members of a project with their Username, owners
+	// first.
+	Members(projectID uint) ([]models.ProjectMember, error)
+	AddMember(member *models.ProjectMember) error
+	UpdateRole(projectID, userID uint, role string) error
+	RemoveMember(projectID, userID uint) error
+	CountOwners(projectID uint) (int64, error)
+}
+
+// GormProjectRepository is a ProjectRepository backed by GORM.
+type GormProjectRepository struct {
+	db *gorm.DB
+}
+
+var _ ProjectRepository = (*GormProjectRepository)(nil)
+
+func NewProjectRepository(db *gorm.DB) *GormProjectRepository {
+	return &GormProjectRepository{db: db}
+}
+
+func (r *GormProjectRepository) Create(project *models.Project, ownerID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(project).Error; err != nil {
+			return err
+		}
+		project.Role = models.ProjectOwner
+		return tx.Create(&models.ProjectMember{ProjectID: project.ID, UserID: ownerID, Role: models.ProjectOwner}).Error
+	})
+}
+
+func (r *GormProjectRepository) ListForUser(userID uint) ([]models.Project, error) {
+	projects := []models.Project{}
+	err := r.db.Select("projects.*, project_members.role AS role").
+		Joins("JOIN project_members ON project_members.project_id = projects.id").
+		Where("project_members.user_id = ?", userID).
+		Order("projects.name, projects.id").Find(&projects).Error
+	return projects, err
+}
+
+func (r *GormProjectRepository) Find(id uint) (models.Project, error) {
+	var project models.Project
+	err := r.db.First(&project, id).Error
+	return project, notFound(err)
+}
+
+func (r *GormProjectRepository) Delete(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Not every database enforces the cascade, SQLite without
+		// foreign_keys for one.
+		if err := tx.Where("project_id = ?", id).Delete(&models.ProjectMember{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Project{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *GormProjectRepository) CountTasks(id uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Task{}).Where("project_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+func (r *GormProjectRepository) Role(projectID, userID uint) (string, error) {
+	var member models.ProjectMember
+	err := r.db.Where("project_id = ? AND user_id = ?", projectID, userID).First(&member).Error
+	return member.Role, notFound(err)
+}
+
+func (r *GormProjectRepository) Members(projectID uint) ([]models.ProjectMember, error) {
+	members := []models.ProjectMember{}
+	err := r.db.Select("project_members.*, users.username AS username").
+		Joins("JOIN users ON users.id = project_members.user_id").
+		Where("project_members.project_id = ?", projectID).
+		Order("CASE project_members.role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, users.username").
+		Find(&members).Error
+	return members, err
+}
+
+func (r *GormProjectRepository) AddMember(member *models.ProjectMember) error {
+	return r.db.Create(member).Error
+}
+
+func (r *GormProjectRepository) UpdateRole(projectID, userID uint, role string) error {
+	result := r.db.Model(&models.ProjectMember{}).
+		Where("project_id = ? AND user_id = ?", projectID, userID).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GormProjectRepository) RemoveMember(projectID, userID uint) error {
+	result := r.db.Where("project_id = ? AND user_id = ?", projectID, userID).Delete(&models.ProjectMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *GormProjectRepository) CountOwners(projectID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.ProjectMember{}).
+		Where("project_id = ? AND role = ?", projectID, models.ProjectOwner).Count(&count).Error
+	return count, err
+}
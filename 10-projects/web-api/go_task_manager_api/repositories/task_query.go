@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"strings"
+	"time"
+)
+
+// TaskFilter narrows a task listing. Zero fields do not filter.
+type TaskFilter struct {
+	ProjectID     *uint // 0 for personal tasks
+	Completed     *bool
+	Status        string
+	Priority      string
+	Search        string // in title or description, case-insensitive
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	DueAfter      *time.Time
+	DueBefore     *time.Time
+	OverdueAt     *time.Time // due before this time and not done
+}
+
+// TaskPage selects one page of a sorted task listing. Rows are ordered by
+// Column, then by ID, both in the same direction. Rows where a Nullable
+// column is NULL come last either way.
+type TaskPage struct {
+	Column   string // a column name; callers must whitelist it
+	Nullable bool
+	Desc     bool
+	Limit    int
+	After    *TaskCursor // nil for the first page
+}
+
+// TaskCursor is the position after the last row of a page: its sort value
+// (nil for NULL) and ID.
+type TaskCursor struct {
+	Value interface{}
+	ID    uint
+}
+
+// likePattern turns s into a LIKE pattern matching s anywhere, with '!'
+// as the escape character.
+func likePattern(s string) string {
+	s = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(strings.ToLower(s))
+	return "%" + s + "%"
+}
@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrVersionConflict is returned when a task was modified since the version
+// an update is based on.
+var ErrVersionConflict = errors.New("task was modified")
+
+// TaskRepository stores tasks. Lookups are scoped to the tasks a user can
+// see: their personal tasks and the tasks of their projects. Any other task
+// is reported as ErrNotFound.
+type TaskRepository interface {
+	// ListByUser returns the tasks created by userID.
+	ListByUser(userID uint) ([]models.Task, error)
+	// ListPage returns up to page.Limit matching tasks visible to userID,
+	// with their CommentCount.
+	ListPage(userID uint, filter TaskFilter, page TaskPage) ([]models.Task, error)
+	FindVisible(id, userID uint) (models.Task, error)
+	Create(task *models.Task) error
+	// Update saves task if its stored version is still version, and bumps
+	// the version. It returns ErrVersionConflict if the task changed since.
+	Update(task *models.Task, version uint) error
+	Delete(id uint) error
+}
+
+// GormTaskRepository is a TaskRepository backed by GORM.
+type GormTaskRepository struct {
+	db *gorm.DB
+}
+
+var _ TaskRepository = (*GormTaskRepository)(nil)
+
+func NewTaskRepository(db *gorm.DB) *GormTaskRepository {
+	return &GormTaskRepository{db: db}
+}
+
+func (r *GormTaskRepository) ListByUser(userID uint) ([]models.Task, error) {
+	tasks := []models.Task{}
+	err := r.db.Where("user_id = ?", userID).Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *GormTaskRepository) ListPage(userID uint, filter TaskFilter, page TaskPage) ([]models.Task, error) {
+	query := r.db.Scopes(visibleTo(userID))
+
+	if filter.ProjectID != nil {
+		if *filter.ProjectID == 0 {
+			query = query.Where("project_id IS NULL")
+		} else {
+			query = query.Where("project_id = ?", *filter.ProjectID)
+		}
+	}
+	if filter.Completed != nil {
+		query = query.Where("completed = ?", *filter.Completed)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Priority != "" {
+		query = query.Where("priority = ?", filter.Priority)
+	}
+	if filter.Search != "" {
+		pattern := likePattern(filter.Search)
+		query = query.Where("(LOWER(title) LIKE ? ESCAPE '!' OR LOWER(description) LIKE ? ESCAPE '!')", pattern, pattern)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.UpdatedAfter != nil {
+		query = query.Where("updated_at >= ?", *filter.UpdatedAfter)
+	}
+	if filter.UpdatedBefore != nil {
+		query = query.Where("updated_at < ?", *filter.UpdatedBefore)
+	}
+	if filter.DueAfter != nil {
+		query = query.Where("due_date >= ?", *filter.DueAfter)
+	}
+	if filter.DueBefore != nil {
+		query = query.Where("due_date < ?", *filter.DueBefore)
+	}
+	if filter.OverdueAt != nil {
+		query = query.Where("due_date < ? AND status <> ?", *filter.OverdueAt, models.StatusDone)
+	}
+
+	op, dir := ">", "ASC"
+	if page.Desc {
+		op, dir = "<", "DESC"
+	}
+	col := page.Column
+	if page.After != nil {
+		switch {
+		case col == "id":
+			query = query.Where("id "+op+" ?", page.After.ID)
+		case page.After.Value == nil:
+			query = query.Where(col+" IS NULL AND id "+op+" ?", page.After.ID)
+		case page.Nullable:
+			query = query.Where("("+col+" "+op+" ? OR ("+col+" = ? AND id "+op+" ?) OR "+col+" IS NULL)",
+				page.After.Value, page.After.Value, page.After.ID)
+		default:
+			query = query.Where("("+col+" "+op+" ? OR ("+col+" = ? AND id "+op+" ?))",
+				page.After.Value, page.After.Value, page.After.ID)
+		}
+	}
+	if page.Nullable {
+		query = query.Order(col + " IS NULL")
+	}
+	if col != "id" {
+		query = query.Order(col + " " + dir)
+	}
+
+	tasks := []models.Task{}
+	err := query.Select("tasks.*, (SELECT COUNT(*) FROM comments WHERE comments.task_id = tasks.id) AS comment_count").
+		Order("id " + dir).Limit(page.Limit).Find(&tasks).Error
+	return tasks, err
+}
+
+func (r *GormTaskRepository) FindVisible(id, userID uint) (models.Task, error) {
+	var task models.Task
+	err := r.db.Scopes(visibleTo(userID)).First(&task, id).Error
+	return task, notFound(err)
+}
+
+// visibleTo limits a task query to the personal tasks of userID and the
+// tasks of the projects userID is a member of.
+func visibleTo(userID uint) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("((tasks.project_id IS NULL AND tasks.user_id = ?) OR tasks.project_id IN (SELECT project_id FROM project_members WHERE user_id = ?))",
+			userID, userID)
+	}
+}
+
+func (r *GormTaskRepository) Create(task *models.Task) error {
+	return r.db.Create(task).Error
+}
+
+// Update writes the editable fields of task, its owner and its project;
+// its creation time never changes. Callers check that the user may change
+// the task.
+func (r *GormTaskRepository) Update(task *models.Task, version uint) error {
+	task.Version = version + 1
+	result := r.db.Model(task).Where("version = ?", version).
+		Select("title", "description", "completed", "status", "priority", "due_date", "user_id", "project_id", "version", "updated_at").
+		Updates(task)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := r.db.Select("id").First(&models.Task{}, task.ID).Error; err != nil {
+			return notFound(err)
+		}
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (r *GormTaskRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.Task{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
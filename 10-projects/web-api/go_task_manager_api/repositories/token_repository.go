@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrTokenUsed is returned by Rotate when the refresh token was already
+// exchanged or revoked.
+var ErrTokenUsed = errors.New("refresh token already used")
+
+// TokenRepository stores refresh tokens and the revocation list of access
+// tokens.
+type TokenRepository interface {
+	Create(token *models.RefreshToken) error
+	FindByHash(hash string) (models.RefreshToken, error)
+	// Rotate marks token id as used and stores next, atomically.
+	Rotate(id uint, next *models.RefreshToken, at time.Time) error
+	// RevokeFamily revokes every token of a session, including the access
+	// tokens issued with them.
+	RevokeFamily(familyID string, at time.Time) error
+	// RevokeUser revokes every session of a user.
+	RevokeUser(userID uint, at time.Time) error
+	IsRevoked(jti string) (bool, error)
+}
+
+// GormTokenRepository is a TokenRepository backed by GORM.
+type GormTokenRepository struct {
+	db *gorm.DB
+}
+
+var _ TokenRepository = (*GormTokenRepository)(nil)
+
+func NewTokenRepository(db *gorm.DB) *GormTokenRepository {
+	return &GormTokenRepository{db: db}
+}
+
+func (r *GormTokenRepository) Create(token *models.RefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *GormTokenRepository) FindByHash(hash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.Where("token_hash = ?", hash).First(&token).Error
+	return token, notFound(err)
+}
+
+func (r *GormTokenRepository) Rotate(id uint, next *models.RefreshToken, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// The conditional update lets only one of two concurrent refreshes
+		// with the same token win.
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+			Update("used_at", at)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTokenUsed
+		}
+		return tx.Create(next).Error
+	})
+}
+
+func (r *GormTokenRepository) RevokeFamily(familyID string, at time.Time) error {
+	return r.revoke(at, "family_id = ?", familyID)
+}
+
+func (r *GormTokenRepository) RevokeUser(userID uint, at time.Time) error {
+	return r.revoke(at, "user_id = ?", userID)
+}
+
+// revoke revokes the refresh tokens matching the query and puts their
+// access tokens that have not expired yet on the revocation list.
+func (r *GormTokenRepository) revoke(at time.Time, query string, args ...interface{}) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var tokens []models.RefreshToken
+		if err := tx.Where(query, args...).Where("revoked_at IS NULL").Find(&tokens).Error; err != nil {
+			return err
+		}
+
+		var revoked []models.RevokedToken
+		for _, t := range tokens {
+			if t.AccessExpiresAt.After(at) {
+				revoked = append(revoked, models.RevokedToken{JTI: t.AccessJTI, ExpiresAt: t.AccessExpiresAt})
+			}
+		}
+		if len(revoked) > 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&revoked).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.RefreshToken{}).Where(query, args...).
+			Where("revoked_at IS NULL").Update("revoked_at", at).Error; err != nil {
+			return err
+		}
+		// Expired access tokens are rejected anyway.
+		return tx.Where("expires_at <= ?", at).Delete(&models.RevokedToken{}).Error
+	})
+}
+
+func (r *GormTokenRepository) IsRevoked(jti string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	return count > 0, err
+}
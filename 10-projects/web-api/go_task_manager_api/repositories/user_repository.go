@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when no record matches the query.
+var ErrNotFound = errors.New("record not found")
+
+// UserRepository stores users.
+type UserRepository interface {
+	Create(user *models.User) error
+	// CreateFirstAs stores user, with role instead of its own if there are
+	// no users yet. Of two concurrent calls, only one can be first; the
+	// other is run again when the database reports the conflict.
+	CreateFirstAs(user *models.User, role string) error
+	FindByID(id uint) (models.User, error)
+	FindByUsername(username string) (models.User, error)
+	List() ([]models.User, error)
+	Update(user *models.User) error
+}
+
+// GormUserRepository is a UserRepository backed by GORM.
+type GormUserRepository struct {
+	db *gorm.DB
+}
+
+var _ UserRepository = (*GormUserRepository)(nil)
+
+func NewUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{db: db}
+}
+
+func (r *GormUserRepository) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+// firstUserAttempts bounds how often CreateFirstAs runs its transaction
+// when it loses a race with another registration.
+const firstUserAttempts = 3
+
+func (r *GormUserRepository) CreateFirstAs(user *models.User, role string) error {
+	ownRole := user.Role
+	var err error
+	for attempt := 0; attempt < firstUserAttempts; attempt++ {
+		user.ID, user.Role = 0, ownRole
+		err = r.createFirstAs(user, role)
+		if !database.IsSerializationFailure(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (r *GormUserRepository) createFirstAs(user *models.User, role string) error {
+	// Serializable, so that two transactions that both count no users
+	// cannot both commit: the loser fails with a serialization error and
+	// runs again, now seeing the first user. SQLite serializes writers.
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			user.Role = role
+		}
+		return tx.Create(user).Error
+	}, &sql.TxOptions{Isolation: sql.LevelSerializable})
+}
+
+func (r *GormUserRepository) FindByID(id uint) (models.User, error) {
+	var user models.User
+	err := r.db.First(&user, id).Error
+	return user, notFound(err)
+}
+
+func (r *GormUserRepository) FindByUsername(username string) (models.User, error) {
+	var user models.User
+	err := r.db.Where("username = ?", username).First(&user).Error
+	return user, notFound(err)
+}
+
+func (r *GormUserRepository) List() ([]models.User, error) {
+	var users []models.User
+	err := r.db.Order("id").Find(&users).Error
+	return users, err
+}
+
+func (r *GormUserRepository) Update(user *models.User) error {
+	return r.db.Save(user).Error
+}
+
+// notFound maps GORM's not-found error to ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/middleware"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterAdminRoutes(r *mux.Router, admin *controllers.AdminController, requireAuth mux.MiddlewareFunc) {
+	admins := r.PathPrefix("/admin").Subrouter()
+	admins.Use(requireAuth, middleware.RequireRole(models.RoleAdmin))
+
+	admins.HandleFunc("/users", admin.ListUsers).Methods("GET")
+	admins.HandleFunc("/users/{id}/disable", admin.DisableUser).Methods("POST")
+	admins.HandleFunc("/users/{id}/enable", admin.EnableUser).Methods("POST")
+	admins.HandleFunc("/users/{id}/role", admin.SetRole).Methods("PUT")
+	admins.HandleFunc("/users/{id}/password", admin.ResetPassword).Methods("POST")
+	admins.HandleFunc("/users/{id}/tasks", admin.GetUserTasks).Methods("GET")
+}
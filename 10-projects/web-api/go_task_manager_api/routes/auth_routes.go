@@ -4,13 +4,15 @@ import (
 	"net/http"
 
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/middleware"
 
 	"github.com/gorilla/mux"
 )
 
-func RegisterAuthRoutes(r *mux.Router) {
-	r.HandleFunc("/login", controllers.Login).Methods("POST")
-	r.HandleFunc("/register", controllers.Register).Methods("POST")
-	r.Handle("/me", middleware.JWTMiddleware(http.HandlerFunc(controllers.GetProfile))).Methods("GET")
+func RegisterAuthRoutes(r *mux.Router, auth *controllers.AuthController, requireAuth, limitAuth mux.MiddlewareFunc) {
+	r.Handle("/login", limitAuth(http.HandlerFunc(auth.Login))).Methods("POST")
+	r.Handle("/register", limitAuth(http.HandlerFunc(auth.Register))).Methods("POST")
+	r.Handle("/token/refresh", limitAuth(http.HandlerFunc(auth.RefreshToken))).Methods("POST")
+	r.Handle("/me", requireAuth(http.HandlerFunc(auth.GetProfile))).Methods("GET")
+	r.Handle("/logout", requireAuth(http.HandlerFunc(auth.Logout))).Methods("POST")
+	r.Handle("/logout-all", requireAuth(http.HandlerFunc(auth.LogoutAll))).Methods("POST")
 }
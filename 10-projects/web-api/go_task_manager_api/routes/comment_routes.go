@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterCommentRoutes(r *mux.Router, comments *controllers.CommentController, requireAuth mux.MiddlewareFunc) {
+	authenticated := r.PathPrefix("/tasks/{id}/comments").Subrouter()
+	authenticated.Use(requireAuth)
+
+	authenticated.HandleFunc("", comments.GetComments).Methods("GET")
+	authenticated.HandleFunc("", comments.CreateComment).Methods("POST")
+	authenticated.HandleFunc("/{cid}", comments.UpdateComment).Methods("PUT")
+	authenticated.HandleFunc("/{cid}", comments.DeleteComment).Methods("DELETE")
+}
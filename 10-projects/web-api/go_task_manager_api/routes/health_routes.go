@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterHealthRoutes registers the probes, which need no token and are
+// not rate limited.
+func RegisterHealthRoutes(r *mux.Router, health *controllers.HealthController) {
+	r.HandleFunc("/healthz", health.Live).Methods("GET")
+	r.HandleFunc("/readyz", health.Ready).Methods("GET")
+}
@@ -1,11 +1,102 @@
 package routes
 
 import (
+	"log/slog"
+	"net/http"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/config"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/metrics"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/middleware"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/ratelimit"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/services"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils"
+
 	"github.com/gorilla/mux"
+	httpSwagger "github.com/swaggo/http-swagger"
+	"gorm.io/gorm"
 )
 
-func InitRoutes(r *mux.Router) {
-	RegisterAuthRoutes(r) // Register /login and /register routes
-	RegisterTaskRoutes(r) // Register protected task routes
+// NewRouter builds the complete API on db: all routes, the Swagger UI and
+// the global middlewares. Requests, logins and queries of db are counted
+// in m, which is served at /metrics unless cfg.MetricsAddr is set.
+func NewRouter(db *gorm.DB, cfg config.Config, m *metrics.Metrics) http.Handler {
+	if err := db.Use(metrics.GormPlugin{Metrics: m}); err != nil {
+		slog.Warn("Database queries are not timed", "error", err)
+	}
+
+	r := mux.NewRouter()
+	r.Use(middleware.RouteTemplate) // for the access log and metrics
+	InitRoutes(r, db, cfg, m)       // Register everything from one place
+	if cfg.MetricsAddr == "" {
+		r.Handle("/metrics", MetricsHandler(m, cfg)).Methods("GET")
+	}
+
+	// Unknown routes fail with problem details like every other error
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utils.Error(w, r, http.StatusNotFound, "No such endpoint")
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		utils.Error(w, r, http.StatusMethodNotAllowed, r.Method+" is not supported here")
+	})
+
+	r.PathPrefix("/swagger/").Handler(httpSwagger.WrapHandler)
+
+	return middleware.WrapWithMiddlewares(r, m)
+}
+
+// MetricsHandler serves m in the Prometheus text format, to scrapers with
+// cfg.MetricsToken if that is set.
+func MetricsHandler(m *metrics.Metrics, cfg config.Config) http.Handler {
+	return middleware.MetricsAuth(cfg.MetricsToken)(m.Handler())
+}
+
+func InitRoutes(r *mux.Router, db *gorm.DB, cfg config.Config, m *metrics.Metrics) {
+	users := repositories.NewUserRepository(db)
+	tasks := repositories.NewTaskRepository(db)
+	tokens := repositories.NewTokenRepository(db)
+	comments := repositories.NewCommentRepository(db)
+	projects := repositories.NewProjectRepository(db)
+	jwtAuth := middleware.JWTMiddleware(tokens) // rejects revoked access tokens
+	limitUser := rateLimit(cfg.APIRateLimit, middleware.ByUser)
+	requireAuth := func(next http.Handler) http.Handler { return jwtAuth(limitUser(next)) }
+	limitAuth := rateLimit(cfg.AuthRateLimit, middleware.ByIP) // for the endpoints that work without a token
+
+	healthController := controllers.NewHealthController(services.NewHealthService(repositories.NewHealthRepository(db)))
+	authController := controllers.NewAuthController(services.NewAuthService(users, cfg.AdminUsername, loginLockout(cfg)), services.NewTokenService(tokens, users), m)
+	adminController := controllers.NewAdminController(services.NewAdminService(users, tasks, tokens))
+	commentController := controllers.NewCommentController(services.NewCommentService(comments, tasks, projects))
+	taskController := controllers.NewTaskController(services.NewTaskService(tasks, projects))
+	projectController := controllers.NewProjectController(services.NewProjectService(projects, users))
+	RegisterAuthRoutes(r, authController, requireAuth, limitAuth)            // Register /login, /register, /token/refresh and /logout routes
+	RegisterTaskRoutes(r, taskController, requireAuth)                       // Register protected task routes
+	RegisterCommentRoutes(r, commentController, requireAuth)                 // Register /tasks/{id}/comments routes
+	RegisterProjectRoutes(r, projectController, taskController, requireAuth) // Register /projects routes
+	RegisterAdminRoutes(r, adminController, requireAuth)                     // Register admin-only /admin routes
+	RegisterHealthRoutes(r, healthController)                                // Register /healthz and /readyz probes
 	// Add future route groups here
 }
+
+// rateLimit limits each client, as told apart by key, to perMinute
+// requests. It lets everything through if perMinute is not positive.
+func rateLimit(perMinute int, key func(*http.Request) string) mux.MiddlewareFunc {
+	if perMinute <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return middleware.RateLimit(ratelimit.NewMemoryStore(), ratelimit.PerMinute(perMinute), key)
+}
+
+// loginLockout returns the lockout of usernames after failed logins, or nil
+// if it is disabled.
+func loginLockout(cfg config.Config) *ratelimit.Lockout {
+	if cfg.LoginLockoutThreshold <= 0 {
+		return nil
+	}
+	return &ratelimit.Lockout{
+		Store:     ratelimit.NewMemoryAttemptStore(),
+		Threshold: cfg.LoginLockoutThreshold,
+		Base:      cfg.LoginLockoutBase,
+		Max:       cfg.LoginLockoutMax,
+	}
+}
@@ -0,0 +1,73 @@
+package routes_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/config"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	srv := testutil.NewServer(t)
+	srv.Do(t, "POST", "/register", "", controllers.AuthRequest{Username: "alice", Password: testutil.Password})
+	srv.Do(t, "POST", "/login", "", controllers.AuthRequest{Username: "alice", Password: testutil.Password})
+	srv.Do(t, "POST", "/login", "", controllers.AuthRequest{Username: "alice", Password: "wrong"})
+	srv.Do(t, "GET", "/tasks/1", "", nil)
+	srv.Do(t, "GET", "/nowhere", "", nil)
+
+	resp := srv.Do(t, "GET", "/metrics", "", nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected the Prometheus text format, got %q", ct)
+	}
+	body := string(resp.Body)
+	for _, want := range []string{
+		`http_requests_total{method="POST",route="/register",status="201"} 1`,
+		`http_requests_total{method="POST",route="/login",status="200"} 1`,
+		`http_requests_total{method="POST",route="/login",status="401"} 1`,
+		`http_requests_total{method="GET",route="/tasks/{id}",status="401"} 1`,
+		`http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`http_request_duration_seconds_bucket{method="POST",route="/login",status="200",le="+Inf"} 1`,
+		`auth_logins_total{result="success"} 1`,
+		`auth_logins_total{result="failure"} 1`,
+		`auth_logins_total{result="locked"} 0`,
+		`db_query_duration_seconds_count{operation="create",table="users"}`,
+		`db_query_duration_seconds_count{operation="query",table="users"}`,
+		"go_goroutines",
+		"go_memstats_heap_alloc_bytes",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s", want)
+		}
+	}
+	if strings.Contains(body, `route="/tasks/1"`) {
+		t.Error("expected route templates, not paths")
+	}
+}
+
+func TestMetricsToken(t *testing.T) {
+	srv := testutil.NewServerWithConfig(t, config.Config{MetricsToken: "scrape-secret"})
+
+	if resp := srv.Do(t, "GET", "/metrics", "", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without the token, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "GET", "/metrics", "wrong", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 with a wrong token, got %d", resp.StatusCode)
+	}
+	if resp := srv.Do(t, "GET", "/metrics", "scrape-secret", nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 with the token, got %d", resp.StatusCode)
+	}
+}
+
+func TestMetricsOnSeparateAddr(t *testing.T) {
+	srv := testutil.NewServerWithConfig(t, config.Config{MetricsAddr: ":9090"})
+
+	if resp := srv.Do(t, "GET", "/metrics", "", nil); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected /metrics to be off the API, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
+
+	"github.com/gorilla/mux"
+)
+
+func RegisterProjectRoutes(r *mux.Router, projects *controllers.ProjectController, tasks *controllers.TaskController, requireAuth mux.MiddlewareFunc) {
+	authenticated := r.PathPrefix("/projects").Subrouter()
+	authenticated.Use(requireAuth)
+
+	authenticated.HandleFunc("", projects.GetProjects).Methods("GET")
+	authenticated.HandleFunc("", projects.CreateProject).Methods("POST")
+	authenticated.HandleFunc("/{id}", projects.GetProject).Methods("GET")
+	authenticated.HandleFunc("/{id}", projects.DeleteProject).Methods("DELETE")
+	authenticated.HandleFunc("/{id}/tasks", tasks.CreateProjectTask).Methods("POST")
+	authenticated.HandleFunc("/{id}/members", projects.GetMembers).Methods("GET")
+	authenticated.HandleFunc("/{id}/members", projects.AddMember).Methods("POST")
+	authenticated.HandleFunc("/{id}/members/{uid}", projects.UpdateMember).Methods("PUT")
+	authenticated.HandleFunc("/{id}/members/{uid}", projects.RemoveMember).Methods("DELETE")
+}
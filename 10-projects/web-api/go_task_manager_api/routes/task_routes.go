@@ -4,20 +4,22 @@ import (
 	"net/http"
 
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/controllers"
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/middleware"
 
 	"github.com/gorilla/mux"
 )
 
-func RegisterTaskRoutes(r *mux.Router) {
+func RegisterTaskRoutes(r *mux.Router, tasks *controllers.TaskController, requireAuth mux.MiddlewareFunc) {
 	authenticated := r.PathPrefix("/tasks").Subrouter()
-	authenticated.Use(middleware.JWTMiddleware)
+	authenticated.Use(requireAuth)
 
-	authenticated.HandleFunc("", controllers.GetTasks).Methods("GET")
-	authenticated.HandleFunc("/{id}", controllers.GetTask).Methods("GET")
-	authenticated.HandleFunc("", controllers.CreateTask).Methods("POST")
-	authenticated.HandleFunc("/{id}", controllers.UpdateTask).Methods("PUT")
-	authenticated.HandleFunc("/{id}", controllers.DeleteTask).Methods("DELETE")
+	authenticated.HandleFunc("", tasks.GetTasks).Methods("GET")
+	authenticated.HandleFunc("/overdue", tasks.GetOverdueTasks).Methods("GET") // before /{id}
+	authenticated.HandleFunc("/{id}", tasks.GetTask).Methods("GET")
+	authenticated.HandleFunc("", tasks.CreateTask).Methods("POST")
+	authenticated.HandleFunc("/{id}", tasks.UpdateTask).Methods("PUT")
+	authenticated.HandleFunc("/{id}", tasks.PatchTask).Methods("PATCH")
+	authenticated.HandleFunc("/{id}", tasks.DeleteTask).Methods("DELETE")
+	authenticated.HandleFunc("/{id}/project", tasks.MoveTask).Methods("PUT")
 
 	r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("Welcome to Go Task Manager API"))
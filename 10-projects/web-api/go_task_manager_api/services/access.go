@@ -0,0 +1,89 @@
+package services
+
+import (
+	"errors"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+)
+
+// ErrForbidden matches the errors of changes a user may see but not make,
+// like a viewer editing a task of a project.
+var ErrForbidden = errors.New("forbidden")
+
+// forbiddenError is an error that matches ErrForbidden with errors.Is and
+// tells the user why.
+type forbiddenError string
+
+func (e forbiddenError) Error() string { return string(e) }
+
+func (e forbiddenError) Is(target error) bool { return target == ErrForbidden }
+
+var (
+	// ErrReadOnly is returned when a viewer changes the tasks of a project.
+	ErrReadOnly error = forbiddenError("viewers cannot change the tasks of a project")
+	// ErrNotProjectOwner is returned when anybody but an owner manages a
+	// project or its members.
+	ErrNotProjectOwner error = forbiddenError("only project owners may do this")
+	// ErrCannotTakeOut is returned when anybody but the owner of a task or
+	// an owner of its project takes the task out of the project.
+	ErrCannotTakeOut error = forbiddenError("only the task owner or a project owner may take a task out of its project")
+)
+
+// access decides what a user may do with tasks and projects. The services
+// ask it before every change, so the rules live in one place:
+//
+//   - personal tasks are their owner's alone;
+//   - the members of a project see its tasks, editors and owners change
+//     them, and owners manage the project and its members.
+//
+// Users who are not members of a project get repositories.ErrNotFound, so
+// they cannot tell that it exists.
+type access struct {
+	projects repositories.ProjectRepository
+}
+
+// project returns the role of userID in projectID if it is at least
+// minRole.
+func (a access) project(projectID, userID uint, minRole string) (string, error) {
+	role, err := a.projects.Role(projectID, userID)
+	if err != nil {
+		return "", err
+	}
+	if models.ProjectRoleRank(role) < models.ProjectRoleRank(minRole) {
+		if minRole == models.ProjectOwner {
+			return role, ErrNotProjectOwner
+		}
+		return role, ErrReadOnly
+	}
+	return role, nil
+}
+
+// editTask checks that userID may change or delete task, which userID can
+// see.
+func (a access) editTask(task models.Task, userID uint) error {
+	if task.ProjectID == nil {
+		if task.UserID != userID {
+			return repositories.ErrNotFound
+		}
+		return nil
+	}
+	_, err := a.project(*task.ProjectID, userID, models.ProjectEditor)
+	return err
+}
+
+// moderates reports whether userID looks after task: its owner or, for the
+// task of a project, an owner of the project.
+func (a access) moderates(task models.Task, userID uint) (bool, error) {
+	if task.UserID == userID {
+		return true, nil
+	}
+	if task.ProjectID == nil {
+		return false, nil
+	}
+	_, err := a.project(*task.ProjectID, userID, models.ProjectOwner)
+	if errors.Is(err, ErrForbidden) || errors.Is(err, repositories.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils/validators"
+)
+
+var (
+	// ErrInvalidInput wraps validation errors of requests.
+	ErrInvalidInput = errors.New("invalid input")
+	// ErrInvalidRole is returned for a role other than admin or member.
+	ErrInvalidRole = errors.New("invalid role")
+	// ErrSelfLockout is returned when admins try to disable or demote
+	// themselves, which could leave nobody to undo it.
+	ErrSelfLockout = errors.New("admins cannot disable or demote themselves")
+)
+
+// FieldError is a validation error of one field of a request. It matches
+// ErrInvalidInput with errors.Is.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func invalidField(field, format string, args ...interface{}) error {
+	return &FieldError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *FieldError) Error() string { return e.Message }
+
+func (e *FieldError) Is(target error) bool { return target == ErrInvalidInput }
+
+// AdminService manages the accounts of all users. Changes that take away
+// access also revoke the user's sessions.
+type AdminService struct {
+	users  repositories.UserRepository
+	tasks  repositories.TaskRepository
+	tokens repositories.TokenRepository
+}
+
+func NewAdminService(users repositories.UserRepository, tasks repositories.TaskRepository, tokens repositories.TokenRepository) *AdminService {
+	return &AdminService{users: users, tasks: tasks, tokens: tokens}
+}
+
+func (s *AdminService) ListUsers() ([]models.User, error) {
+	return s.users.List()
+}
+
+// SetDisabled disables or enables user id on behalf of adminID.
+func (s *AdminService) SetDisabled(adminID, id uint, disabled bool) (models.User, error) {
+	if disabled && adminID == id {
+		return models.User{}, ErrSelfLockout
+	}
+	user, err := s.users.FindByID(id)
+	if err != nil {
+		return models.User{}, err
+	}
+	user.Disabled = disabled
+	if err := s.users.Update(&user); err != nil {
+		return models.User{}, err
+	}
+	if disabled {
+		return user, s.tokens.RevokeUser(id, time.Now())
+	}
+	return user, nil
+}
+
+// SetRole changes the role of user id on behalf of adminID. A promotion
+// applies from the user's next login or refresh; a demotion revokes the
+// user's sessions, whose tokens still carry the old role.
+func (s *AdminService) SetRole(adminID, id uint, role string) (models.User, error) {
+	if !models.IsValidRole(role) {
+		return models.User{}, ErrInvalidRole
+	}
+	if adminID == id && role != models.RoleAdmin {
+		return models.User{}, ErrSelfLockout
+	}
+	user, err := s.users.FindByID(id)
+	if err != nil {
+		return models.User{}, err
+	}
+	demoted := user.Role == models.RoleAdmin && role != models.RoleAdmin
+	user.Role = role
+	if err := s.users.Update(&user); err != nil {
+		return models.User{}, err
+	}
+	if demoted {
+		return user, s.tokens.RevokeUser(id, time.Now())
+	}
+	return user, nil
+}
+
+// ResetPassword sets a new password for user id and logs it out everywhere.
+func (s *AdminService) ResetPassword(id uint, password string) error {
+	user, err := s.users.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := validators.ValidatePassword(password); err != nil {
+		return invalidField("password", "%v", err)
+	}
+	if user.Password, err = hashPassword(password); err != nil {
+		return err
+	}
+	if err := s.users.Update(&user); err != nil {
+		return err
+	}
+	return s.tokens.RevokeUser(id, time.Now())
+}
+
+// UserTasks returns the tasks of user id.
+func (s *AdminService) UserTasks(id uint) ([]models.Task, error) {
+	if _, err := s.users.FindByID(id); err != nil {
+		return nil, err
+	}
+	return s.tasks.ListByUser(id)
+}
@@ -2,46 +2,143 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/ratelimit"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/utils/validators"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
-func RegisterUser(username, password string) error {
-	if err := validators.ValidateUserInput(username, password); err != nil {
-		return err
+var (
+	// ErrAccountDisabled is returned when a disabled user tries to log in
+	// or refresh a session.
+	ErrAccountDisabled = errors.New("account disabled")
+	// ErrInvalidCredentials is returned for a wrong password or unknown
+	// username, which are not told apart.
+	ErrInvalidCredentials = errors.New("invalid username or password")
+)
+
+// LockedOutError is returned for logins of a username that failed too often
+// recently.
+type LockedOutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedOutError) Error() string {
+	return fmt.Sprintf("too many failed logins, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+type AuthService struct {
+	users         repositories.UserRepository
+	adminUsername string
+	lockout       *ratelimit.Lockout
+}
+
+// NewAuthService returns an AuthService. The user named adminUsername, if
+// not empty, becomes admin; so does the first user to register. Failed
+// logins lock usernames out according to lockout, unless it is nil.
+func NewAuthService(users repositories.UserRepository, adminUsername string, lockout *ratelimit.Lockout) *AuthService {
+	return &AuthService{users: users, adminUsername: adminUsername, lockout: lockout}
+}
+
+// RegisterUser validates the credentials and creates the user with a
+// hashed password.
+func (s *AuthService) RegisterUser(username, password string) (models.User, error) {
+	if err := validators.ValidateUsername(username); err != nil {
+		return models.User{}, invalidField("username", "%v", err)
+	}
+	if err := validators.ValidatePassword(password); err != nil {
+		return models.User{}, invalidField("password", "%v", err)
 	}
 
-	var existing models.User
-	if err := database.DB.Where("username = ?", username).First(&existing).Error; err == nil {
-		return errors.New("username already taken")
+	if _, err := s.users.FindByUsername(username); err == nil {
+		return models.User{}, invalidField("username", "username already taken")
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return models.User{}, err
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := hashPassword(password)
 	if err != nil {
-		return err
+		return models.User{}, err
+	}
+
+	role := models.RoleMember
+	if username == s.adminUsername {
+		role = models.RoleAdmin
 	}
 
 	user := models.User{
 		Username: username,
-		Password: string(hashedPassword),
+		Password: hashedPassword,
+		Role:     role,
+	}
+	if err := s.users.CreateFirstAs(&user, models.RoleAdmin); err != nil {
+		return models.User{}, err
 	}
+	return user, nil
+}
 
-	return database.DB.Create(&user).Error
+// BootstrapAdmin promotes the configured admin user, if it already exists.
+func (s *AuthService) BootstrapAdmin() error {
+	if s.adminUsername == "" {
+		return nil
+	}
+	user, err := s.users.FindByUsername(s.adminUsername)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil // promoted when it registers
+	} else if err != nil {
+		return err
+	}
+	if user.Role == models.RoleAdmin {
+		return nil
+	}
+	user.Role = models.RoleAdmin
+	return s.users.Update(&user)
 }
 
-func AuthenticateUser(username, password string) (models.User, error) {
-	var user models.User
-	if err := database.DB.Where("username = ?", username).First(&user).Error; err != nil {
-		return user, errors.New("user not found")
+// AuthenticateUser checks the password of username. While the username is
+// locked out it fails with a *LockedOutError without checking anything.
+func (s *AuthService) AuthenticateUser(username, password string) (models.User, error) {
+	key := strings.ToLower(username)
+	now := time.Now()
+	if wait, err := s.lockout.LockedFor(key, now); err != nil {
+		return models.User{}, err
+	} else if wait > 0 {
+		return models.User{}, &LockedOutError{RetryAfter: wait}
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return user, errors.New("invalid credentials")
+	user, err := s.users.FindByUsername(username)
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return models.User{}, err
+	}
+	if err != nil {
+		// Unknown usernames count too, so a lockout does not tell whether
+		// the account exists.
+		if wait, err := s.lockout.Fail(key, now); err != nil {
+			return models.User{}, err
+		} else if wait > 0 {
+			return models.User{}, &LockedOutError{RetryAfter: wait}
+		}
+		return models.User{}, ErrInvalidCredentials
+	}
+	if err := s.lockout.Succeed(key); err != nil {
+		return models.User{}, err
+	}
+	if user.Disabled {
+		return user, ErrAccountDisabled
 	}
 
 	return user, nil
 }
+
+func hashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hashed), err
+}
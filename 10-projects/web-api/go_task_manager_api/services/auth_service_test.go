@@ -1,71 +1,139 @@
-package services
+package services_test
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/services"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/testutil"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+// newAuthService returns an AuthService over a fresh in-memory database.
+func newAuthService(t *testing.T) (*services.AuthService, repositories.UserRepository) {
+	t.Helper()
+	users := repositories.NewUserRepository(testutil.NewDB(t))
+	return services.NewAuthService(users, "", nil), users
+}
+
 func TestRegisterUser(t *testing.T) {
-	database.Connect()
-	models.InitUserModel()
+	auth, users := newAuthService(t)
 
 	username := "test_register_user"
-	password := "test123"
+	password := "test123!"
 
-	// Clean up if user already exists
-	database.DB.Where("username = ?", username).Delete(&models.User{})
+	if _, err := auth.RegisterUser(username, password); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
 
-	err := RegisterUser(username, password)
+	// Confirm user was created with a hashed password
+	user, err := users.FindByUsername(username)
 	if err != nil {
-		t.Fatalf("expected no error, got: %v", err)
+		t.Fatalf("user was not created: %v", err)
+	}
+	if user.Password == password {
+		t.Error("expected the password to be hashed")
 	}
 
-	// Confirm user was created
-	var user models.User
-	result := database.DB.Where("username = ?", username).First(&user)
-	if result.Error != nil {
-		t.Fatalf("user was not created: %v", result.Error)
+	if _, err := auth.RegisterUser(username, password); err == nil {
+		t.Error("expected error for a taken username, got nil")
 	}
 }
 
 func TestAuthenticateUser(t *testing.T) {
-	database.Connect()
-	models.InitUserModel()
+	auth, users := newAuthService(t)
 
 	username := "test_login_user"
 	password := "testpass123"
 
-	// Clean slate
-	database.DB.Where("username = ?", username).Delete(&models.User{})
-
 	// Insert user manually
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	user := models.User{
 		Username: username,
 		Password: string(hashedPassword),
 	}
-	database.DB.Create(&user)
+	if err := users.Create(&user); err != nil {
+		t.Fatal(err)
+	}
 
 	// Test authentication
-	_, err := AuthenticateUser(username, password)
-	if err != nil {
+	if _, err := auth.AuthenticateUser(username, password); err != nil {
 		t.Fatalf("expected valid credentials, got error: %v", err)
 	}
 
 	// Test invalid credentials
-	_, err = AuthenticateUser(username, "wrongpass")
-	if err == nil {
+	if _, err := auth.AuthenticateUser(username, "wrongpass"); err == nil {
 		t.Fatal("expected error for wrong password, got nil")
 	}
 }
 
 func TestRegisterUserWithShortPassword(t *testing.T) {
-	err := RegisterUser("validuser", "123") // too short
+	auth, _ := newAuthService(t)
+	_, err := auth.RegisterUser("validuser", "123") // too short
 	if err == nil || err.Error() != "password must be at least 6 characters" {
 		t.Errorf("expected password validation error, got: %v", err)
 	}
 }
+
+func TestRegisterUserRoles(t *testing.T) {
+	users := repositories.NewUserRepository(testutil.NewDB(t))
+	auth := services.NewAuthService(users, "boss", nil)
+
+	first, _ := auth.RegisterUser("first", "Pass123!")
+	second, _ := auth.RegisterUser("second", "Pass123!")
+	boss, _ := auth.RegisterUser("boss", "Pass123!")
+	if first.Role != models.RoleAdmin || second.Role != models.RoleMember || boss.Role != models.RoleAdmin {
+		t.Errorf("unexpected roles: first=%s second=%s boss=%s", first.Role, second.Role, boss.Role)
+	}
+}
+
+func TestRegisterUserConcurrentFirstAdmin(t *testing.T) {
+	auth, users := newAuthService(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			auth.RegisterUser(fmt.Sprintf("racer%d", i), "Pass123!")
+		}(i)
+	}
+	wg.Wait()
+
+	all, err := users.List()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	admins := 0
+	for _, user := range all {
+		if user.Role == models.RoleAdmin {
+			admins++
+		}
+	}
+	if len(all) == 0 || admins != 1 {
+		t.Errorf("expected exactly one admin among %d users, got %d", len(all), admins)
+	}
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	users := repositories.NewUserRepository(testutil.NewDB(t))
+	services.NewAuthService(users, "", nil).RegisterUser("first", "Pass123!")
+	services.NewAuthService(users, "", nil).RegisterUser("later", "Pass123!")
+
+	if err := services.NewAuthService(users, "later", nil).BootstrapAdmin(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	user, _ := users.FindByUsername("later")
+	if user.Role != models.RoleAdmin {
+		t.Errorf("expected later to be promoted, got %s", user.Role)
+	}
+
+	// A configured admin that has not registered yet is not an error.
+	if err := services.NewAuthService(users, "nobody", nil).BootstrapAdmin(); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+)
+
+// Page sizes and limits of comments.
+const (
+	DefaultCommentLimit = 20
+	MaxCommentLimit     = 100
+	MaxCommentLength    = 5000 // characters
+)
+
+var (
+	// ErrCommentNotFound is returned for a comment that is not on the task.
+	ErrCommentNotFound = errors.New("comment not found")
+	// ErrNotCommentAuthor is returned when anybody but the author edits a
+	// comment.
+	ErrNotCommentAuthor error = forbiddenError("only the author may edit a comment")
+	// ErrCannotDeleteComment is returned when anybody but the author, the
+	// owner of the task or an owner of its project deletes a comment.
+	ErrCannotDeleteComment error = forbiddenError("only the author or the task owner may delete a comment")
+)
+
+// CommentService manages the comments of tasks. Users can only reach the
+// comments of tasks they can see; a task they cannot see is
+// repositories.ErrNotFound, like in TaskService. Everybody who sees a task
+// may comment on it, viewers of its project included.
+type CommentService struct {
+	comments repositories.CommentRepository
+	tasks    repositories.TaskRepository
+	access   access
+}
+
+func NewCommentService(comments repositories.CommentRepository, tasks repositories.TaskRepository, projects repositories.ProjectRepository) *CommentService {
+	return &CommentService{comments: comments, tasks: tasks, access: access{projects: projects}}
+}
+
+// CommentListOptions selects a page of the comments of a task.
+type CommentListOptions struct {
+	Limit  int    // default DefaultCommentLimit, at most MaxCommentLimit
+	Cursor string // NextCursor of the previous page
+}
+
+// CommentList is one page of comments, oldest first.
+type CommentList struct {
+	Comments   []models.Comment
+	NextCursor string // empty on the last page
+}
+
+// commentCursor marks the cursors of comment pages, so that a cursor of
+// a task listing is not accepted here.
+const commentCursor = "comments"
+
+// ListComments returns one page of the comments of task taskID.
+func (s *CommentService) ListComments(taskID, userID uint, opts CommentListOptions) (CommentList, error) {
+	if _, err := s.tasks.FindVisible(taskID, userID); err != nil {
+		return CommentList{}, err
+	}
+	switch {
+	case opts.Limit == 0:
+		opts.Limit = DefaultCommentLimit
+	case opts.Limit < 0 || opts.Limit > MaxCommentLimit:
+		return CommentList{}, invalidField("limit", "limit must be between 1 and %d", MaxCommentLimit)
+	}
+	var after uint
+	if opts.Cursor != "" {
+		var err error
+		if after, err = decodeCommentCursor(opts.Cursor); err != nil {
+			return CommentList{}, err
+		}
+	}
+
+	comments, err := s.comments.ListPage(taskID, after, opts.Limit+1)
+	if err != nil {
+		return CommentList{}, err
+	}
+	list := CommentList{Comments: comments}
+	if len(comments) > opts.Limit {
+		list.Comments = comments[:opts.Limit]
+		last := list.Comments[opts.Limit-1]
+		list.NextCursor = encodeCursor(cursor{Sort: commentCursor, ID: last.ID})
+	}
+	return list, nil
+}
+
+// decodeCommentCursor returns the ID of the last comment before the page.
+func decodeCommentCursor(s string) (uint, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	var c cursor
+	if err != nil || json.Unmarshal(data, &c) != nil || c.Sort != commentCursor {
+		return 0, invalidField("cursor", "invalid cursor")
+	}
+	return c.ID, nil
+}
+
+// AddComment posts body as a comment of userID on task taskID.
+func (s *CommentService) AddComment(taskID, userID uint, body string) (models.Comment, error) {
+	if _, err := s.tasks.FindVisible(taskID, userID); err != nil {
+		return models.Comment{}, err
+	}
+	if err := checkCommentBody(body); err != nil {
+		return models.Comment{}, err
+	}
+	comment := models.Comment{TaskID: taskID, AuthorID: userID, Body: body}
+	return comment, s.comments.Create(&comment)
+}
+
+// EditComment replaces the body of comment id. Only its author may.
+func (s *CommentService) EditComment(taskID, id, userID uint, body string) (models.Comment, error) {
+	comment, _, err := s.find(taskID, id, userID)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if comment.AuthorID != userID {
+		return models.Comment{}, ErrNotCommentAuthor
+	}
+	if err := checkCommentBody(body); err != nil {
+		return models.Comment{}, err
+	}
+	comment.Body = body
+	comment.UpdatedAt = time.Now()
+	return comment, s.comments.UpdateBody(&comment)
+}
+
+// DeleteComment deletes comment id. Its author and the owner of the task
+// may, as may the owners of the task's project, so owners can clean up the
+// discussion of their tasks.
+func (s *CommentService) DeleteComment(taskID, id, userID uint) error {
+	comment, task, err := s.find(taskID, id, userID)
+	if err != nil {
+		return err
+	}
+	if comment.AuthorID != userID {
+		if ok, err := s.access.moderates(task, userID); err != nil {
+			return err
+		} else if !ok {
+			return ErrCannotDeleteComment
+		}
+	}
+	return s.comments.Delete(id, taskID)
+}
+
+// find returns comment id on task taskID, if userID can see the task.
+func (s *CommentService) find(taskID, id, userID uint) (models.Comment, models.Task, error) {
+	task, err := s.tasks.FindVisible(taskID, userID)
+	if err != nil {
+		return models.Comment{}, models.Task{}, err
+	}
+	comment, err := s.comments.Find(id, taskID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.Comment{}, models.Task{}, ErrCommentNotFound
+	}
+	return comment, task, err
+}
+
+func checkCommentBody(body string) error {
+	if strings.TrimSpace(body) == "" {
+		return invalidField("body", "body is required")
+	}
+	if utf8.RuneCountInString(body) > MaxCommentLength {
+		return invalidField("body", "body must be at most %d characters", MaxCommentLength)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+)
+
+var (
+	// ErrDatabaseUnavailable is returned by Ready when the database does
+	// not answer.
+	ErrDatabaseUnavailable = errors.New("database unavailable")
+	// ErrMigrationsPending is returned by Ready when the schema is behind.
+	ErrMigrationsPending = errors.New("migrations pending")
+)
+
+// HealthService tells whether the server can serve requests.
+type HealthService struct {
+	health repositories.HealthRepository
+}
+
+func NewHealthService(health repositories.HealthRepository) *HealthService {
+	return &HealthService{health: health}
+}
+
+// Ready checks that the database answers before ctx ends and has every
+// migration applied.
+func (s *HealthService) Ready(ctx context.Context) error {
+	if err := s.health.Ping(ctx); err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseUnavailable, err)
+	}
+	pending, err := s.health.PendingMigrations()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDatabaseUnavailable, err)
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %s", ErrMigrationsPending, strings.Join(pending, ", "))
+	}
+	return nil
+}
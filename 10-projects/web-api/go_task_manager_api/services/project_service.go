@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+)
+
+// MaxProjectNameLength is the longest project name, in characters.
+const MaxProjectNameLength = 200
+
+var (
+	// ErrMemberNotFound is returned for a user who is not a member of the
+	// project.
+	ErrMemberNotFound = errors.New("member not found")
+	// ErrAlreadyMember is returned when adding a user who is a member of
+	// the project already.
+	ErrAlreadyMember = errors.New("user is a member of the project already")
+	// ErrLastOwner is returned when the last owner of a project would
+	// leave or lose the role, which would leave nobody to manage it.
+	ErrLastOwner = errors.New("a project needs at least one owner")
+	// ErrProjectNotEmpty is returned when deleting a project that still
+	// has tasks.
+	ErrProjectNotEmpty = errors.New("project still has tasks, move or delete them first")
+)
+
+// ProjectService manages projects and their members. Projects that a user
+// is not a member of are repositories.ErrNotFound; what members may do is
+// decided by access.
+type ProjectService struct {
+	projects repositories.ProjectRepository
+	users    repositories.UserRepository
+	access   access
+}
+
+func NewProjectService(projects repositories.ProjectRepository, users repositories.UserRepository) *ProjectService {
+	return &ProjectService{projects: projects, users: users, access: access{projects: projects}}
+}
+
+// ListProjects returns the projects of userID, with their role.
+func (s *ProjectService) ListProjects(userID uint) ([]models.Project, error) {
+	return s.projects.ListForUser(userID)
+}
+
+// GetProject returns project id with the role of userID.
+func (s *ProjectService) GetProject(id, userID uint) (models.Project, error) {
+	role, err := s.access.project(id, userID, models.ProjectViewer)
+	if err != nil {
+		return models.Project{}, err
+	}
+	project, err := s.projects.Find(id)
+	project.Role = role
+	return project, err
+}
+
+// CreateProject stores project with userID as its owner.
+func (s *ProjectService) CreateProject(userID uint, project *models.Project) error {
+	project.ID = 0
+	project.Name = strings.TrimSpace(project.Name)
+	if project.Name == "" {
+		return invalidField("name", "name is required")
+	}
+	if utf8.RuneCountInString(project.Name) > MaxProjectNameLength {
+		return invalidField("name", "name must be at most %d characters", MaxProjectNameLength)
+	}
+	return s.projects.Create(project, userID)
+}
+
+// DeleteProject deletes project id, which must have no tasks left. Only
+// owners may.
+func (s *ProjectService) DeleteProject(id, userID uint) error {
+	if _, err := s.access.project(id, userID, models.ProjectOwner); err != nil {
+		return err
+	}
+	count, err := s.projects.CountTasks(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrProjectNotEmpty
+	}
+	return s.projects.Delete(id)
+}
+
+// ListMembers returns the members of project id to any of its members.
+func (s *ProjectService) ListMembers(id, userID uint) ([]models.ProjectMember, error) {
+	if _, err := s.access.project(id, userID, models.ProjectViewer); err != nil {
+		return nil, err
+	}
+	return s.projects.Members(id)
+}
+
+// AddMember gives the user named username a role in project id. Only
+// owners may.
+func (s *ProjectService) AddMember(id, userID uint, username, role string) (models.ProjectMember, error) {
+	if _, err := s.access.project(id, userID, models.ProjectOwner); err != nil {
+		return models.ProjectMember{}, err
+	}
+	if !models.IsValidProjectRole(role) {
+		return models.ProjectMember{}, invalidField("role", "role must be owner, editor or viewer")
+	}
+	user, err := s.users.FindByUsername(username)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return models.ProjectMember{}, invalidField("username", "user %q not found", username)
+	} else if err != nil {
+		return models.ProjectMember{}, err
+	}
+	if _, err := s.projects.Role(id, user.ID); err == nil {
+		return models.ProjectMember{}, ErrAlreadyMember
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return models.ProjectMember{}, err
+	}
+	member := models.ProjectMember{ProjectID: id, UserID: user.ID, Username: user.Username, Role: role}
+	return member, s.projects.AddMember(&member)
+}
+
+// SetMemberRole changes the role of member memberID of project id. Only
+// owners may, and the last owner cannot step down.
+func (s *ProjectService) SetMemberRole(id, userID, memberID uint, role string) error {
+	if _, err := s.access.project(id, userID, models.ProjectOwner); err != nil {
+		return err
+	}
+	if !models.IsValidProjectRole(role) {
+		return invalidField("role", "role must be owner, editor or viewer")
+	}
+	current, err := s.memberRole(id, memberID)
+	if err != nil {
+		return err
+	}
+	if current == models.ProjectOwner && role != models.ProjectOwner {
+		if err := s.keepOwner(id); err != nil {
+			return err
+		}
+	}
+	return s.projects.UpdateRole(id, memberID, role)
+}
+
+// RemoveMember removes member memberID from project id. Owners may remove
+// anybody, and every member may leave, except the last owner.
+func (s *ProjectService) RemoveMember(id, userID, memberID uint) error {
+	minRole := models.ProjectOwner
+	if memberID == userID {
+		minRole = models.ProjectViewer
+	}
+	if _, err := s.access.project(id, userID, minRole); err != nil {
+		return err
+	}
+	current, err := s.memberRole(id, memberID)
+	if err != nil {
+		return err
+	}
+	if current == models.ProjectOwner {
+		if err := s.keepOwner(id); err != nil {
+			return err
+		}
+	}
+	return s.projects.RemoveMember(id, memberID)
+}
+
+// memberRole returns the role of memberID in project id, or
+// ErrMemberNotFound.
+func (s *ProjectService) memberRole(id, memberID uint) (string, error) {
+	role, err := s.projects.Role(id, memberID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return "", ErrMemberNotFound
+	}
+	return role, err
+}
+
+// keepOwner returns ErrLastOwner if project id has one owner only.
+func (s *ProjectService) keepOwner(id uint) error {
+	count, err := s.projects.CountOwners(id)
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
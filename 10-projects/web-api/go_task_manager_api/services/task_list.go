@@ -0,0 +1,192 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+)
+
+// Page sizes of task listings.
+const (
+	DefaultTaskLimit = 20
+	MaxTaskLimit     = 100
+)
+
+// TaskListOptions selects a page of a user's tasks.
+type TaskListOptions struct {
+	Filter repositories.TaskFilter
+	Sort   string // a TaskSortFields key, "-" prefixed for descending; default "id"
+	Limit  int    // default DefaultTaskLimit, at most MaxTaskLimit
+	Cursor string // NextCursor of the previous page
+}
+
+// TaskList is one page of tasks.
+type TaskList struct {
+	Tasks      []models.Task
+	NextCursor string // empty on the last page
+}
+
+// sortField is a field tasks can be sorted by. The cursor keeps its value
+// as a string, or nil for NULL.
+type sortField struct {
+	column   string
+	nullable bool
+	value    func(models.Task) *string
+	parse    func(string) (interface{}, error)
+}
+
+func str(s string) *string { return &s }
+
+func formatTime(t time.Time) *string { return str(t.Format(time.RFC3339Nano)) }
+
+func parseTime(s string) (interface{}, error) { return time.Parse(time.RFC3339Nano, s) }
+
+var taskSortFields = map[string]sortField{
+	"id": {
+		column: "id",
+		value:  func(t models.Task) *string { return str(strconv.FormatUint(uint64(t.ID), 10)) },
+		parse:  func(s string) (interface{}, error) { return strconv.ParseUint(s, 10, 64) },
+	},
+	"title": {
+		column: "title",
+		value:  func(t models.Task) *string { return str(t.Title) },
+		parse:  func(s string) (interface{}, error) { return s, nil },
+	},
+	"created_at": {
+		column: "created_at",
+		value:  func(t models.Task) *string { return formatTime(t.CreatedAt) },
+		parse:  parseTime,
+	},
+	"updated_at": {
+		column: "updated_at",
+		value:  func(t models.Task) *string { return formatTime(t.UpdatedAt) },
+		parse:  parseTime,
+	},
+	"due_date": {
+		column:   "due_date",
+		nullable: true,
+		value: func(t models.Task) *string {
+			if t.DueDate == nil {
+				return nil
+			}
+			return formatTime(*t.DueDate)
+		},
+		parse: parseTime,
+	},
+}
+
+// TaskSortFields returns the fields tasks can be sorted by.
+func TaskSortFields() []string {
+	fields := make([]string, 0, len(taskSortFields))
+	for name := range taskSortFields {
+		fields = append(fields, name)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// cursor is the JSON inside an opaque page cursor. It records the sort it
+// belongs to, so it cannot be replayed against another order.
+type cursor struct {
+	Sort  string  `json:"s"`
+	Value *string `json:"v"`
+	ID    uint    `json:"id"`
+}
+
+// ListTasks returns one page of the tasks of userID. Invalid options are
+// reported as ErrInvalidInput.
+func (s *TaskService) ListTasks(userID uint, opts TaskListOptions) (TaskList, error) {
+	if opts.Sort == "" {
+		opts.Sort = "id"
+	}
+	field, ok := taskSortFields[strings.TrimPrefix(opts.Sort, "-")]
+	if !ok {
+		return TaskList{}, invalidField("sort", "cannot sort by %q, expected one of %s",
+			opts.Sort, strings.Join(TaskSortFields(), ", "))
+	}
+	if f := opts.Filter; f.Status != "" && !models.IsValidStatus(f.Status) {
+		return TaskList{}, invalidField("status", "status must be todo, in_progress or done")
+	} else if f.Priority != "" && !models.IsValidPriority(f.Priority) {
+		return TaskList{}, invalidField("priority", "priority must be low, medium or high")
+	}
+	switch {
+	case opts.Limit == 0:
+		opts.Limit = DefaultTaskLimit
+	case opts.Limit < 0 || opts.Limit > MaxTaskLimit:
+		return TaskList{}, invalidField("limit", "limit must be between 1 and %d", MaxTaskLimit)
+	}
+
+	page := repositories.TaskPage{
+		Column:   field.column,
+		Nullable: field.nullable,
+		Desc:     strings.HasPrefix(opts.Sort, "-"),
+		Limit:    opts.Limit + 1, // one more tells whether there is a next page
+	}
+	if opts.Cursor != "" {
+		after, err := decodeCursor(opts.Cursor, opts.Sort, field)
+		if err != nil {
+			return TaskList{}, err
+		}
+		page.After = &after
+	}
+
+	tasks, err := s.tasks.ListPage(userID, opts.Filter, page)
+	if err != nil {
+		return TaskList{}, err
+	}
+	list := TaskList{Tasks: tasks}
+	if len(tasks) > opts.Limit {
+		list.Tasks = tasks[:opts.Limit]
+		last := list.Tasks[opts.Limit-1]
+		list.NextCursor = encodeCursor(cursor{Sort: opts.Sort, Value: field.value(last), ID: last.ID})
+	}
+	return list, nil
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s, sort string, field sortField) (repositories.TaskCursor, error) {
+	invalid := invalidField("cursor", "invalid cursor")
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return repositories.TaskCursor{}, invalid
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return repositories.TaskCursor{}, invalid
+	}
+	if c.Sort != sort {
+		return repositories.TaskCursor{}, invalidField("cursor", "cursor belongs to sort=%s", c.Sort)
+	}
+	if c.Value == nil {
+		if !field.nullable {
+			return repositories.TaskCursor{}, invalid
+		}
+		return repositories.TaskCursor{ID: c.ID}, nil
+	}
+	value, err := field.parse(*c.Value)
+	if err != nil {
+		return repositories.TaskCursor{}, invalid
+	}
+	return repositories.TaskCursor{Value: value, ID: c.ID}, nil
+}
+
+// ListOverdueTasks is ListTasks limited to tasks past their due date that
+// are not done, sorted by due date unless opts says otherwise.
+func (s *TaskService) ListOverdueTasks(userID uint, opts TaskListOptions) (TaskList, error) {
+	now := time.Now()
+	opts.Filter.OverdueAt = &now
+	if opts.Sort == "" {
+		opts.Sort = "due_date"
+	}
+	return s.ListTasks(userID, opts)
+}
@@ -1,16 +1,183 @@
 package services
 
 import (
-	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
 )
 
-func CreateTask(task *models.Task) error {
-	return database.DB.Create(task).Error
+// TaskService manages tasks. Users see their personal tasks and the tasks
+// of their projects; whether they may change them is decided by access.
+type TaskService struct {
+	tasks  repositories.TaskRepository
+	access access
+}
+
+func NewTaskService(tasks repositories.TaskRepository, projects repositories.ProjectRepository) *TaskService {
+	return &TaskService{tasks: tasks, access: access{projects: projects}}
+}
+
+// GetAllTasks returns the tasks created by userID.
+func (s *TaskService) GetAllTasks(userID uint) ([]models.Task, error) {
+	return s.tasks.ListByUser(userID)
+}
+
+// GetTask returns a task visible to userID, or repositories.ErrNotFound.
+func (s *TaskService) GetTask(id, userID uint) (models.Task, error) {
+	return s.tasks.FindVisible(id, userID)
+}
+
+// CreateTask stores task as a new task of userID, in project
+// task.ProjectID if set, which needs editor rights. Any ID, owner or
+// timestamps in task are ignored. Invalid fields are reported as
+// ErrInvalidInput.
+func (s *TaskService) CreateTask(userID uint, task *models.Task) error {
+	task.ID = 0
+	task.UserID = userID
+	task.CreatedAt, task.UpdatedAt = time.Time{}, time.Time{}
+	if task.ProjectID != nil {
+		if _, err := s.access.project(*task.ProjectID, userID, models.ProjectEditor); err != nil {
+			return err
+		}
+	}
+	if err := prepareTask(task, nil); err != nil {
+		return err
+	}
+	return s.tasks.Create(task)
+}
+
+// UpdateTask replaces task id with task: fields missing from task
+// get their defaults, as on create. version is the version the change is
+// based on, or 0 for the current one; a stale version returns
+// repositories.ErrVersionConflict.
+func (s *TaskService) UpdateTask(id, userID uint, task *models.Task, version uint) error {
+	existing, err := s.editable(id, userID)
+	if err != nil {
+		return err
+	}
+	if err := prepareTask(task, nil); err != nil {
+		return err
+	}
+	return s.save(existing, task, version)
+}
+
+// PatchTask changes task id with apply, which gets a copy of the
+// stored task. Like UpdateTask, it only applies on top of version, if not 0.
+func (s *TaskService) PatchTask(id, userID uint, version uint, apply func(task *models.Task) error) (models.Task, error) {
+	existing, err := s.editable(id, userID)
+	if err != nil {
+		return models.Task{}, err
+	}
+	task := existing
+	if err := apply(&task); err != nil {
+		return models.Task{}, err
+	}
+	if err := prepareTask(&task, &existing); err != nil {
+		return models.Task{}, err
+	}
+	return task, s.save(existing, &task, version)
+}
+
+// MoveTask moves task id into project projectID, or out of its project
+// into the personal tasks of its owner if projectID is nil. userID needs
+// editor rights where the task is and where it goes; only the task's owner
+// and the project's owners may take it out, as it is private afterwards.
+// Like UpdateTask, it only applies on top of version, if not 0.
+func (s *TaskService) MoveTask(id, userID uint, projectID *uint, version uint) (models.Task, error) {
+	existing, err := s.editable(id, userID)
+	if err != nil {
+		return models.Task{}, err
+	}
+	task := existing
+	task.ProjectID = projectID
+	if projectID == nil && existing.ProjectID != nil {
+		if ok, err := s.access.moderates(existing, userID); err != nil {
+			return models.Task{}, err
+		} else if !ok {
+			return models.Task{}, ErrCannotTakeOut
+		}
+	} else if projectID != nil {
+		if _, err := s.access.project(*projectID, userID, models.ProjectEditor); errors.Is(err, repositories.ErrNotFound) {
+			return models.Task{}, invalidField("project_id", "project %d not found", *projectID)
+		} else if err != nil {
+			return models.Task{}, err
+		}
+	}
+	if version == 0 {
+		version = existing.Version
+	} else if version != existing.Version {
+		return models.Task{}, repositories.ErrVersionConflict
+	}
+	return task, s.tasks.Update(&task, version)
+}
+
+// editable returns task id if userID may change it.
+func (s *TaskService) editable(id, userID uint) (models.Task, error) {
+	task, err := s.tasks.FindVisible(id, userID)
+	if err != nil {
+		return models.Task{}, err
+	}
+	return task, s.access.editTask(task, userID)
+}
+
+// save stores task over existing. The identity fields always come from
+// existing: the body cannot move the update to another row, owner or
+// project.
+func (s *TaskService) save(existing models.Task, task *models.Task, version uint) error {
+	if version == 0 {
+		version = existing.Version
+	} else if version != existing.Version {
+		return repositories.ErrVersionConflict
+	}
+	task.ID = existing.ID
+	task.UserID = existing.UserID
+	task.ProjectID = existing.ProjectID
+	task.CreatedAt = existing.CreatedAt
+	return s.tasks.Update(task, version)
+}
+
+// DeleteTask deletes task id if userID may change it.
+func (s *TaskService) DeleteTask(id, userID uint) error {
+	if _, err := s.editable(id, userID); err != nil {
+		return err
+	}
+	return s.tasks.Delete(id)
 }
 
-func GetAllTasks() ([]models.Task, error) {
-	var tasks []models.Task
-	err := database.DB.Find(&tasks).Error
-	return tasks, err
+// prepareTask fills in defaults, validates task and keeps Status and
+// Completed in sync. For updates, existing is the stored task: changing
+// only Completed moves the status to done or back to todo.
+func prepareTask(task *models.Task, existing *models.Task) error {
+	if existing != nil && task.Status == "" {
+		task.Status = existing.Status
+	}
+	switch {
+	case task.Status == "" && task.Completed:
+		task.Status = models.StatusDone
+	case task.Status == "":
+		task.Status = models.StatusTodo
+	case existing != nil && task.Status == existing.Status && task.Completed != existing.Completed:
+		if task.Completed {
+			task.Status = models.StatusDone
+		} else if task.Status == models.StatusDone {
+			task.Status = models.StatusTodo
+		}
+	}
+	if task.Priority == "" {
+		task.Priority = models.PriorityMedium
+	}
+	if strings.TrimSpace(task.Title) == "" {
+		return invalidField("title", "title is required")
+	}
+	if !models.IsValidStatus(task.Status) {
+		return invalidField("status", "status must be todo, in_progress or done")
+	}
+	if !models.IsValidPriority(task.Priority) {
+		return invalidField("priority", "priority must be low, medium or high")
+	}
+	task.Completed = task.Status == models.StatusDone
+	return nil
 }
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/middleware"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/repositories"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked
+	// refresh tokens.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused is returned when a refresh token is presented a
+	// second time. Its whole family has been revoked by then.
+	ErrRefreshTokenReused = errors.New("refresh token reused, session revoked")
+)
+
+// TokenPair is what a client gets on login and on every refresh.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time // of the access token
+}
+
+type TokenService struct {
+	tokens repositories.TokenRepository
+	users  repositories.UserRepository
+}
+
+func NewTokenService(tokens repositories.TokenRepository, users repositories.UserRepository) *TokenService {
+	return &TokenService{tokens: tokens, users: users}
+}
+
+// Issue starts a new session for user.
+func (s *TokenService) Issue(user models.User) (TokenPair, error) {
+	familyID, err := middleware.NewTokenID()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	pair, token, err := s.newPair(user, familyID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err := s.tokens.Create(&token); err != nil {
+		return TokenPair{}, err
+	}
+	return pair, nil
+}
+
+// Refresh exchanges a refresh token for a new pair of the same session.
+// A refresh token works once: presenting it again revokes the session.
+func (s *TokenService) Refresh(refreshToken string) (TokenPair, error) {
+	now := time.Now()
+	stored, err := s.tokens.FindByHash(middleware.HashRefreshToken(refreshToken))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	} else if err != nil {
+		return TokenPair{}, err
+	}
+	if stored.RevokedAt != nil || !stored.ExpiresAt.After(now) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return TokenPair{}, s.reused(stored.FamilyID, now)
+	}
+
+	user, err := s.users.FindByID(stored.UserID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	} else if err != nil {
+		return TokenPair{}, err
+	}
+	if user.Disabled {
+		return TokenPair{}, ErrAccountDisabled
+	}
+
+	pair, next, err := s.newPair(user, stored.FamilyID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	if err := s.tokens.Rotate(stored.ID, &next, now); errors.Is(err, repositories.ErrTokenUsed) {
+		return TokenPair{}, s.reused(stored.FamilyID, now)
+	} else if err != nil {
+		return TokenPair{}, err
+	}
+	return pair, nil
+}
+
+// Logout revokes one session: its refresh tokens and access tokens.
+func (s *TokenService) Logout(sessionID string) error {
+	return s.tokens.RevokeFamily(sessionID, time.Now())
+}
+
+// LogoutAll revokes every session of the user.
+func (s *TokenService) LogoutAll(userID uint) error {
+	return s.tokens.RevokeUser(userID, time.Now())
+}
+
+// reused revokes the family of a refresh token that was presented twice:
+// either the client or an attacker holds a stolen copy.
+func (s *TokenService) reused(familyID string, at time.Time) error {
+	if err := s.tokens.RevokeFamily(familyID, at); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// newPair creates the tokens of one refresh of a session, without storing
+// them.
+func (s *TokenService) newPair(user models.User, familyID string) (TokenPair, models.RefreshToken, error) {
+	access, err := middleware.GenerateToken(user.Username, user.ID, user.Role, familyID)
+	if err != nil {
+		return TokenPair{}, models.RefreshToken{}, err
+	}
+	refresh, err := middleware.GenerateRefreshToken()
+	if err != nil {
+		return TokenPair{}, models.RefreshToken{}, err
+	}
+
+	stored := models.RefreshToken{
+		UserID:          user.ID,
+		FamilyID:        familyID,
+		TokenHash:       refresh.Hash,
+		AccessJTI:       access.JTI,
+		AccessExpiresAt: access.ExpiresAt,
+		ExpiresAt:       refresh.ExpiresAt,
+	}
+	pair := TokenPair{
+		AccessToken:  access.Token,
+		RefreshToken: refresh.Token,
+		ExpiresAt:    access.ExpiresAt,
+	}
+	return pair, stored, nil
+}
@@ -0,0 +1,154 @@
+// Package testutil runs the whole API in tests without a database server.
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/config"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/database"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/metrics"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/models"
+	"github.com/neotylor/go-lang-learning/tree/master/10-projects/web-api/go_task_manager_api/routes"
+
+	"gorm.io/gorm"
+)
+
+// Password passes the registration rules.
+const Password = "StrongPass1!"
+
+// Server is the full router served by httptest over its own in-memory
+// database.
+type Server struct {
+	*httptest.Server
+	DB *gorm.DB
+}
+
+// NewDB returns a fresh, migrated in-memory database that is closed when
+// the test ends.
+func NewDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := database.OpenInMemory()
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	if err := models.Migrate(db); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// NewServer starts the API on a fresh database. It is shut down when the
+// test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	return NewServerWithConfig(t, config.Config{})
+}
+
+// NewServerWithConfig is NewServer with settings such as AdminUsername.
+func NewServerWithConfig(t testing.TB, cfg config.Config) *Server {
+	t.Helper()
+	db := NewDB(t)
+	srv := httptest.NewServer(routes.NewRouter(db, cfg, metrics.New()))
+	t.Cleanup(srv.Close)
+	return &Server{Server: srv, DB: db}
+}
+
+// Response is a finished response with its body read.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Decode unmarshals the JSON body into v.
+func (r *Response) Decode(t testing.TB, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		t.Fatalf("failed to decode response %q: %v", r.Body, err)
+	}
+}
+
+// Do sends a request with body encoded as JSON (if not nil) and token as
+// the bearer token (if not empty).
+func (s *Server) Do(t testing.TB, method, path, token string, body interface{}) *Response {
+	t.Helper()
+	return s.DoWithHeaders(t, method, path, token, body, nil)
+}
+
+// DoWithHeaders is Do with extra request headers.
+func (s *Server) DoWithHeaders(t testing.TB, method, path, token string, body interface{}, headers map[string]string) *Response {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Response{StatusCode: resp.StatusCode, Header: resp.Header, Body: data}
+}
+
+// Register signs up username with Password and returns its token.
+func (s *Server) Register(t testing.TB, username string) string {
+	t.Helper()
+	resp := s.Do(t, "POST", "/register", "", map[string]string{"username": username, "password": Password})
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("failed to register %s: %d %s", username, resp.StatusCode, resp.Body)
+	}
+	var tokens Tokens
+	resp.Decode(t, &tokens)
+	return tokens.Token
+}
+
+// Tokens is the token pair returned by /login, /register and
+// /token/refresh.
+type Tokens struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Login starts a new session for username, which must have been registered
+// with Password.
+func (s *Server) Login(t testing.TB, username string) Tokens {
+	t.Helper()
+	resp := s.Do(t, "POST", "/login", "", map[string]string{"username": username, "password": Password})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("failed to log in %s: %d %s", username, resp.StatusCode, resp.Body)
+	}
+	var tokens Tokens
+	resp.Decode(t, &tokens)
+	return tokens
+}
@@ -1 +1,65 @@
 package utils
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ProblemContentType is the media type of error responses.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object, the body of every error
+// response of the API.
+type Problem struct {
+	// Type identifies the kind of problem; "about:blank" means it is
+	// described by the status alone.
+	Type     string       `json:"type" example:"about:blank"`
+	Title    string       `json:"title" example:"Bad Request"`
+	Status   int          `json:"status" example:"400"`
+	Detail   string       `json:"detail,omitempty" example:"invalid input: title is required"`
+	Instance string       `json:"instance,omitempty" example:"/tasks/42"`
+	Errors   []FieldError `json:"errors,omitempty"` // of validation problems
+} // @name Problem
+
+// FieldError is what is wrong with one field of a request.
+type FieldError struct {
+	Field   string `json:"field" example:"title"`
+	Message string `json:"message" example:"title is required"`
+} // @name FieldError
+
+// WriteProblem writes p as the response to r. Type defaults to
+// "about:blank", Title to the text of Status and Instance to the path of r.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" && r != nil {
+		p.Instance = r.URL.Path
+	}
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// Error writes a problem with status and detail. It is the http.Error of
+// this API.
+func Error(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	WriteProblem(w, r, Problem{Status: status, Detail: detail})
+}
+
+// ValidationError writes a 400 problem listing what is wrong with the
+// fields of the request.
+func ValidationError(w http.ResponseWriter, r *http.Request, detail string, fields ...FieldError) {
+	WriteProblem(w, r, Problem{Status: http.StatusBadRequest, Detail: detail, Errors: fields})
+}
+
+// JSON writes v as a JSON response with status.
+func JSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
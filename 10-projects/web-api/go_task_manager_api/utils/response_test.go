@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestWriteProblem(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(w http.ResponseWriter, r *http.Request)
+		want  Problem
+	}{
+		{
+			name:  "error",
+			write: func(w http.ResponseWriter, r *http.Request) { Error(w, r, http.StatusNotFound, "Task not found") },
+			want:  Problem{Type: "about:blank", Title: "Not Found", Status: 404, Detail: "Task not found", Instance: "/tasks/7"},
+		},
+		{
+			name: "validation",
+			write: func(w http.ResponseWriter, r *http.Request) {
+				ValidationError(w, r, "title is required", FieldError{Field: "title", Message: "title is required"})
+			},
+			want: Problem{Type: "about:blank", Title: "Bad Request", Status: 400, Detail: "title is required", Instance: "/tasks/7",
+				Errors: []FieldError{{Field: "title", Message: "title is required"}}},
+		},
+		{
+			name: "custom",
+			write: func(w http.ResponseWriter, r *http.Request) {
+				WriteProblem(w, r, Problem{Type: "https://example.com/probs/out-of-credit", Title: "Out of credit", Status: 403, Instance: "/account"})
+			},
+			want: Problem{Type: "https://example.com/probs/out-of-credit", Title: "Out of credit", Status: 403, Instance: "/account"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.write(w, httptest.NewRequest("GET", "/tasks/7", nil))
+			if w.Code != tt.want.Status {
+				t.Errorf("status = %d, want %d", w.Code, tt.want.Status)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != ProblemContentType {
+				t.Errorf("Content-Type = %q, want %q", ct, ProblemContentType)
+			}
+			var got Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid body %q: %v", w.Body, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("problem = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package validators
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// FieldError is a rule that one field of a request breaks.
+type FieldError struct {
+	Field   string // as named in JSON
+	Message string
+}
+
+// Errors are the FieldErrors of a request, at most one per field.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Struct checks the fields of the struct v (or pointer to one) against the
+// rules in their `validate` tags, such as
+//
+//	Title string `json:"title" validate:"required,max=200"`
+//
+// and returns Errors, or nil if every rule holds. Rules are separated by
+// commas and checked in order; a field reports only its first broken rule.
+//
+//	required     the value is not zero; strings must not be blank
+//	min=N max=N  length of strings in characters, or value of numbers
+//	oneof=a b c  strings must be one of the space separated values
+//	after=DATE   times must be after DATE (2006-01-02)
+//	before=DATE  times must be before DATE
+//
+// All rules but required accept zero values: empty strings and nil
+// pointers mean "not given".
+func Struct(v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+	var errs Errors
+	for i := 0; i < rt.NumField(); i++ {
+		tag := rt.Field(i).Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		name := jsonName(rt.Field(i))
+		for _, rule := range strings.Split(tag, ",") {
+			key, param, _ := strings.Cut(rule, "=")
+			if msg := check(key, param, rv.Field(i)); msg != "" {
+				errs = append(errs, FieldError{Field: name, Message: name + " " + msg})
+				break
+			}
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func jsonName(f reflect.StructField) string {
+	if name, _, _ := strings.Cut(f.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return f.Name
+}
+
+// check returns what is wrong with v under rule key=param, or "".
+func check(key, param string, v reflect.Value) string {
+	if key == "required" {
+		if v.IsZero() || (v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "") {
+			return "is required"
+		}
+		return ""
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	if v.IsZero() {
+		return ""
+	}
+
+	switch key {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validators: invalid %s=%s", key, param))
+		}
+		var n float64
+		unit := ""
+		switch v.Kind() {
+		case reflect.String:
+			n, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(v.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = float64(v.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = v.Float()
+		default:
+			panic(fmt.Sprintf("validators: %s on %s", key, v.Type()))
+		}
+		if key == "min" && n < limit {
+			return fmt.Sprintf("must be at least %s%s", param, unit)
+		}
+		if key == "max" && n > limit {
+			return fmt.Sprintf("must be at most %s%s", param, unit)
+		}
+	case "oneof":
+		values := strings.Fields(param)
+		for _, allowed := range values {
+			if v.String() == allowed {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(values, ", ")
+	case "after", "before":
+		bound, err := time.Parse("2006-01-02", param)
+		if err != nil {
+			panic(fmt.Sprintf("validators: invalid %s=%s", key, param))
+		}
+		t, ok := v.Interface().(time.Time)
+		if !ok {
+			panic(fmt.Sprintf("validators: %s on %s", key, v.Type()))
+		}
+		if key == "after" && !t.After(bound) {
+			return "must be after " + param
+		}
+		if key == "before" && !t.Before(bound) {
+			return "must be before " + param
+		}
+	default:
+		panic("validators: unknown rule " + key)
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package validators
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type ruleRequest struct {
+	Name   string     `json:"name" validate:"required,min=2,max=5"`
+	Note   string     `json:"note,omitempty" validate:"max=3"`
+	Kind   string     `json:"kind" validate:"oneof=a b"`
+	Count  int        `json:"count" validate:"min=1,max=10"`
+	When   *time.Time `json:"when" validate:"after=2000-01-01,before=2100-01-01"`
+	Active bool       `json:"active" validate:"required"`
+	Plain  string     // no rules
+}
+
+func TestStruct(t *testing.T) {
+	at := func(s string) *time.Time {
+		tm, _ := time.Parse(time.RFC3339, s)
+		return &tm
+	}
+	valid := ruleRequest{Name: "ok", Active: true}
+
+	tests := []struct {
+		name   string
+		change func(r *ruleRequest)
+		want   Errors
+	}{
+		{"valid", func(r *ruleRequest) {}, nil},
+		{"all optional rules given", func(r *ruleRequest) {
+			r.Note, r.Kind, r.Count, r.When = "abc", "b", 10, at("2030-01-01T00:00:00Z")
+		}, nil},
+		{"required string", func(r *ruleRequest) { r.Name = "" }, Errors{{"name", "name is required"}}},
+		{"required blank string", func(r *ruleRequest) { r.Name = "   " }, Errors{{"name", "name is required"}}},
+		{"required bool", func(r *ruleRequest) { r.Active = false }, Errors{{"active", "active is required"}}},
+		{"min length", func(r *ruleRequest) { r.Name = "a" }, Errors{{"name", "name must be at least 2 characters"}}},
+		{"max length", func(r *ruleRequest) { r.Name = "abcdef" }, Errors{{"name", "name must be at most 5 characters"}}},
+		{"max length counts characters", func(r *ruleRequest) { r.Name = "ééééé" }, nil},
+		{"json name with omitempty", func(r *ruleRequest) { r.Note = "abcd" }, Errors{{"note", "note must be at most 3 characters"}}},
+		{"oneof", func(r *ruleRequest) { r.Kind = "c" }, Errors{{"kind", "kind must be one of a, b"}}},
+		{"min number", func(r *ruleRequest) { r.Count = -1 }, Errors{{"count", "count must be at least 1"}}},
+		{"max number", func(r *ruleRequest) { r.Count = 11 }, Errors{{"count", "count must be at most 10"}}},
+		{"after", func(r *ruleRequest) { r.When = at("1999-12-31T00:00:00Z") }, Errors{{"when", "when must be after 2000-01-01"}}},
+		{"before", func(r *ruleRequest) { r.When = at("2100-01-01T00:00:00Z") }, Errors{{"when", "when must be before 2100-01-01"}}},
+		{"one error per field, all fields", func(r *ruleRequest) { r.Name, r.Kind = "", "c" }, Errors{
+			{"name", "name is required"},
+			{"kind", "kind must be one of a, b"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.change(&req)
+			err := Struct(&req)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Struct() = %v, want nil", err)
+				}
+				return
+			}
+			got, ok := err.(Errors)
+			if !ok || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Struct() = %#v, want %#v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructUnknownRule(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for an unknown rule")
+		}
+	}()
+	Struct(struct {
+		A string `validate:"shiny"`
+	}{A: "x"})
+}
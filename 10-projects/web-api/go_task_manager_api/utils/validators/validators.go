@@ -6,12 +6,28 @@ import (
 )
 
 func ValidateUserInput(username, password string) error {
+	if err := ValidateUsername(username); err != nil {
+		return err
+	}
+	return ValidatePassword(password)
+}
+
+// ValidateUsername checks the username rules of ValidateUserInput.
+func ValidateUsername(username string) error {
 	if len(username) < 3 {
 		return errors.New("username must be at least 3 characters")
 	}
+	return nil
+}
+
+// ValidatePassword checks the password rules of ValidateUserInput.
+func ValidatePassword(password string) error {
 	if len(password) < 6 {
 		return errors.New("password must be at least 6 characters")
 	}
+	if len(password) > 72 {
+		return errors.New("password must be at most 72 bytes")
+	}
 	if !containsDigit(password) {
 		return errors.New("password must include at least one number")
 	}
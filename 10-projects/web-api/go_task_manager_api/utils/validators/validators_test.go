@@ -1,6 +1,9 @@
 package validators
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestValidateUserInput(t *testing.T) {
 	tests := []struct {
@@ -13,6 +16,7 @@ func TestValidateUserInput(t *testing.T) {
 		{"validuser", "longbutno123", true},
 		{"validuser", "NoSymbol123", true},
 		{"validuser", "StrongPass1!", false},
+		{"validuser", "StrongPass1!" + strings.Repeat("x", 61), true}, // over bcrypt's 72 bytes
 	}
 
 	for _, tt := range tests {